@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/tabed23/cloudmarket-auth/graph/config"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+)
+
+// command is an admin subcommand. It adds its flags to fset and returns the
+// function that runs it once they are parsed.
+type command func(fset *flag.FlagSet) func(ctx context.Context, env *cliEnv) error
+
+// cliEnv is what admin subcommands work with.
+type cliEnv struct {
+	repo repos.Repository
+	in   io.Reader
+	out  io.Writer
+}
+
+// runCommand implements `auth <group> <name> [flags]` for the commands of
+// group. Their flags are parsed along with the configuration flags.
+func runCommand(group, usage string, commands map[string]command, args []string) {
+	if len(args) == 0 {
+		fatal("invalid arguments", errors.New(usage))
+	}
+	name, args := args[0], args[1:]
+	cmd, ok := commands[name]
+	if !ok {
+		fatal("invalid arguments", errors.New(usage))
+	}
+
+	fset := flag.NewFlagSet("auth "+group+" "+name, flag.ContinueOnError)
+	run := cmd(fset)
+	cfg, err := config.LoadFlags(fset, args)
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	// Standard output is the command's own.
+	logging.Setup(os.Stderr, cfg.Log.Level, cfg.Log.Levels)
+	jwt.Configure(cfg.JWT.Secret, cfg.JWT.Issuer, cfg.JWT.AccessTokenTTL, cfg.JWT.RefreshTokenTTL, cfg.JWT.InvitationTTL, cfg.JWT.ImpersonationTTL)
+	if cfg.DB.Driver == config.DriverMemory {
+		fatal("invalid arguments", fmt.Errorf("%s: the memory driver keeps nothing between runs", group))
+	}
+	repo, db, err := openRepository(cfg)
+	if err != nil {
+		fatal("failed to open repository", err)
+	}
+
+	err = run(context.Background(), &cliEnv{repo: repo, in: os.Stdin, out: os.Stdout})
+	if sqlDB, dbErr := db.DB(); dbErr == nil {
+		sqlDB.Close()
+	}
+	if err != nil {
+		fatal(group+" "+name+" failed", err)
+	}
+}
+
+// password reads a password from the first line of the input, or generates
+// one if fromInput is false.
+func (env *cliEnv) password(fromInput bool) (password string, generated bool, err error) {
+	if !fromInput {
+		password, err := utils.RandomToken(18)
+		return password, true, err
+	}
+	line, err := bufio.NewReader(env.in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", false, fmt.Errorf("failed to read password: %w", err)
+	}
+	password = strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", false, errors.New("the password read from stdin is empty")
+	}
+	return password, false, nil
+}
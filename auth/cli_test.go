@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/memory"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+)
+
+// runCmd parses args for cmd and runs it against env, returning its output.
+func runCmd(t *testing.T, env *cliEnv, cmd command, stdin string, args ...string) (string, error) {
+	t.Helper()
+	fset := flag.NewFlagSet("test", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	run := cmd(fset)
+	if err := fset.Parse(args); err != nil {
+		t.Fatalf("parse %v: %v", args, err)
+	}
+	var out bytes.Buffer
+	env.in, env.out = strings.NewReader(stdin), &out
+	err := run(context.Background(), env)
+	return out.String(), err
+}
+
+func TestUserCommands(t *testing.T) {
+	ctx := context.Background()
+	env := &cliEnv{repo: memory.NewStore()}
+
+	out, err := runCmd(t, env, userCreate, "", "-email", "Root@Example.com", "-first-name", "Ada", "-admin")
+	if err != nil {
+		t.Fatalf("user create: %v", err)
+	}
+	password := strings.TrimPrefix(out[strings.Index(out, "password: "):], "password: ")
+	password = strings.TrimSpace(password)
+	admin, _ := env.repo.UserByEmail(ctx, "root@example.com")
+	if admin == nil || admin.Role != model.RoleAdmin || !utils.CheckPasswordHash(password, admin.Password) {
+		t.Fatalf("created admin %+v with output %q", admin, out)
+	}
+
+	if _, err := runCmd(t, env, userCreate, "", "-email", "root@EXAMPLE.com"); err == nil {
+		t.Error("creating a second account with the same email succeeded")
+	}
+	if _, err := runCmd(t, env, userCreate, "", "-email", "not-an-email"); err == nil {
+		t.Error("creating an account with an invalid email succeeded")
+	}
+	if _, err := runCmd(t, env, userCreate, "chosen-password\n", "-email", "bob@example.com", "-password-stdin"); err != nil {
+		t.Fatalf("user create -password-stdin: %v", err)
+	}
+	bob, _ := env.repo.UserByEmail(ctx, "bob@example.com")
+	if bob == nil || bob.Role != model.RoleUser || !utils.CheckPasswordHash("chosen-password", bob.Password) {
+		t.Fatalf("created user %+v", bob)
+	}
+
+	if out, err := runCmd(t, env, userSetRole, "", "-email", "bob@example.com", "-role", "admin"); err != nil {
+		t.Fatalf("user set-role: %v", err)
+	} else if !strings.Contains(out, "tokens issued before keep the USER role") {
+		t.Errorf("user set-role output %q does not warn about existing tokens", out)
+	}
+	if bob, _ = env.repo.UserByEmail(ctx, "bob@example.com"); bob.Role != model.RoleAdmin || !utils.CheckPasswordHash("chosen-password", bob.Password) {
+		t.Errorf("after set-role: %+v", bob)
+	}
+	if _, err := runCmd(t, env, userSetRole, "", "-email", "bob@example.com", "-role", "OWNER"); err == nil {
+		t.Error("set-role with an unknown role succeeded")
+	}
+	if _, err := runCmd(t, env, userSetRole, "", "-email", "nobody@example.com", "-role", "USER"); err == nil {
+		t.Error("set-role of a missing account succeeded")
+	}
+
+	if _, err := runCmd(t, env, userResetPassword, "new-password\n", "-email", "BOB@example.com", "-password-stdin"); err != nil {
+		t.Fatalf("user reset-password: %v", err)
+	}
+	if bob, _ = env.repo.UserByEmail(ctx, "bob@example.com"); !utils.CheckPasswordHash("new-password", bob.Password) || bob.Role != model.RoleAdmin {
+		t.Errorf("after reset-password: %+v", bob)
+	}
+	if _, err := runCmd(t, env, userResetPassword, "", "-email", "bob@example.com", "-password-stdin"); err == nil {
+		t.Error("reset-password with an empty password succeeded")
+	}
+
+	if _, err := runCmd(t, env, userCreate, "", "-email", "carol@example.com"); err != nil {
+		t.Fatalf("user create: %v", err)
+	}
+	out, err = runCmd(t, env, userList, "", "-role", "ADMIN")
+	if err != nil {
+		t.Fatalf("user list: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "bob@example.com") || !strings.Contains(lines[2], "Root@Example.com") {
+		t.Errorf("user list -role ADMIN =\n%s", out)
+	}
+	out, _ = runCmd(t, env, userList, "")
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 4 {
+		t.Errorf("user list =\n%s", out)
+	}
+}
+
+func TestKeysRotate(t *testing.T) {
+	t.Cleanup(func() { jwt.SetKeys(nil) })
+	ctx := context.Background()
+	env := &cliEnv{repo: memory.NewStore()}
+
+	before, err := jwt.GenreateJwt(ctx, "u1", "ada@example.com", model.RoleUser)
+	if err != nil {
+		t.Fatalf("GenreateJwt: %v", err)
+	}
+	for range 2 {
+		if _, err := runCmd(t, env, keysRotate, ""); err != nil {
+			t.Fatalf("keys rotate: %v", err)
+		}
+	}
+	keys, _ := env.repo.SigningKeys(ctx)
+	if len(keys) != 2 || keys[0].ExpiresAt == nil || keys[1].ExpiresAt != nil {
+		t.Fatalf("stored keys = %+v", keys)
+	}
+	if !strings.HasPrefix(keys[0].Secret, "aesgcm:") {
+		t.Errorf("stored secret %q is not sealed", keys[0].Secret)
+	}
+	if !keys[0].ActiveFrom.After(time.Now()) {
+		t.Errorf("rotated key active from %v, want after the propagation delay", keys[0].ActiveFrom)
+	}
+
+	if err := loadSigningKeys(ctx, env.repo); err != nil {
+		t.Fatalf("loadSigningKeys: %v", err)
+	}
+	// The new keys are not active yet, so tokens from before still verify.
+	if _, err := jwt.ValidateJwt(ctx, before); err != nil {
+		t.Errorf("token signed before the rotation: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+const maxStatusReason = 500
+
+// statusChange validates the arguments of setUserStatus and returns the
+// reason and end date to store. Reactivating an account clears both.
+func statusChange(status model.AccountStatus, reason *string, until *time.Time, now time.Time) (string, *time.Time, error) {
+	if !status.IsValid() {
+		return "", nil, apperr.Validation("invalid account status %s", status)
+	}
+	if status == model.AccountStatusActive {
+		return "", nil, nil
+	}
+	detail := ""
+	if reason != nil {
+		detail = strings.TrimSpace(*reason)
+	}
+	if len(detail) > maxStatusReason {
+		return "", nil, apperr.Validation("reason must be at most %d characters", maxStatusReason)
+	}
+	if detail == "" && (status == model.AccountStatusSuspended || status == model.AccountStatusDisabled) {
+		return "", nil, apperr.Validation("a reason is required to %s an account", statusVerb(status))
+	}
+	if until != nil {
+		if status != model.AccountStatusSuspended {
+			return "", nil, apperr.Validation("until only applies to suspensions")
+		}
+		if !until.After(now) {
+			return "", nil, apperr.Validation("until must be in the future")
+		}
+	}
+	return detail, until, nil
+}
+
+func statusVerb(status model.AccountStatus) string {
+	if status == model.AccountStatusSuspended {
+		return "suspend"
+	}
+	return "disable"
+}
+
+// statusDetail is the audit log detail for a status change.
+func statusDetail(status model.AccountStatus, reason string, until *time.Time) string {
+	detail := string(status)
+	if until != nil {
+		detail += " until " + until.UTC().Format(time.RFC3339)
+	}
+	if reason != "" {
+		detail += ": " + reason
+	}
+	return detail
+}
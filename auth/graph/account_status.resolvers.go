@@ -0,0 +1,58 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.80
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// SetUserStatus is the resolver for the setUserStatus field.
+func (r *mutationResolver) SetUserStatus(ctx context.Context, userID string, status model.AccountStatus, reason *string, until *time.Time) (*model.User, error) {
+	actor, err := r.currentAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if userID == actor.ID {
+		return nil, apperr.Forbidden("cannot change your own account status")
+	}
+	detail, until, err := statusChange(status, reason, until, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	subject, err := r.UserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if subject == nil {
+		return nil, apperr.NotFound("user not found")
+	}
+
+	_, err = r.AuditLogCreation(ctx, &model.NewAuditLogModel{
+		ActorID:   actor.ID,
+		SubjectID: subject.ID,
+		Action:    model.AuditUserStatus,
+		Operation: "setUserStatus",
+		Detail:    statusDetail(status, detail, until),
+		RequestID: logging.RequestID(ctx),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	updated, err := r.UserStatusUpdate(ctx, subject.ID, string(status), detail, until)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, apperr.NotFound("user not found")
+	}
+	return model.ConvertToGraphQLUser(*updated), nil
+}
@@ -0,0 +1,241 @@
+package graph_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+const setUserStatus = `mutation($id: ID!, $status: AccountStatus!, $reason: String, $until: Time) {
+	setUserStatus(userId: $id, status: $status, reason: $reason, until: $until) { id status statusReason suspendedUntil }
+}`
+
+type statusResp struct {
+	ID             string
+	Status         *string
+	StatusReason   *string
+	SuspendedUntil *string
+}
+
+func postUserStatus(h *graphtest.Harness, caller graphtest.Session, userID, status string, reason *string, until *time.Time) (statusResp, error) {
+	var resp struct{ SetUserStatus statusResp }
+	opts := []client.Option{client.Var("id", userID), client.Var("status", status), caller.Auth()}
+	if reason != nil {
+		opts = append(opts, client.Var("reason", *reason))
+	}
+	if until != nil {
+		opts = append(opts, client.Var("until", until.Format(time.RFC3339)))
+	}
+	err := h.Post(setUserStatus, &resp, opts...)
+	return resp.SetUserStatus, err
+}
+
+func login(h *graphtest.Harness, email string) error {
+	var resp struct{ Login struct{ Token string } }
+	return h.Post(`mutation($email: String!, $password: String!) { login(email: $email, password: $password) { token } }`,
+		&resp, client.Var("email", email), client.Var("password", graphtest.Password))
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestSuspendingAUserRevokesTheirTokens(t *testing.T) {
+	h := graphtest.New(t)
+	admin := h.LoginAsAdmin()
+	user := h.LoginAsUser()
+
+	until := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	got, err := postUserStatus(h, admin, user.User.ID, "SUSPENDED", strPtr("chargebacks"), &until)
+	if err != nil {
+		t.Fatalf("setUserStatus: %v", err)
+	}
+	if got.Status == nil || *got.Status != "SUSPENDED" || got.StatusReason == nil || *got.StatusReason != "chargebacks" || got.SuspendedUntil == nil {
+		t.Fatalf("setUserStatus = %+v", got)
+	}
+
+	var me struct{ GetMe struct{ ID string } }
+	if err := h.Post(`query { getMe { id } }`, &me, user.Auth()); err == nil {
+		t.Fatal("a suspended user's token still works")
+	}
+	err = login(h, user.User.Email)
+	if err == nil || !strings.Contains(err.Error(), "account is suspended until "+until.UTC().Format(time.RFC3339)) {
+		t.Fatalf("login while suspended: %v", err)
+	}
+
+	if _, err := postUserStatus(h, admin, user.User.ID, "ACTIVE", nil, nil); err != nil {
+		t.Fatalf("reactivate: %v", err)
+	}
+	h.MustPost(`query { getMe { id } }`, &me, user.Auth())
+	if err := login(h, user.User.Email); err != nil {
+		t.Fatalf("login after reactivation: %v", err)
+	}
+
+	entries := auditLog(t, h, admin, user.User.ID)
+	if len(entries) != 2 || entries[0].Detail != "ACTIVE" || entries[1].Action != model.AuditUserStatus ||
+		entries[1].Detail != "SUSPENDED until "+until.UTC().Format(time.RFC3339)+": chargebacks" {
+		t.Fatalf("audit log = %+v", entries)
+	}
+}
+
+func TestDisabledAndUnverifiedAccountsCannotSignIn(t *testing.T) {
+	h := graphtest.New(t)
+	admin := h.LoginAsAdmin()
+
+	tests := []struct {
+		status string
+		reason *string
+		want   string
+	}{
+		{"DISABLED", strPtr("fraud"), "account is disabled"},
+		{"SUSPENDED", strPtr("abuse"), "account is suspended"},
+		{"PENDING_VERIFICATION", nil, "email address is not verified"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.status, func(t *testing.T) {
+			user := h.LoginAsUser()
+			if _, err := postUserStatus(h, admin, user.User.ID, tc.status, tc.reason, nil); err != nil {
+				t.Fatalf("setUserStatus: %v", err)
+			}
+			if err := login(h, user.User.Email); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("login error = %v, want %q", err, tc.want)
+			}
+			// A wrong password still gets the generic answer.
+			var resp struct{ Login struct{ Token string } }
+			err := h.Post(`mutation($email: String!) { login(email: $email, password: "wrong") { token } }`,
+				&resp, client.Var("email", user.User.Email))
+			if err == nil || !strings.Contains(err.Error(), "invalid credentials") {
+				t.Fatalf("login with a wrong password: %v", err)
+			}
+		})
+	}
+}
+
+func TestLapsedSuspensionReactivatesTheAccount(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.LoginAsUser()
+
+	past := time.Now().Add(-time.Minute)
+	if _, err := h.Repo.UserStatusUpdate(context.Background(), user.User.ID, model.StatusSuspended, "cooling off", &past); err != nil {
+		t.Fatal(err)
+	}
+	var me struct {
+		GetMe struct {
+			Status         string
+			SuspendedUntil *string
+		}
+	}
+	h.MustPost(`query { getMe { status suspendedUntil } }`, &me, user.Auth())
+	if me.GetMe.Status != "ACTIVE" || me.GetMe.SuspendedUntil != nil {
+		t.Fatalf("getMe = %+v", me.GetMe)
+	}
+	if err := login(h, user.User.Email); err != nil {
+		t.Fatalf("login after the suspension ended: %v", err)
+	}
+}
+
+func TestSetUserStatusValidation(t *testing.T) {
+	h := graphtest.New(t)
+	admin := h.LoginAsAdmin()
+	user := h.LoginAsUser()
+	other := h.LoginAsUser()
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name   string
+		caller graphtest.Session
+		userID string
+		status string
+		reason *string
+		until  *time.Time
+		want   string
+	}{
+		{"not an admin", other, user.User.ID, "DISABLED", strPtr("spam"), nil, "only admins"},
+		{"own account", admin, admin.User.ID, "DISABLED", strPtr("oops"), nil, "your own account status"},
+		{"no reason", admin, user.User.ID, "SUSPENDED", nil, nil, "a reason is required"},
+		{"blank reason", admin, user.User.ID, "DISABLED", strPtr("  "), nil, "a reason is required"},
+		{"until in the past", admin, user.User.ID, "SUSPENDED", strPtr("spam"), &past, "must be in the future"},
+		{"until when disabling", admin, user.User.ID, "DISABLED", strPtr("spam"), &future, "only applies to suspensions"},
+		{"unknown user", admin, "missing", "DISABLED", strPtr("spam"), nil, "user not found"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := postUserStatus(h, tc.caller, tc.userID, tc.status, tc.reason, tc.until)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("setUserStatus error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	// Only admins see why an account was blocked.
+	if _, err := postUserStatus(h, admin, user.User.ID, "SUSPENDED", strPtr("spam"), &future); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := h.Repo.UserByID(context.Background(), user.User.ID)
+	if stored.Status != model.StatusSuspended {
+		t.Fatalf("stored status = %q", stored.Status)
+	}
+	var resp struct {
+		User struct {
+			Status       *string
+			StatusReason *string
+		}
+	}
+	h.MustPost(`query($id: ID!) { user(id: $id) { status statusReason } }`, &resp, client.Var("id", user.User.ID), other.Auth())
+	if resp.User.Status != nil || resp.User.StatusReason != nil {
+		t.Fatalf("another user sees status %v, reason %v", resp.User.Status, resp.User.StatusReason)
+	}
+}
+
+func TestDemotedAdminLosesAdminFields(t *testing.T) {
+	h := graphtest.New(t)
+	admin := h.LoginAsAdmin()
+	user := h.LoginAsUser()
+	if _, err := postUserStatus(h, admin, user.User.ID, "DISABLED", strPtr("spam"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	query := `query($id: ID!) { user(id: $id) { email statusReason } }`
+	var resp struct {
+		User struct {
+			Email        *string
+			StatusReason *string
+		}
+	}
+	h.MustPost(query, &resp, client.Var("id", user.User.ID), admin.Auth())
+	if resp.User.Email == nil || resp.User.StatusReason == nil {
+		t.Fatalf("admin sees email %v, reason %v", resp.User.Email, resp.User.StatusReason)
+	}
+
+	// The token still says ADMIN, but the account no longer is one.
+	stored := admin.User
+	if _, err := h.Repo.UserUpdate(context.Background(), stored.Email, &model.NewUserModel{
+		FirstName: stored.FirstName,
+		LastName:  stored.LastName,
+		Password:  stored.Password,
+		Role:      model.RoleUser,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp.User.Email, resp.User.StatusReason = nil, nil
+	h.MustPost(query, &resp, client.Var("id", user.User.ID), admin.Auth())
+	if resp.User.Email != nil || resp.User.StatusReason != nil {
+		t.Fatalf("demoted admin sees email %v, reason %v", resp.User.Email, resp.User.StatusReason)
+	}
+}
+
+func TestImpersonationCannotChangeStatus(t *testing.T) {
+	h := graphtest.New(t)
+	admin := h.LoginAsAdmin()
+	user := h.LoginAsUser()
+	other := h.LoginAsUser()
+
+	session := h.Impersonate(admin, user.User.ID)
+	if _, err := postUserStatus(h, session, other.User.ID, "DISABLED", strPtr("spam"), nil); err == nil {
+		t.Fatal("setUserStatus worked with an impersonation token")
+	}
+}
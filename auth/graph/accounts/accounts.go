@@ -0,0 +1,293 @@
+// Package accounts signs users in and registers them. The GraphQL resolvers
+// and the REST endpoints share it, so both apply the same checks, metrics
+// and login history.
+package accounts
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/events"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/mailer"
+	"github.com/tabed23/cloudmarket-auth/graph/metrics"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+)
+
+// Errors callers can tell apart with errors.Is. Errors of accounts that may
+// not sign in and of invalid input keep their own message but match
+// ErrAccountInactive and ErrInvalidInput, and have their apperr code.
+var (
+	ErrInvalidCredentials  = apperr.Unauthenticated("invalid credentials")
+	ErrInvalidRefreshToken = apperr.Unauthenticated("invalid or expired refresh token")
+	ErrUnauthenticated     = apperr.Unauthenticated("user not authenticated")
+	ErrEmailTaken          = apperr.Conflict("an account with this email already exists")
+	ErrAccountInactive     = apperr.Forbidden("account inactive")
+	ErrInvalidInput        = apperr.Validation("invalid input")
+)
+
+// dummyHash is checked against the password given for an unknown email, so
+// that login takes as long whether or not the email has an account.
+var dummyHash, _ = utils.HashPassword("cloudmarket-no-such-account")
+
+// kindError has a message of its own but wraps kind.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string { return e.msg }
+
+func (e *kindError) Unwrap() error { return e.kind }
+
+func invalidInput(format string, args ...any) error {
+	return &kindError{kind: ErrInvalidInput, msg: fmt.Sprintf(format, args...)}
+}
+
+// Service signs users in and registers them.
+type Service struct {
+	repo   repos.Repository
+	mailer mailer.Mailer
+	events events.Publisher
+}
+
+// NewService returns a Service over repo. New device notifications are
+// emailed through m and published to p.
+func NewService(repo repos.Repository, m mailer.Mailer, p events.Publisher) *Service {
+	return &Service{repo: repo, mailer: m, events: p}
+}
+
+// AuditImpersonation records a request made by the caller if they are
+// impersonating a user. See middleware.AuditImpersonation.
+func (s *Service) AuditImpersonation(ctx context.Context, operation, detail string) error {
+	return middleware.AuditImpersonation(ctx, s.repo, middleware.CtxValue(ctx), operation, detail)
+}
+
+// Session is a signed-in user and the tokens issued to them.
+type Session struct {
+	User         *model.UserModel
+	Token        string
+	RefreshToken string
+}
+
+// Registration is what a new account is created from.
+type Registration struct {
+	FirstName string
+	LastName  string
+	Email     string
+	Password  string
+}
+
+// Login checks email and password and starts a session.
+func (s *Service) Login(ctx context.Context, email, password string) (*Session, error) {
+	if !middleware.SignInAllowed(ctx) {
+		return nil, middleware.ErrCrossSiteSignIn
+	}
+	user, err := s.repo.UserByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user by email: %w", err)
+	}
+	if user == nil {
+		utils.CheckPasswordHash(password, dummyHash)
+		metrics.LoginsTotal.WithLabelValues(metrics.LoginUnknownEmail).Inc()
+		return nil, ErrInvalidCredentials
+	}
+
+	if !utils.CheckPasswordHash(password, user.Password) {
+		metrics.LoginsTotal.WithLabelValues(metrics.LoginBadPassword).Inc()
+		s.RecordFailedLogin(ctx, user, model.LoginMethodPassword, model.LoginOutcomeBadPassword)
+		return nil, ErrInvalidCredentials
+	}
+	if err := StatusError(user, time.Now()); err != nil {
+		metrics.LoginsTotal.WithLabelValues(metrics.LoginLocked).Inc()
+		s.RecordFailedLogin(ctx, user, model.LoginMethodPassword, model.LoginOutcomeAccountInactive)
+		return nil, err
+	}
+
+	metrics.LoginsTotal.WithLabelValues(metrics.LoginSuccess).Inc()
+	return s.StartSession(ctx, user, model.LoginMethodPassword)
+}
+
+// Register creates a USER account and starts its first session.
+func (s *Service) Register(ctx context.Context, input Registration) (*Session, error) {
+	if !middleware.SignInAllowed(ctx) {
+		return nil, middleware.ErrCrossSiteSignIn
+	}
+	user, err := s.CreateUser(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return s.StartSession(ctx, user, model.LoginMethodPassword)
+}
+
+// CreateUser creates a USER account from input. It backs registration.
+func (s *Service) CreateUser(ctx context.Context, input Registration) (*model.UserModel, error) {
+	newUser, err := s.newUser(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.UserCreation(ctx, newUser)
+	if err != nil {
+		metrics.RegistrationsTotal.WithLabelValues(metrics.OutcomeFailure).Inc()
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	metrics.RegistrationsTotal.WithLabelValues(metrics.OutcomeSuccess).Inc()
+	if user == nil {
+		return nil, fmt.Errorf("created user is nil")
+	}
+	return user, nil
+}
+
+// CreateInvitedUser creates a USER account from input and accepts the
+// invitation with the given ID for it, in one step: if the invitation can no
+// longer be accepted, no account is left behind. It backs invitations
+// accepted by someone without an account. The membership is nil if the
+// invitation does not exist.
+func (s *Service) CreateInvitedUser(ctx context.Context, invitationID string, input Registration) (*model.UserModel, *model.MembershipModel, error) {
+	newUser, err := s.newUser(ctx, input)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, membership, err := s.repo.InvitationSignUp(ctx, invitationID, newUser)
+	if err != nil {
+		metrics.RegistrationsTotal.WithLabelValues(metrics.OutcomeFailure).Inc()
+		return nil, nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	if membership == nil {
+		return nil, nil, nil
+	}
+	metrics.RegistrationsTotal.WithLabelValues(metrics.OutcomeSuccess).Inc()
+	return user, membership, nil
+}
+
+// newUser checks input and returns the USER account to create from it.
+func (s *Service) newUser(ctx context.Context, input Registration) (*model.NewUserModel, error) {
+	if _, err := utils.NormalizeEmail(input.Email); err != nil {
+		return nil, invalidInput("%s", err)
+	}
+	if input.Password == "" {
+		return nil, invalidInput("password is required")
+	}
+	existing, err := s.repo.UserByEmail(ctx, input.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user by email: %w", err)
+	}
+	if existing != nil {
+		metrics.RegistrationsTotal.WithLabelValues(metrics.OutcomeFailure).Inc()
+		return nil, ErrEmailTaken
+	}
+	hashpass, err := utils.HashPassword(input.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	return &model.NewUserModel{
+		FirstName: input.FirstName,
+		LastName:  input.LastName,
+		Email:     input.Email,
+		Password:  hashpass,
+		Role:      model.RoleUser,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for new tokens. The account must still
+// be active and the session not revoked; role and organization role are
+// read again, so changes to them apply from the next refresh.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*Session, error) {
+	claims, err := jwt.ValidateRefreshJwt(ctx, refreshToken)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	user, err := s.repo.UserByID(ctx, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user by id: %w", err)
+	}
+	if user == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err := StatusError(user, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var opts []jwt.ClaimOption
+	if claims.SessionID != "" {
+		login, err := s.repo.LoginEventByID(ctx, claims.SessionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch session: %w", err)
+		}
+		if login == nil || login.UserID != user.ID || login.Revoked() {
+			return nil, ErrInvalidRefreshToken
+		}
+		opts = append(opts, jwt.WithSession(login.ID))
+	}
+	// A user who left the organization falls back to a personal token.
+	if claims.OrgID != "" {
+		membership, err := s.repo.MembershipByUser(ctx, claims.OrgID, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch membership: %w", err)
+		}
+		if membership != nil {
+			opts = append(opts, jwt.WithOrganization(membership.OrganizationID, membership.Role))
+		}
+	}
+	return IssueTokens(ctx, user, opts...)
+}
+
+// Me returns the signed-in caller.
+func (s *Service) Me(ctx context.Context) (*model.UserModel, error) {
+	claims := middleware.CtxValue(ctx)
+	if claims == nil {
+		return nil, ErrUnauthenticated
+	}
+	user, err := s.repo.UserByID(ctx, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user by id: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUnauthenticated
+	}
+	return user, nil
+}
+
+// IssueTokens signs an access and a refresh token for user. Tokens reissued
+// to a signed-in caller, e.g. on switchOrganization, stay in the caller's
+// session.
+func IssueTokens(ctx context.Context, user *model.UserModel, opts ...jwt.ClaimOption) (*Session, error) {
+	if claims := middleware.CtxValue(ctx); claims != nil && claims.ID == user.ID && claims.SessionID != "" {
+		opts = append([]jwt.ClaimOption{jwt.WithSession(claims.SessionID)}, opts...)
+	}
+	token, err := jwt.GenreateJwt(ctx, user.ID, user.Email, user.Role, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT: %w", err)
+	}
+	refreshToken, err := jwt.GenerateRefreshJwt(ctx, user.ID, user.Email, user.Role, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	return &Session{User: user, Token: token, RefreshToken: refreshToken}, nil
+}
+
+// StatusError explains why user may not sign in, or returns nil if they may.
+// It is only shown once the caller has proven they hold the account. The
+// error matches ErrAccountInactive.
+func StatusError(user *model.UserModel, now time.Time) error {
+	var msg string
+	switch user.EffectiveStatus(now) {
+	case model.StatusActive:
+		return nil
+	case model.StatusSuspended:
+		msg = "account is suspended"
+		if user.StatusUntil != nil {
+			msg += " until " + user.StatusUntil.UTC().Format(time.RFC3339)
+		}
+	case model.StatusPendingVerification:
+		msg = "email address is not verified"
+	default:
+		msg = "account is disabled"
+	}
+	return &kindError{kind: ErrAccountInactive, msg: msg}
+}
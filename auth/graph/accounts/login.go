@@ -0,0 +1,114 @@
+package accounts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/events"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/mailer"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+var logger = logging.For("login")
+
+func newLoginEvent(ctx context.Context, user *model.UserModel, method model.LoginMethod, outcome model.LoginOutcome) *model.NewLoginEventModel {
+	client := middleware.ClientFrom(ctx)
+	return &model.NewLoginEventModel{
+		UserID:      user.ID,
+		Method:      method,
+		Outcome:     outcome,
+		IP:          client.IP,
+		UserAgent:   client.UserAgent,
+		Fingerprint: client.Fingerprint(),
+	}
+}
+
+// RecordFailedLogin adds a failed attempt to user's login history. Errors
+// are only logged, so the caller gets the same answer either way.
+func (s *Service) RecordFailedLogin(ctx context.Context, user *model.UserModel, method model.LoginMethod, outcome model.LoginOutcome) {
+	if _, err := s.repo.LoginEventCreation(ctx, newLoginEvent(ctx, user, method, outcome)); err != nil {
+		logger.ErrorContext(ctx, "failed to record login", slog.Any("error", err))
+	}
+}
+
+// StartSession records a successful sign-in and issues tokens bound to it,
+// so the user can revoke them from their login history. The user is told
+// when the sign-in comes from a device they have not used before. opts add
+// claims to the tokens, such as an active organization.
+func (s *Service) StartSession(ctx context.Context, user *model.UserModel, method model.LoginMethod, opts ...jwt.ClaimOption) (*Session, error) {
+	input := newLoginEvent(ctx, user, method, model.LoginOutcomeSuccess)
+	isNew, err := s.newDevice(ctx, user.ID, input.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	login, err := s.repo.LoginEventCreation(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login: %w", err)
+	}
+	if isNew {
+		s.notifyNewDevice(ctx, user, login)
+	}
+	return IssueTokens(ctx, user, append(opts, jwt.WithSession(login.ID))...)
+}
+
+// newDevice reports whether fingerprint has no successful sign-in on the
+// account that the user still stands by. The very first recorded sign-in
+// is not new: there is nothing yet to compare it with.
+func (s *Service) newDevice(ctx context.Context, userID, fingerprint string) (bool, error) {
+	previous, err := s.repo.LoginEvents(ctx, model.LoginEventFilter{UserID: userID, Outcome: model.LoginOutcomeSuccess, Limit: 1})
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch login history: %w", err)
+	}
+	if len(previous) == 0 {
+		return false, nil
+	}
+	same, err := s.repo.LoginEvents(ctx, model.LoginEventFilter{UserID: userID, Outcome: model.LoginOutcomeSuccess, Fingerprint: fingerprint})
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch login history: %w", err)
+	}
+	for _, login := range same {
+		if !login.Revoked() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// notifyNewDevice publishes an events.NewDeviceLogin and emails the user.
+// Errors are only logged: the sign-in itself has succeeded.
+func (s *Service) notifyNewDevice(ctx context.Context, user *model.UserModel, login *model.LoginEventModel) {
+	err := s.events.Publish(ctx, events.Event{
+		Type:   events.NewDeviceLogin,
+		UserID: user.ID,
+		Data: map[string]string{
+			"login_id":   login.ID,
+			"ip":         login.IP,
+			"user_agent": login.UserAgent,
+		},
+		Time: login.CreatedAt,
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to publish new device event", slog.Any("error", err))
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "New sign-in to your CloudMarket account",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Your CloudMarket account was signed in to from a new device:\n\n"+
+			"  Time:       %s\n"+
+			"  IP address: %s\n"+
+			"  Browser:    %s\n\n"+
+			"If this was you, there is nothing to do.\n"+
+			"If not, mark the sign-in as \"not me\" in your login history to sign that device out, and change your password.\n",
+			user.FirstName, login.CreatedAt.UTC().Format(time.RFC1123), login.IP, login.UserAgent),
+	})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to send new device notification", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/postal"
+)
+
+const (
+	// maxAddresses bounds the address book of a single user.
+	maxAddresses    = 20
+	maxAddressField = 200
+	addressNotFound = "address not found"
+)
+
+// addressInput validates input and converts it for storage. Default flags
+// left out keep their value on current, which is nil for a new address.
+func addressInput(input model.AddressInput, current *model.AddressModel) (*model.NewAddressModel, error) {
+	optional := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return strings.TrimSpace(*s)
+	}
+	address := &model.NewAddressModel{
+		FullName: strings.TrimSpace(input.FullName),
+		Street:   strings.TrimSpace(input.Street),
+		City:     strings.TrimSpace(input.City),
+		State:    optional(input.State),
+		Country:  strings.ToUpper(strings.TrimSpace(input.Country)),
+		Phone:    optional(input.Phone),
+	}
+
+	for name, value := range map[string]string{"fullName": address.FullName, "street": address.Street, "city": address.City} {
+		if value == "" {
+			return nil, apperr.Validation("%s is required", name)
+		}
+	}
+	for name, value := range map[string]string{
+		"fullName": address.FullName, "street": address.Street, "city": address.City,
+		"state": address.State, "phone": address.Phone,
+	} {
+		if len(value) > maxAddressField {
+			return nil, apperr.Validation("%s must be at most %d characters", name, maxAddressField)
+		}
+	}
+
+	postalCode, err := postal.Validate(address.Country, optional(input.PostalCode))
+	if err != nil {
+		return nil, err
+	}
+	address.PostalCode = postalCode
+
+	if current != nil {
+		address.IsDefaultShipping = current.IsDefaultShipping
+		address.IsDefaultBilling = current.IsDefaultBilling
+	}
+	if input.IsDefaultShipping != nil {
+		address.IsDefaultShipping = *input.IsDefaultShipping
+	}
+	if input.IsDefaultBilling != nil {
+		address.IsDefaultBilling = *input.IsDefaultBilling
+	}
+	return address, nil
+}
+
+// ownAddress returns the caller's address with id. Addresses of other users
+// are reported as missing.
+func (r *Resolver) ownAddress(ctx context.Context, id string) (*model.AddressModel, error) {
+	claims := middleware.CtxValue(ctx)
+	if claims == nil {
+		return nil, apperr.Unauthenticated("user not authenticated")
+	}
+	address, err := r.AddressByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch address: %w", err)
+	}
+	if address == nil || address.UserID != claims.ID {
+		return nil, apperr.NotFound(addressNotFound)
+	}
+	return address, nil
+}
+
+// storedAddress converts a stored address back to repository input.
+func storedAddress(a *model.AddressModel) *model.NewAddressModel {
+	return &model.NewAddressModel{
+		FullName:          a.FullName,
+		Street:            a.Street,
+		City:              a.City,
+		State:             a.State,
+		PostalCode:        a.PostalCode,
+		Country:           a.Country,
+		Phone:             a.Phone,
+		IsDefaultShipping: a.IsDefaultShipping,
+		IsDefaultBilling:  a.IsDefaultBilling,
+	}
+}
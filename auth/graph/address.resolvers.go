@@ -0,0 +1,118 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.80
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// CreateAddress is the resolver for the createAddress field.
+func (r *mutationResolver) CreateAddress(ctx context.Context, input model.AddressInput) (*model.Address, error) {
+	claims := middleware.CtxValue(ctx)
+	if claims == nil {
+		return nil, apperr.Unauthenticated("user not authenticated")
+	}
+	address, err := addressInput(input, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := r.AddressesByUser(ctx, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch addresses: %w", err)
+	}
+	if len(existing) >= maxAddresses {
+		return nil, apperr.Validation("an address book holds at most %d addresses", maxAddresses)
+	}
+	if len(existing) == 0 {
+		address.IsDefaultShipping = input.IsDefaultShipping == nil || *input.IsDefaultShipping
+		address.IsDefaultBilling = input.IsDefaultBilling == nil || *input.IsDefaultBilling
+	}
+
+	created, err := r.AddressCreation(ctx, claims.ID, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create address: %w", err)
+	}
+	return model.ConvertToGraphQLAddress(*created), nil
+}
+
+// UpdateAddress is the resolver for the updateAddress field.
+func (r *mutationResolver) UpdateAddress(ctx context.Context, id string, input model.AddressInput) (*model.Address, error) {
+	current, err := r.ownAddress(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	address, err := addressInput(input, current)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := r.AddressUpdate(ctx, id, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update address: %w", err)
+	}
+	if updated == nil {
+		return nil, apperr.NotFound(addressNotFound)
+	}
+	return model.ConvertToGraphQLAddress(*updated), nil
+}
+
+// DeleteAddress is the resolver for the deleteAddress field.
+func (r *mutationResolver) DeleteAddress(ctx context.Context, id string) (bool, error) {
+	if _, err := r.ownAddress(ctx, id); err != nil {
+		return false, err
+	}
+	if err := r.AddressDelete(ctx, id); err != nil {
+		return false, fmt.Errorf("failed to delete address: %w", err)
+	}
+	return true, nil
+}
+
+// SetDefaultAddress is the resolver for the setDefaultAddress field.
+func (r *mutationResolver) SetDefaultAddress(ctx context.Context, id string, kind model.AddressKind) (*model.Address, error) {
+	current, err := r.ownAddress(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	address := storedAddress(current)
+	switch kind {
+	case model.AddressKindShipping:
+		address.IsDefaultShipping = true
+	case model.AddressKindBilling:
+		address.IsDefaultBilling = true
+	}
+
+	updated, err := r.AddressUpdate(ctx, id, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update address: %w", err)
+	}
+	if updated == nil {
+		return nil, apperr.NotFound(addressNotFound)
+	}
+	return model.ConvertToGraphQLAddress(*updated), nil
+}
+
+// MyAddresses is the resolver for the myAddresses field.
+func (r *queryResolver) MyAddresses(ctx context.Context) ([]*model.Address, error) {
+	claims := middleware.CtxValue(ctx)
+	if claims == nil {
+		return nil, apperr.Unauthenticated("user not authenticated")
+	}
+	addresses, err := r.AddressesByUser(ctx, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch addresses: %w", err)
+	}
+
+	result := make([]*model.Address, 0, len(addresses))
+	for _, a := range addresses {
+		result = append(result, model.ConvertToGraphQLAddress(*a))
+	}
+	return result, nil
+}
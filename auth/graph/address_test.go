@@ -0,0 +1,144 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+)
+
+type addressResp struct {
+	ID                string
+	PostalCode        string
+	Country           string
+	Phone             *string
+	IsDefaultShipping bool
+	IsDefaultBilling  bool
+}
+
+const addressFields = `id postalCode country phone isDefaultShipping isDefaultBilling`
+
+func addressInput(street, country, postalCode string) map[string]any {
+	return map[string]any{
+		"fullName":   "Ada Lovelace",
+		"street":     street,
+		"city":       "Somewhere",
+		"country":    country,
+		"postalCode": postalCode,
+	}
+}
+
+func createAddress(t *testing.T, h *graphtest.Harness, s graphtest.Session, input map[string]any) addressResp {
+	t.Helper()
+	var resp struct{ CreateAddress addressResp }
+	h.MustPost(`mutation($input: AddressInput!) { createAddress(input: $input) { `+addressFields+` } }`,
+		&resp, client.Var("input", input), s.Auth())
+	return resp.CreateAddress
+}
+
+func myAddresses(t *testing.T, h *graphtest.Harness, s graphtest.Session) map[string]addressResp {
+	t.Helper()
+	var resp struct{ MyAddresses []addressResp }
+	h.MustPost(`query { myAddresses { `+addressFields+` } }`, &resp, s.Auth())
+	byID := map[string]addressResp{}
+	for _, a := range resp.MyAddresses {
+		byID[a.ID] = a
+	}
+	return byID
+}
+
+func TestCreateAddressValidatesPostalCode(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.LoginAsUser()
+
+	got := createAddress(t, h, user, addressInput("1 Main St", "gb", " sw1a   1aa "))
+	if got.Country != "GB" || got.PostalCode != "SW1A 1AA" || got.Phone != nil {
+		t.Fatalf("createAddress = %+v", got)
+	}
+	if !got.IsDefaultShipping || !got.IsDefaultBilling {
+		t.Fatalf("first address is not the default: %+v", got)
+	}
+
+	noCode := createAddress(t, h, user, addressInput("Sheikh Zayed Rd", "AE", "00000"))
+	if noCode.PostalCode != "" || noCode.IsDefaultShipping || noCode.IsDefaultBilling {
+		t.Fatalf("address without postal code = %+v", noCode)
+	}
+
+	create := `mutation($input: AddressInput!) { createAddress(input: $input) { id } }`
+	for _, tc := range []struct {
+		input map[string]any
+		want  string
+	}{
+		{addressInput("1 Main St", "DE", "1234"), "for Germany"},
+		{addressInput("1 Main St", "US", ""), "postal code is required for United States"},
+		{addressInput("1 Main St", "XX", "12345"), "unsupported country"},
+		{addressInput("  ", "US", "94103"), "street is required"},
+	} {
+		var resp map[string]any
+		err := h.Post(create, &resp, client.Var("input", tc.input), user.Auth())
+		expectError(t, err, tc.want)
+	}
+}
+
+func TestAddressDefaults(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.LoginAsUser()
+	home := createAddress(t, h, user, addressInput("1 Home St", "US", "94103"))
+
+	input := addressInput("2 Work St", "US", "10001-1234")
+	input["isDefaultShipping"] = true
+	work := createAddress(t, h, user, input)
+
+	book := myAddresses(t, h, user)
+	if book[home.ID].IsDefaultShipping || !book[home.ID].IsDefaultBilling {
+		t.Fatalf("home after work took shipping = %+v", book[home.ID])
+	}
+	if !book[work.ID].IsDefaultShipping || book[work.ID].IsDefaultBilling {
+		t.Fatalf("work = %+v", book[work.ID])
+	}
+
+	var set struct{ SetDefaultAddress addressResp }
+	h.MustPost(`mutation($id: ID!) { setDefaultAddress(id: $id, kind: BILLING) { `+addressFields+` } }`,
+		&set, client.Var("id", work.ID), user.Auth())
+	if !set.SetDefaultAddress.IsDefaultBilling || !set.SetDefaultAddress.IsDefaultShipping {
+		t.Fatalf("setDefaultAddress = %+v", set.SetDefaultAddress)
+	}
+	if book := myAddresses(t, h, user); book[home.ID].IsDefaultBilling {
+		t.Fatalf("home kept default billing: %+v", book[home.ID])
+	}
+
+	// Updating without the flags keeps them.
+	var upd struct{ UpdateAddress addressResp }
+	input = addressInput("3 Work St", "US", "10002")
+	input["phone"] = "+1 555 0100"
+	h.MustPost(`mutation($id: ID!, $input: AddressInput!) { updateAddress(id: $id, input: $input) { `+addressFields+` } }`,
+		&upd, client.Var("id", work.ID), client.Var("input", input), user.Auth())
+	if got := upd.UpdateAddress; !got.IsDefaultShipping || !got.IsDefaultBilling || got.PostalCode != "10002" ||
+		got.Phone == nil || *got.Phone != "+1 555 0100" {
+		t.Fatalf("updateAddress = %+v", got)
+	}
+}
+
+func TestAddressesArePrivate(t *testing.T) {
+	h := graphtest.New(t)
+	owner := h.LoginAsUser()
+	other := h.LoginAsUser()
+	address := createAddress(t, h, owner, addressInput("1 Main St", "US", "94103"))
+
+	if book := myAddresses(t, h, other); len(book) != 0 {
+		t.Fatalf("other user sees %d addresses", len(book))
+	}
+
+	var resp map[string]any
+	err := h.Post(`mutation($id: ID!) { deleteAddress(id: $id) }`, &resp, client.Var("id", address.ID), other.Auth())
+	expectError(t, err, "address not found")
+	err = h.Post(`mutation($id: ID!, $input: AddressInput!) { updateAddress(id: $id, input: $input) { id } }`,
+		&resp, client.Var("id", address.ID), client.Var("input", addressInput("x", "US", "94103")), other.Auth())
+	expectError(t, err, "address not found")
+
+	var del struct{ DeleteAddress bool }
+	h.MustPost(`mutation($id: ID!) { deleteAddress(id: $id) }`, &del, client.Var("id", address.ID), owner.Auth())
+	if !del.DeleteAddress || len(myAddresses(t, h, owner)) != 0 {
+		t.Fatalf("deleteAddress = %v", del.DeleteAddress)
+	}
+}
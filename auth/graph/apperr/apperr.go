@@ -0,0 +1,89 @@
+// Package apperr defines the errors the API shows to clients. Each carries a
+// code that the GraphQL API reports in extensions.code and the REST API as
+// the HTTP status. Any other error is internal: it is logged and clients see
+// a generic message instead.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code classifies an error for clients.
+type Code string
+
+// Codes of the errors clients can act on, and of internal errors.
+const (
+	CodeNotFound        Code = "NOT_FOUND"
+	CodeConflict        Code = "CONFLICT"
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	CodeForbidden       Code = "FORBIDDEN"
+	CodeValidation      Code = "BAD_USER_INPUT"
+	CodeInternal        Code = "INTERNAL_SERVER_ERROR"
+)
+
+// Error is an error with a message that is safe to show to clients.
+type Error struct {
+	Code Code
+	err  error
+}
+
+func newError(code Code, format string, args []any) *Error {
+	return &Error{Code: code, err: fmt.Errorf(format, args...)}
+}
+
+// Error returns the message shown to clients.
+func (e *Error) Error() string { return e.err.Error() }
+
+// Unwrap returns the errors wrapped with %w in the message format.
+func (e *Error) Unwrap() error { return errors.Unwrap(e.err) }
+
+// NotFound reports that the object asked for does not exist, or is not
+// visible to the caller.
+func NotFound(format string, args ...any) *Error {
+	return newError(CodeNotFound, format, args)
+}
+
+// Conflict reports that the request clashes with existing data, such as an
+// email address that is already taken.
+func Conflict(format string, args ...any) *Error {
+	return newError(CodeConflict, format, args)
+}
+
+// Unauthenticated reports a missing or invalid token or credentials.
+func Unauthenticated(format string, args ...any) *Error {
+	return newError(CodeUnauthenticated, format, args)
+}
+
+// Forbidden reports that the caller may not do what it asked.
+func Forbidden(format string, args ...any) *Error {
+	return newError(CodeForbidden, format, args)
+}
+
+// Validation reports invalid input.
+func Validation(format string, args ...any) *Error {
+	return newError(CodeValidation, format, args)
+}
+
+// Internal is an internal error with a message of its own, for failures
+// that were already logged.
+func Internal(format string, args ...any) *Error {
+	return newError(CodeInternal, format, args)
+}
+
+// CodeOf returns the code of the first Error in err's chain, or
+// CodeInternal if there is none.
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeInternal
+}
+
+// Is reports whether err has an Error in its chain, and so a message meant
+// for clients.
+func Is(err error) bool {
+	var e *Error
+	return errors.As(err, &e)
+}
@@ -0,0 +1,37 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCodeOf(t *testing.T) {
+	sentinel := errors.New("taken")
+	tests := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{"typed", NotFound("user not found"), CodeNotFound},
+		{"wrapped", fmt.Errorf("register: %w", Conflict("email %w", sentinel)), CodeConflict},
+		{"plain", errors.New("connection refused"), CodeInternal},
+		{"nil", nil, CodeInternal},
+	}
+	for _, tt := range tests {
+		if got := CodeOf(tt.err); got != tt.want {
+			t.Errorf("CodeOf(%s) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestErrorWrapsArguments(t *testing.T) {
+	sentinel := errors.New("account inactive")
+	err := Forbidden("%w: account is suspended", sentinel)
+	if !errors.Is(err, sentinel) {
+		t.Errorf("errors.Is(%v, sentinel) = false", err)
+	}
+	if err.Error() != "account inactive: account is suspended" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
@@ -0,0 +1,55 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tabed23/cloudmarket-auth/graph/accounts"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// issueTokens signs an access and a refresh token for user and wraps them in
+// an AuthPayload, as accounts.IssueTokens does.
+func issueTokens(ctx context.Context, user *model.UserModel, opts ...jwt.ClaimOption) (*model.AuthPayload, error) {
+	return authPayload(ctx)(accounts.IssueTokens(ctx, user, opts...))
+}
+
+// authPayload wraps a session from the accounts service for the API. In the
+// cookie session mode the tokens are set as cookies instead and left empty
+// in the payload.
+func authPayload(ctx context.Context) func(*accounts.Session, error) (*model.AuthPayload, error) {
+	return func(session *accounts.Session, err error) (*model.AuthPayload, error) {
+		if err != nil {
+			return nil, err
+		}
+		payload := &model.AuthPayload{
+			Token:        session.Token,
+			RefreshToken: session.RefreshToken,
+			User:         model.ConvertToGraphQLUser(*session.User),
+		}
+		inCookies, err := middleware.SetSessionCookies(ctx, session.Token, session.RefreshToken)
+		if errors.Is(err, middleware.ErrCrossSiteSignIn) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to set session cookies: %w", err)
+		}
+		if inCookies {
+			payload.Token, payload.RefreshToken = "", ""
+		}
+		return payload, nil
+	}
+}
+
+// registration is the accounts service input for a NewUser.
+func registration(input model.NewUser) accounts.Registration {
+	return accounts.Registration{
+		FirstName: input.FirstName,
+		LastName:  input.LastName,
+		Email:     input.Email,
+		Password:  input.Password,
+	}
+}
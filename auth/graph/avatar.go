@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/avatar"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+var avatarLogger = logging.For("avatar")
+
+// avatarSizes maps the GraphQL sizes to rendition edge lengths.
+var avatarSizes = map[model.AvatarSize]avatar.Size{
+	model.AvatarSizeSmall:  avatar.Small,
+	model.AvatarSizeMedium: avatar.Medium,
+	model.AvatarSizeLarge:  avatar.Large,
+}
+
+// avatarKey is the blob prefix of one upload. Every upload gets a fresh
+// prefix so that URLs change with the picture and caches never go stale.
+func avatarKey(userID string) string {
+	return fmt.Sprintf("avatars/%s/%s", userID, uuid.NewString())
+}
+
+func renditionKey(prefix string, size avatar.Size) string {
+	return fmt.Sprintf("%s/%d.jpg", prefix, size)
+}
+
+// storeAvatar validates and re-encodes file and stores its renditions under
+// a new prefix, which it returns. Nothing is left behind on failure.
+func (r *Resolver) storeAvatar(ctx context.Context, userID string, file graphql.Upload) (string, error) {
+	if r.Blobs == nil {
+		return "", apperr.Forbidden("avatar uploads are not enabled")
+	}
+	if file.Size > r.AvatarMaxBytes {
+		return "", apperr.Validation("%w: the limit is %d bytes", avatar.ErrTooLarge, r.AvatarMaxBytes)
+	}
+	renditions, err := avatar.Process(file.File, r.AvatarMaxBytes)
+	if err != nil {
+		if errors.Is(err, avatar.ErrTooLarge) {
+			return "", apperr.Validation("%w: the limit is %d bytes", err, r.AvatarMaxBytes)
+		}
+		return "", err
+	}
+
+	prefix := avatarKey(userID)
+	for _, rendition := range renditions {
+		err := r.Blobs.Put(ctx, renditionKey(prefix, rendition.Size), avatar.ContentType, bytes.NewReader(rendition.Data))
+		if err != nil {
+			r.deleteAvatar(ctx, prefix)
+			return "", fmt.Errorf("failed to store avatar: %w", err)
+		}
+	}
+	return prefix, nil
+}
+
+// deleteAvatar removes the renditions under prefix. Failures only leave
+// unreferenced files behind, so they are logged rather than returned.
+func (r *Resolver) deleteAvatar(ctx context.Context, prefix string) {
+	if prefix == "" || r.Blobs == nil {
+		return
+	}
+	if err := r.Blobs.Delete(ctx, prefix); err != nil {
+		avatarLogger.WarnContext(ctx, "failed to delete avatar", slog.String("key", prefix), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,62 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.80
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// UploadAvatar is the resolver for the uploadAvatar field.
+func (r *mutationResolver) UploadAvatar(ctx context.Context, file graphql.Upload) (*model.User, error) {
+	claims := middleware.CtxValue(ctx)
+	if claims == nil {
+		return nil, apperr.Unauthenticated("user not authenticated")
+	}
+	current, err := r.UserByID(ctx, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if current == nil {
+		return nil, apperr.NotFound("user not found")
+	}
+
+	key, err := r.storeAvatar(ctx, claims.ID, file)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := r.UserAvatarUpdate(ctx, claims.ID, key)
+	if err != nil || updated == nil {
+		r.deleteAvatar(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update avatar: %w", err)
+		}
+		return nil, apperr.NotFound("user not found")
+	}
+	r.deleteAvatar(ctx, current.AvatarKey)
+	return model.ConvertToGraphQLUser(*updated), nil
+}
+
+// AvatarURL is the resolver for the avatarUrl field.
+func (r *userResolver) AvatarURL(ctx context.Context, obj *model.User, size *model.AvatarSize) (*string, error) {
+	if obj.AvatarKey == "" || r.Blobs == nil {
+		return nil, nil
+	}
+	requested := model.AvatarSizeMedium
+	if size != nil {
+		requested = *size
+	}
+	edge, ok := avatarSizes[requested]
+	if !ok {
+		return nil, apperr.Validation("unknown avatar size %s", requested)
+	}
+	url := r.Blobs.URL(renditionKey(obj.AvatarKey, edge))
+	return &url, nil
+}
@@ -0,0 +1,142 @@
+// Package avatar validates uploaded profile pictures and re-encodes them into
+// square JPEG renditions of standard sizes.
+package avatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"net/http"
+
+	// Registered decoders for the accepted upload formats.
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Size is the edge length of a square rendition in pixels.
+type Size int
+
+const (
+	Small  Size = 64
+	Medium Size = 128
+	Large  Size = 256
+)
+
+// Sizes lists the renditions Process produces, smallest first.
+var Sizes = []Size{Small, Medium, Large}
+
+// ContentType is the type of every rendition.
+const ContentType = "image/jpeg"
+
+const (
+	// MaxPixels bounds the decoded size of an upload so that a small,
+	// highly compressed file cannot exhaust memory.
+	MaxPixels = 40_000_000
+	minEdge   = 16
+	quality   = 85
+)
+
+// allowed maps sniffed content types to the image.Decode format names.
+var allowed = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+var (
+	// ErrTooLarge is returned for uploads over the byte limit.
+	ErrTooLarge = apperr.Validation("image is too large")
+	// ErrUnsupportedType is returned for anything but JPEG, PNG, GIF or WebP.
+	ErrUnsupportedType = apperr.Validation("unsupported image type, use JPEG, PNG, GIF or WebP")
+)
+
+// Rendition is one encoded size of an avatar.
+type Rendition struct {
+	Size Size
+	Data []byte
+}
+
+// Process reads at most maxBytes from r, checks that it is an image of an
+// accepted type and sensible dimensions, and returns one rendition per entry
+// in Sizes. The content type is sniffed from the data; whatever the client
+// declared is not trusted.
+func Process(r io.Reader, maxBytes int64) ([]Rendition, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	format, ok := allowed[http.DetectContentType(data)]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+	cfg, decoded, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || decoded != format {
+		return nil, apperr.Validation("invalid %s image", format)
+	}
+	if cfg.Width < minEdge || cfg.Height < minEdge {
+		return nil, apperr.Validation("image must be at least %dx%d pixels", minEdge, minEdge)
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, apperr.Validation("image has too many pixels (%dx%d)", cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, apperr.Validation("invalid %s image", format)
+	}
+	src = squareCrop(src)
+
+	renditions := make([]Rendition, 0, len(Sizes))
+	for _, size := range Sizes {
+		out, err := encode(src, size)
+		if err != nil {
+			return nil, err
+		}
+		renditions = append(renditions, Rendition{Size: size, Data: out})
+	}
+	return renditions, nil
+}
+
+// squareCrop returns the largest centered square of img.
+func squareCrop(img image.Image) image.Image {
+	b := img.Bounds()
+	edge := min(b.Dx(), b.Dy())
+	x := b.Min.X + (b.Dx()-edge)/2
+	y := b.Min.Y + (b.Dy()-edge)/2
+	return subImage(img, image.Rect(x, y, x+edge, y+edge))
+}
+
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if s, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	return img
+}
+
+// encode scales src onto a white size×size canvas, which also flattens any
+// transparency since JPEG has none, and encodes it.
+func encode(src image.Image, size Size) ([]byte, error) {
+	dst := image.NewRGBA(image.Rect(0, 0, int(size), int(size)))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode avatar: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,68 @@
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+)
+
+func TestProcessCropsToSquares(t *testing.T) {
+	// A wide image, red on the left third, blue in the middle, green on the
+	// right: the centered square crop keeps only blue.
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for x := 0; x < 300; x++ {
+		c := color.RGBA{B: 255, A: 255}
+		if x < 100 {
+			c = color.RGBA{R: 255, A: 255}
+		} else if x >= 200 {
+			c = color.RGBA{G: 255, A: 255}
+		}
+		for y := 0; y < 100; y++ {
+			src.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, src, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	renditions, err := Process(&buf, 1<<20)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if len(renditions) != len(Sizes) {
+		t.Fatalf("got %d renditions, want %d", len(renditions), len(Sizes))
+	}
+	for i, r := range renditions {
+		img, err := jpeg.Decode(bytes.NewReader(r.Data))
+		if err != nil {
+			t.Fatalf("rendition %d: %v", r.Size, err)
+		}
+		if r.Size != Sizes[i] || img.Bounds().Dx() != int(r.Size) || img.Bounds().Dy() != int(r.Size) {
+			t.Errorf("rendition %d is %v", r.Size, img.Bounds())
+		}
+		for _, p := range []image.Point{{0, 0}, {int(r.Size) - 1, int(r.Size) / 2}} {
+			cr, cg, cb, _ := img.At(p.X, p.Y).RGBA()
+			if cb>>8 < 200 || cr>>8 > 60 || cg>>8 > 60 {
+				t.Errorf("rendition %d pixel %v = %d,%d,%d; want blue", r.Size, p, cr>>8, cg>>8, cb>>8)
+			}
+		}
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	if _, err := Process(bytes.NewReader(make([]byte, 101)), 100); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("oversized upload: %v", err)
+	}
+	if _, err := Process(bytes.NewReader([]byte("%PDF-1.7 not an image")), 100); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("PDF upload: %v", err)
+	}
+	// A PNG signature followed by garbage sniffs as PNG but does not decode.
+	if _, err := Process(bytes.NewReader([]byte("\x89PNG\r\n\x1a\ngarbage")), 100); err == nil {
+		t.Error("corrupt PNG accepted")
+	}
+}
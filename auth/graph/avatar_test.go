@@ -0,0 +1,180 @@
+package graph_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+)
+
+const uploadAvatar = `mutation($file: Upload!) { uploadAvatar(file: $file) { id small: avatarUrl(size: SMALL) medium: avatarUrl large: avatarUrl(size: LARGE) } }`
+
+type avatarResp struct {
+	ID     string
+	Small  *string
+	Medium *string
+	Large  *string
+}
+
+// writeFile writes data to a temporary file for client.WithFiles.
+func writeFile(t *testing.T, name string, data []byte) *os.File {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func pngImage(t *testing.T, w, h int, noise bool) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	rnd := rand.New(rand.NewSource(1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255}
+			if noise {
+				c = color.NRGBA{R: uint8(rnd.Intn(256)), G: uint8(rnd.Intn(256)), B: uint8(rnd.Intn(256)), A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func postAvatar(h *graphtest.Harness, s graphtest.Session, file *os.File) (avatarResp, error) {
+	var resp struct{ UploadAvatar avatarResp }
+	err := h.Post(uploadAvatar, &resp, client.Var("file", file), client.WithFiles(), s.Auth())
+	return resp.UploadAvatar, err
+}
+
+// fetchBlob GETs url from the harness blob store.
+func fetchBlob(t *testing.T, h *graphtest.Harness, url string) *httptest.ResponseRecorder {
+	t.Helper()
+	if !strings.HasPrefix(url, graphtest.BlobURL+"/") {
+		t.Fatalf("avatar URL %q is not in the blob store", url)
+	}
+	rec := httptest.NewRecorder()
+	h.Blobs.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, strings.TrimPrefix(url, graphtest.BlobURL), nil))
+	return rec
+}
+
+func TestUploadAvatarStoresResizedRenditions(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.LoginAsUser()
+
+	var before struct{ GetMe avatarResp }
+	h.MustPost(`query { getMe { id medium: avatarUrl } }`, &before, user.Auth())
+	if before.GetMe.Medium != nil {
+		t.Fatalf("avatarUrl before upload = %q", *before.GetMe.Medium)
+	}
+
+	got, err := postAvatar(h, user, writeFile(t, "me.png", pngImage(t, 300, 200, false)))
+	if err != nil {
+		t.Fatalf("uploadAvatar: %v", err)
+	}
+	for size, url := range map[int]*string{64: got.Small, 128: got.Medium, 256: got.Large} {
+		if url == nil {
+			t.Fatalf("no %dpx avatar URL", size)
+		}
+		rec := fetchBlob(t, h, *url)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d", *url, rec.Code)
+		}
+		img, err := jpeg.Decode(rec.Body)
+		if err != nil {
+			t.Fatalf("%dpx rendition is not a JPEG: %v", size, err)
+		}
+		if b := img.Bounds(); b.Dx() != size || b.Dy() != size {
+			t.Errorf("%dpx rendition is %dx%d", size, b.Dx(), b.Dy())
+		}
+	}
+
+	var after struct{ GetMe avatarResp }
+	h.MustPost(`query { getMe { id medium: avatarUrl } }`, &after, user.Auth())
+	if after.GetMe.Medium == nil || *after.GetMe.Medium != *got.Medium {
+		t.Fatalf("getMe avatarUrl = %v, want %s", after.GetMe.Medium, *got.Medium)
+	}
+}
+
+func TestUploadAvatarReplacesPreviousFiles(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.LoginAsUser()
+
+	first, err := postAvatar(h, user, writeFile(t, "a.png", pngImage(t, 64, 64, false)))
+	if err != nil {
+		t.Fatalf("first upload: %v", err)
+	}
+	second, err := postAvatar(h, user, writeFile(t, "b.png", pngImage(t, 80, 80, false)))
+	if err != nil {
+		t.Fatalf("second upload: %v", err)
+	}
+	if *first.Medium == *second.Medium {
+		t.Fatal("a new upload kept the old URL")
+	}
+	if rec := fetchBlob(t, h, *first.Medium); rec.Code != http.StatusNotFound {
+		t.Errorf("old avatar still served: %d", rec.Code)
+	}
+	if rec := fetchBlob(t, h, *second.Medium); rec.Code != http.StatusOK {
+		t.Errorf("new avatar not served: %d", rec.Code)
+	}
+}
+
+func TestUploadAvatarRejectsInvalidFiles(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.LoginAsUser()
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"text.png", []byte("definitely not an image, whatever the name says"), "unsupported image type"},
+		{"page.jpg", []byte("<!DOCTYPE html><html><body>hi</body></html>"), "unsupported image type"},
+		{"tiny.png", pngImage(t, 8, 8, false), "at least 16x16"},
+		{"big.png", pngImage(t, 400, 400, true), "too large"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := postAvatar(h, user, writeFile(t, tc.name, tc.data))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("uploadAvatar(%s) error = %v, want %q", tc.name, err, tc.want)
+			}
+		})
+	}
+
+	var me struct{ GetMe avatarResp }
+	h.MustPost(`query { getMe { id medium: avatarUrl } }`, &me, user.Auth())
+	if me.GetMe.Medium != nil {
+		t.Fatalf("rejected upload set avatarUrl %q", *me.GetMe.Medium)
+	}
+}
+
+func TestUploadAvatarRequiresAuth(t *testing.T) {
+	h := graphtest.New(t)
+	var resp struct{ UploadAvatar avatarResp }
+	err := h.Post(uploadAvatar, &resp, client.Var("file", writeFile(t, "me.png", pngImage(t, 32, 32, false))), client.WithFiles())
+	if err == nil {
+		t.Fatal("anonymous upload succeeded")
+	}
+}
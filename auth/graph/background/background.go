@@ -0,0 +1,91 @@
+// Package background runs work that must not delay the response, such as
+// sending sign-in links, on a fixed number of goroutines.
+package background
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/metrics"
+)
+
+var logger = logging.For("background")
+
+// Pool runs work on a fixed number of workers. Work waits in a bounded
+// queue; when the queue is full, new work is dropped rather than piling up,
+// so that a flood of requests cannot start unbounded goroutines.
+type Pool struct {
+	mu     sync.Mutex
+	closed bool
+	queue  chan func()
+	wg     sync.WaitGroup
+}
+
+// New starts workers goroutines that take work from a queue of queueSize.
+func New(workers, queueSize int) *Pool {
+	p := &Pool{queue: make(chan func(), queueSize)}
+	p.wg.Add(workers)
+	for range workers {
+		go p.work()
+	}
+	return p
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for f := range p.queue {
+		p.run(f)
+	}
+}
+
+// run keeps a panicking task from taking the worker down with it.
+func (p *Pool) run(f func()) {
+	defer func() {
+		if v := recover(); v != nil {
+			logger.Error("background task panicked", slog.Any("panic", v))
+		}
+	}()
+	f()
+}
+
+// Go queues f. It reports false if f was dropped because the queue is full
+// or the pool is shut down.
+func (p *Pool) Go(f func()) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		select {
+		case p.queue <- f:
+			return true
+		default:
+		}
+	}
+	metrics.BackgroundDroppedTotal.Inc()
+	logger.Warn("dropped background task", slog.Bool("shutting_down", p.closed))
+	return false
+}
+
+// Shutdown stops taking work and waits for the queued work to finish, or
+// for ctx to end.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,83 @@
+package background
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolDropsWorkBeyondTheQueue(t *testing.T) {
+	p := New(1, 1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var ran atomic.Int32
+
+	// One task occupies the worker and one waits in the queue.
+	if !p.Go(func() { close(started); <-release; ran.Add(1) }) {
+		t.Fatal("first task dropped")
+	}
+	<-started
+	if !p.Go(func() { ran.Add(1) }) {
+		t.Fatal("queued task dropped")
+	}
+	if p.Go(func() { ran.Add(1) }) {
+		t.Error("task beyond the queue was accepted")
+	}
+
+	close(release)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := ran.Load(); got != 2 {
+		t.Errorf("%d tasks ran; want the 2 accepted", got)
+	}
+	if p.Go(func() {}) {
+		t.Error("task accepted after Shutdown")
+	}
+}
+
+func TestShutdownWaitsForQueuedWork(t *testing.T) {
+	p := New(2, 10)
+	var ran atomic.Int32
+	for range 10 {
+		p.Go(func() { time.Sleep(time.Millisecond); ran.Add(1) })
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := ran.Load(); got != 10 {
+		t.Errorf("%d tasks ran before Shutdown returned; want 10", got)
+	}
+}
+
+func TestShutdownGivesUpWithTheContext(t *testing.T) {
+	p := New(1, 1)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	p.Go(func() { close(started); <-release })
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown = %v; want the deadline", err)
+	}
+}
+
+func TestPanickingTaskKeepsTheWorker(t *testing.T) {
+	p := New(1, 1)
+	p.Go(func() { panic("boom") })
+	var ran atomic.Bool
+	for !p.Go(func() { ran.Store(true) }) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if !ran.Load() {
+		t.Error("the task after a panic did not run")
+	}
+}
@@ -0,0 +1,18 @@
+// Package cache stores short-lived values by key, in process or in Redis.
+// It backs the read-through user cache of repos/cached.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache is a key-value store whose entries expire.
+type Cache interface {
+	// Get returns the value of key, and false if it is missing or expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the keys. Missing keys are ignored.
+	Delete(ctx context.Context, keys ...string) error
+}
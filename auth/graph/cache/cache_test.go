@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testCache checks the behaviour every Cache shares. advance moves the
+// cache's clock forward.
+func testCache(t *testing.T, c Cache, advance func(time.Duration)) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, ok, err := c.Get(ctx, "a"); ok || err != nil {
+		t.Fatalf("Get(a) on empty cache = %v, %v", ok, err)
+	}
+	if err := c.Set(ctx, "a", []byte("1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "b", []byte("2"), 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok, err := c.Get(ctx, "a"); !ok || err != nil || string(value) != "1" {
+		t.Fatalf("Get(a) = %q, %v, %v; want 1", value, ok, err)
+	}
+
+	advance(30 * time.Second)
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("Get(b) found an expired entry")
+	}
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Error("Get(a) lost an entry before its TTL")
+	}
+
+	if err := c.Set(ctx, "a", []byte("3"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if value, _, _ := c.Get(ctx, "a"); string(value) != "3" {
+		t.Errorf("Get(a) after overwrite = %q; want 3", value)
+	}
+
+	if err := c.Delete(ctx, "a", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Error("Get(a) found a deleted entry")
+	}
+}
+
+func TestLRU(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU(10, WithClock(func() time.Time { return now }))
+	testCache(t, c, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	c.Set(ctx, "a", []byte("1"), time.Minute)
+	c.Set(ctx, "b", []byte("2"), time.Minute)
+	c.Get(ctx, "a")
+	c.Set(ctx, "c", []byte("3"), time.Minute)
+
+	if c.Len() != 2 {
+		t.Errorf("Len = %d; want 2", c.Len())
+	}
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := c.Get(ctx, key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+}
+
+func TestRedis(t *testing.T) {
+	srv := miniredis.RunT(t)
+	c, err := OpenRedis(context.Background(), "redis://"+srv.Addr(), "auth:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	testCache(t, c, srv.FastForward)
+
+	c.Set(context.Background(), "k", []byte("v"), time.Minute)
+	if !srv.Exists("auth:k") {
+		t.Errorf("keys = %v; want auth:k", srv.Keys())
+	}
+}
+
+func TestRedisErrors(t *testing.T) {
+	srv := miniredis.RunT(t)
+	addr := "redis://" + srv.Addr()
+	c, err := OpenRedis(context.Background(), addr, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	srv.Close()
+
+	if _, ok, err := c.Get(context.Background(), "k"); ok || err == nil {
+		t.Errorf("Get with the server down = %v, %v; want an error", ok, err)
+	}
+	if _, err := OpenRedis(context.Background(), addr, ""); err == nil {
+		t.Error("OpenRedis with the server down succeeded")
+	}
+}
@@ -0,0 +1,611 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config is the typed configuration of the auth service. Values are read from
+// the environment (after loading .env) and can be overridden by flags.
+type Config struct {
+	Port string
+	// GRPCAddr is the interface the gRPC API for internal services listens
+	// on. It defaults to loopback; listening anywhere else requires
+	// GRPCToken.
+	GRPCAddr string
+	GRPCPort string
+	// GRPCToken is the shared secret gRPC callers send as a bearer token.
+	GRPCToken       string
+	ShutdownTimeout time.Duration
+	// DrainDelay is how long readiness fails on SIGTERM before the server
+	// stops accepting connections, so that load balancers notice first.
+	DrainDelay time.Duration
+	// PublicURL is the base URL of links sent to users, such as invitations.
+	PublicURL string
+	// AvatarMaxBytes caps the size of uploaded avatar images.
+	AvatarMaxBytes int64
+	// MagicLinkTTL is how long an emailed sign-in link stays valid.
+	MagicLinkTTL time.Duration
+	// BackgroundWorkers send emails such as sign-in links after the
+	// response. At most BackgroundQueue of them wait; more are dropped.
+	BackgroundWorkers int
+	BackgroundQueue   int
+	// TrustProxy takes the client IP from X-Forwarded-For. Enable it only
+	// behind a proxy that sets the header, or clients can forge their IP.
+	TrustProxy bool
+	// MetricOperations are the GraphQL operation names, such as those of
+	// the storefront, that metrics are labelled with. Others count as
+	// "other", so that clients cannot add series at will.
+	MetricOperations []string
+	Cookies          CookieConfig
+	DB               DBConfig
+	JWT              JWTConfig
+	Mail             MailConfig
+	Blob             BlobConfig
+	Cache            CacheConfig
+	Tracing          TracingConfig
+	Log              LogConfig
+}
+
+// CookieConfig configures the cookie session mode for browser clients that
+// should not keep tokens in script-readable storage.
+type CookieConfig struct {
+	// Enabled makes sign-ins also set HttpOnly token cookies, which then
+	// authenticate requests like the Authorization header. Mutations made
+	// with them need the double-submitted CSRF token.
+	Enabled bool
+	// Domain scopes the cookies, e.g. to share them with subdomains. Empty
+	// means the host of the request.
+	Domain string
+	// SameSite is lax, strict or none.
+	SameSite string
+}
+
+// SameSiteMode is the http.SameSite of SameSite.
+func (c CookieConfig) SameSiteMode() (http.SameSite, bool) {
+	switch c.SameSite {
+	case "lax":
+		return http.SameSiteLaxMode, true
+	case "strict":
+		return http.SameSiteStrictMode, true
+	case "none":
+		return http.SameSiteNoneMode, true
+	}
+	return 0, false
+}
+
+// LogConfig sets the default log level and per-component overrides, e.g.
+// LOG_LEVELS=gorm=warn,http=info.
+type LogConfig struct {
+	Level  slog.Level
+	Levels map[string]slog.Level
+}
+
+// Database backends selectable through DB_DRIVER.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// DBConfig configures the database connection and its pool.
+type DBConfig struct {
+	Driver          string
+	URL             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	MigrateOnStart  bool
+}
+
+// JWTConfig configures token signing and lifetimes.
+type JWTConfig struct {
+	Secret          string
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	InvitationTTL   time.Duration
+	// ImpersonationTTL is the lifetime of tokens issued by impersonate.
+	ImpersonationTTL time.Duration
+}
+
+// Mail drivers selectable through MAIL_DRIVER.
+const (
+	MailDriverLog  = "log"
+	MailDriverSMTP = "smtp"
+	MailDriverFile = "file"
+)
+
+// MailConfig configures how outgoing email is delivered.
+type MailConfig struct {
+	Driver       string
+	From         string
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	// Dir is where the file driver writes messages.
+	Dir string
+}
+
+// Blob stores selectable through BLOB_DRIVER.
+const (
+	BlobDriverLocal = "local"
+)
+
+// BlobConfig configures where uploaded files such as avatars are kept.
+type BlobConfig struct {
+	Driver string
+	// Dir is the root directory of the local driver.
+	Dir string
+	// BaseURL is where stored files are served from. It defaults to
+	// PublicURL + "/blobs", where the server mounts the local driver.
+	BaseURL string
+}
+
+// User caches selectable through CACHE_DRIVER.
+const (
+	CacheDriverNone   = "none"
+	CacheDriverMemory = "memory"
+	CacheDriverRedis  = "redis"
+)
+
+// CacheConfig configures the cache of user lookups.
+//
+// The memory driver keeps a cache per process, which writes made by other
+// servers or by the auth CLI do not invalidate. A suspended account can
+// then keep using its tokens on those servers for up to TTL. Use it only
+// with a single server; deployments with more use the redis driver.
+type CacheConfig struct {
+	Driver string
+	// TTL is how long a found user is cached.
+	TTL time.Duration
+	// NegativeTTL is how long a lookup that found no user is cached.
+	NegativeTTL time.Duration
+	// Size is the most users the memory driver keeps.
+	Size int
+	// RedisURL is the redis:// URL of the redis driver.
+	RedisURL string
+}
+
+// Trace exporters selectable through OTEL_TRACES_EXPORTER.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+// TracingConfig configures OpenTelemetry tracing.
+type TracingConfig struct {
+	Exporter     string
+	ServiceName  string
+	OTLPEndpoint string
+	OTLPInsecure bool
+	FilePath     string
+	SampleRatio  float64
+}
+
+const minJWTSecretLength = 32
+
+// Load reads .env if present, then the environment, then the flags in args,
+// and validates the result.
+func Load(args []string) (*Config, error) {
+	return LoadFlags(flag.NewFlagSet("auth", flag.ContinueOnError), args)
+}
+
+// LoadFlags is Load with the configuration flags added to fset, so that a
+// subcommand can parse its own flags along with them.
+func LoadFlags(fset *flag.FlagSet, args []string) (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load .env: %w", err)
+	}
+
+	env := &envReader{}
+	cfg := &Config{
+		Port:              env.String("PORT", "8080"),
+		GRPCAddr:          env.String("GRPC_ADDR", "127.0.0.1"),
+		GRPCPort:          env.String("GRPC_PORT", "9090"),
+		GRPCToken:         env.String("GRPC_TOKEN", ""),
+		ShutdownTimeout:   env.Duration("SHUTDOWN_TIMEOUT", 15*time.Second),
+		DrainDelay:        env.Duration("DRAIN_DELAY", 0),
+		PublicURL:         env.String("PUBLIC_URL", "http://localhost:8080"),
+		AvatarMaxBytes:    int64(env.Int("AVATAR_MAX_BYTES", 5<<20)),
+		MagicLinkTTL:      env.Duration("MAGIC_LINK_TTL", 10*time.Minute),
+		BackgroundWorkers: env.Int("BACKGROUND_WORKERS", 4),
+		BackgroundQueue:   env.Int("BACKGROUND_QUEUE", 100),
+		TrustProxy:        env.Bool("TRUST_PROXY", false),
+		Cookies: CookieConfig{
+			Enabled:  env.Bool("COOKIE_SESSIONS", false),
+			Domain:   env.String("COOKIE_DOMAIN", ""),
+			SameSite: strings.ToLower(env.String("COOKIE_SAMESITE", "lax")),
+		},
+		DB: DBConfig{
+			Driver:          env.String("DB_DRIVER", DriverPostgres),
+			URL:             env.String("DB_URL", ""),
+			MaxOpenConns:    env.Int("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    env.Int("DB_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime: env.Duration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+			MigrateOnStart:  env.Bool("DB_MIGRATE_ON_START", false),
+		},
+		JWT: JWTConfig{
+			Secret:           env.String("JWT_SECRET", ""),
+			Issuer:           env.String("JWT_ISSUER", "cloudmarket"),
+			AccessTokenTTL:   env.Duration("ACCESS_TOKEN_TTL", 24*time.Hour),
+			RefreshTokenTTL:  env.Duration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+			InvitationTTL:    env.Duration("INVITATION_TTL", 72*time.Hour),
+			ImpersonationTTL: env.Duration("IMPERSONATION_TTL", 15*time.Minute),
+		},
+		Mail: MailConfig{
+			Driver:       env.String("MAIL_DRIVER", MailDriverLog),
+			From:         env.String("MAIL_FROM", "CloudMarket <no-reply@cloudmarket.local>"),
+			SMTPAddr:     env.String("SMTP_ADDR", ""),
+			SMTPUsername: env.String("SMTP_USERNAME", ""),
+			SMTPPassword: env.String("SMTP_PASSWORD", ""),
+			Dir:          env.String("MAIL_DIR", "data/mail"),
+		},
+		Blob: BlobConfig{
+			Driver:  env.String("BLOB_DRIVER", BlobDriverLocal),
+			Dir:     env.String("BLOB_DIR", "data/blobs"),
+			BaseURL: env.String("BLOB_BASE_URL", ""),
+		},
+		Cache: CacheConfig{
+			Driver:      env.String("CACHE_DRIVER", CacheDriverNone),
+			TTL:         env.Duration("CACHE_TTL", time.Minute),
+			NegativeTTL: env.Duration("CACHE_NEGATIVE_TTL", 10*time.Second),
+			Size:        env.Int("CACHE_SIZE", 10000),
+			RedisURL:    env.String("REDIS_URL", ""),
+		},
+		Tracing: TracingConfig{
+			Exporter:     env.String("OTEL_TRACES_EXPORTER", ExporterNone),
+			ServiceName:  env.String("OTEL_SERVICE_NAME", "cloudmarket-auth"),
+			OTLPEndpoint: env.String("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318"),
+			OTLPInsecure: env.Bool("OTEL_EXPORTER_OTLP_INSECURE", false),
+			FilePath:     env.String("OTEL_TRACES_FILE", "traces.json"),
+			SampleRatio:  env.Float("OTEL_TRACES_SAMPLE_RATIO", 1),
+		},
+	}
+	cfg.MetricOperations = env.List("METRICS_OPERATIONS")
+	cfg.Log.Level = env.Level("LOG_LEVEL", slog.LevelInfo)
+	cfg.Log.Levels = env.Levels("LOG_LEVELS", map[string]slog.Level{"gorm": slog.LevelWarn})
+	if len(env.errs) > 0 {
+		return nil, errors.Join(env.errs...)
+	}
+
+	fset.StringVar(&cfg.Port, "port", cfg.Port, "HTTP port to listen on (PORT)")
+	fset.StringVar(&cfg.GRPCAddr, "grpc-addr", cfg.GRPCAddr, "interface the gRPC API listens on (GRPC_ADDR)")
+	fset.StringVar(&cfg.GRPCPort, "grpc-port", cfg.GRPCPort, "gRPC port for internal services to listen on (GRPC_PORT)")
+	fset.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "how long to drain in-flight requests on SIGTERM (SHUTDOWN_TIMEOUT)")
+	fset.DurationVar(&cfg.DrainDelay, "drain-delay", cfg.DrainDelay, "how long readiness fails on SIGTERM before the server stops accepting connections (DRAIN_DELAY)")
+	fset.StringVar(&cfg.PublicURL, "public-url", cfg.PublicURL, "base URL of links sent by email (PUBLIC_URL)")
+	fset.BoolVar(&cfg.TrustProxy, "trust-proxy", cfg.TrustProxy, "take the client IP from X-Forwarded-For (TRUST_PROXY)")
+	fset.BoolVar(&cfg.Cookies.Enabled, "cookie-sessions", cfg.Cookies.Enabled, "also sign browsers in with HttpOnly cookies (COOKIE_SESSIONS)")
+	fset.StringVar(&cfg.DB.Driver, "db-driver", cfg.DB.Driver, "repository backend: postgres, sqlite or memory (DB_DRIVER)")
+	fset.StringVar(&cfg.DB.URL, "db-url", cfg.DB.URL, "database connection URL, or file name for sqlite (DB_URL)")
+	fset.IntVar(&cfg.DB.MaxOpenConns, "db-max-open-conns", cfg.DB.MaxOpenConns, "maximum open database connections (DB_MAX_OPEN_CONNS)")
+	fset.IntVar(&cfg.DB.MaxIdleConns, "db-max-idle-conns", cfg.DB.MaxIdleConns, "maximum idle database connections (DB_MAX_IDLE_CONNS)")
+	fset.DurationVar(&cfg.DB.ConnMaxLifetime, "db-conn-max-lifetime", cfg.DB.ConnMaxLifetime, "maximum lifetime of a database connection (DB_CONN_MAX_LIFETIME)")
+	fset.BoolVar(&cfg.DB.MigrateOnStart, "migrate-on-start", cfg.DB.MigrateOnStart, "apply pending migrations before serving (DB_MIGRATE_ON_START)")
+	fset.StringVar(&cfg.JWT.Secret, "jwt-secret", cfg.JWT.Secret, "HMAC secret used to sign tokens (JWT_SECRET)")
+	fset.StringVar(&cfg.JWT.Issuer, "jwt-issuer", cfg.JWT.Issuer, "issuer claim of signed tokens (JWT_ISSUER)")
+	fset.DurationVar(&cfg.JWT.AccessTokenTTL, "access-token-ttl", cfg.JWT.AccessTokenTTL, "lifetime of access tokens (ACCESS_TOKEN_TTL)")
+	fset.DurationVar(&cfg.JWT.RefreshTokenTTL, "refresh-token-ttl", cfg.JWT.RefreshTokenTTL, "lifetime of refresh tokens (REFRESH_TOKEN_TTL)")
+	fset.DurationVar(&cfg.JWT.InvitationTTL, "invitation-ttl", cfg.JWT.InvitationTTL, "how long organization invitations stay valid (INVITATION_TTL)")
+	fset.DurationVar(&cfg.JWT.ImpersonationTTL, "impersonation-ttl", cfg.JWT.ImpersonationTTL, "lifetime of tokens issued to admins impersonating a user (IMPERSONATION_TTL)")
+	fset.DurationVar(&cfg.MagicLinkTTL, "magic-link-ttl", cfg.MagicLinkTTL, "how long emailed sign-in links stay valid (MAGIC_LINK_TTL)")
+	fset.IntVar(&cfg.BackgroundWorkers, "background-workers", cfg.BackgroundWorkers, "goroutines sending emails after the response (BACKGROUND_WORKERS)")
+	fset.IntVar(&cfg.BackgroundQueue, "background-queue", cfg.BackgroundQueue, "emails that may wait for a background worker before more are dropped (BACKGROUND_QUEUE)")
+	fset.StringVar(&cfg.Mail.Driver, "mail-driver", cfg.Mail.Driver, "mail delivery: log, smtp or file (MAIL_DRIVER)")
+	fset.StringVar(&cfg.Mail.Dir, "mail-dir", cfg.Mail.Dir, "directory the file driver writes messages to (MAIL_DIR)")
+	fset.StringVar(&cfg.Mail.SMTPAddr, "smtp-addr", cfg.Mail.SMTPAddr, "host:port of the SMTP server (SMTP_ADDR)")
+	fset.StringVar(&cfg.Blob.Dir, "blob-dir", cfg.Blob.Dir, "directory of the local blob store (BLOB_DIR)")
+	fset.Int64Var(&cfg.AvatarMaxBytes, "avatar-max-bytes", cfg.AvatarMaxBytes, "largest accepted avatar upload in bytes (AVATAR_MAX_BYTES)")
+	fset.StringVar(&cfg.Cache.Driver, "cache-driver", cfg.Cache.Driver, "user cache: none, memory or redis (CACHE_DRIVER)")
+	fset.StringVar(&cfg.Cache.RedisURL, "redis-url", cfg.Cache.RedisURL, "redis:// URL of the redis cache (REDIS_URL)")
+	fset.StringVar(&cfg.Tracing.Exporter, "trace-exporter", cfg.Tracing.Exporter, "trace exporter: none, stdout, file or otlp (OTEL_TRACES_EXPORTER)")
+	fset.StringVar(&cfg.Tracing.OTLPEndpoint, "otlp-endpoint", cfg.Tracing.OTLPEndpoint, "host:port of the OTLP/HTTP collector (OTEL_EXPORTER_OTLP_ENDPOINT)")
+	fset.StringVar(&cfg.Tracing.FilePath, "trace-file", cfg.Tracing.FilePath, "file the file exporter appends spans to (OTEL_TRACES_FILE)")
+	if err := fset.Parse(args); err != nil {
+		return nil, err
+	}
+	if cfg.Blob.BaseURL == "" {
+		cfg.Blob.BaseURL = strings.TrimRight(cfg.PublicURL, "/") + "/blobs"
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT: %q is not a valid port", c.Port))
+	}
+	if port, err := strconv.Atoi(c.GRPCPort); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("GRPC_PORT: %q is not a valid port", c.GRPCPort))
+	} else if c.GRPCPort == c.Port {
+		errs = append(errs, errors.New("GRPC_PORT: must differ from PORT"))
+	}
+	if c.GRPCToken == "" && !isLoopback(c.GRPCAddr) {
+		errs = append(errs, fmt.Errorf("GRPC_TOKEN: is required when GRPC_ADDR %q is not a loopback address", c.GRPCAddr))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT: must be positive"))
+	}
+	if c.DrainDelay < 0 {
+		errs = append(errs, errors.New("DRAIN_DELAY: must not be negative"))
+	}
+	if u, err := url.Parse(c.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, errors.New("PUBLIC_URL: must be an http:// or https:// URL"))
+	}
+
+	if _, ok := c.Cookies.SameSiteMode(); !ok {
+		errs = append(errs, fmt.Errorf("COOKIE_SAMESITE: %q must be one of lax, strict or none", c.Cookies.SameSite))
+	}
+
+	switch c.DB.Driver {
+	case DriverPostgres:
+		if c.DB.URL == "" {
+			errs = append(errs, errors.New("DB_URL: is required"))
+		} else if u, err := url.Parse(c.DB.URL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			errs = append(errs, errors.New("DB_URL: must be a postgres:// URL"))
+		}
+	case DriverSQLite:
+		if c.DB.URL == "" {
+			errs = append(errs, errors.New("DB_URL: is required, use :memory: for a throwaway database"))
+		}
+	case DriverMemory:
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER: %q must be one of postgres, sqlite or memory", c.DB.Driver))
+	}
+	if c.DB.MaxOpenConns < 0 {
+		errs = append(errs, errors.New("DB_MAX_OPEN_CONNS: must not be negative"))
+	}
+	if c.DB.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("DB_MAX_IDLE_CONNS: must not be negative"))
+	}
+	if c.DB.MaxOpenConns > 0 && c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		errs = append(errs, errors.New("DB_MAX_IDLE_CONNS: must not exceed DB_MAX_OPEN_CONNS"))
+	}
+	if c.DB.ConnMaxLifetime < 0 {
+		errs = append(errs, errors.New("DB_CONN_MAX_LIFETIME: must not be negative"))
+	}
+
+	if len(c.JWT.Secret) < minJWTSecretLength {
+		errs = append(errs, fmt.Errorf("JWT_SECRET: must be at least %d bytes", minJWTSecretLength))
+	}
+	if c.JWT.Issuer == "" {
+		errs = append(errs, errors.New("JWT_ISSUER: is required"))
+	}
+	if c.JWT.AccessTokenTTL <= 0 {
+		errs = append(errs, errors.New("ACCESS_TOKEN_TTL: must be positive"))
+	}
+	if c.JWT.RefreshTokenTTL < c.JWT.AccessTokenTTL {
+		errs = append(errs, errors.New("REFRESH_TOKEN_TTL: must not be shorter than ACCESS_TOKEN_TTL"))
+	}
+	if c.JWT.InvitationTTL <= 0 {
+		errs = append(errs, errors.New("INVITATION_TTL: must be positive"))
+	}
+	if c.JWT.ImpersonationTTL <= 0 || c.JWT.ImpersonationTTL > c.JWT.AccessTokenTTL {
+		errs = append(errs, errors.New("IMPERSONATION_TTL: must be positive and not longer than ACCESS_TOKEN_TTL"))
+	}
+
+	if c.MagicLinkTTL <= 0 || c.MagicLinkTTL > time.Hour {
+		errs = append(errs, errors.New("MAGIC_LINK_TTL: must be positive and at most 1h"))
+	}
+	if c.BackgroundWorkers <= 0 {
+		errs = append(errs, errors.New("BACKGROUND_WORKERS: must be positive"))
+	}
+	if c.BackgroundQueue < 0 {
+		errs = append(errs, errors.New("BACKGROUND_QUEUE: must not be negative"))
+	}
+
+	switch c.Mail.Driver {
+	case MailDriverLog:
+	case MailDriverSMTP:
+		if c.Mail.SMTPAddr == "" {
+			errs = append(errs, errors.New("SMTP_ADDR: is required for the smtp driver"))
+		}
+	case MailDriverFile:
+		if c.Mail.Dir == "" {
+			errs = append(errs, errors.New("MAIL_DIR: is required for the file driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("MAIL_DRIVER: %q must be one of log, smtp or file", c.Mail.Driver))
+	}
+	if c.Mail.From == "" {
+		errs = append(errs, errors.New("MAIL_FROM: is required"))
+	}
+
+	switch c.Blob.Driver {
+	case BlobDriverLocal:
+		if c.Blob.Dir == "" {
+			errs = append(errs, errors.New("BLOB_DIR: is required for the local driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("BLOB_DRIVER: %q must be local", c.Blob.Driver))
+	}
+	if u, err := url.Parse(c.Blob.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, errors.New("BLOB_BASE_URL: must be an http:// or https:// URL"))
+	}
+	if c.AvatarMaxBytes <= 0 {
+		errs = append(errs, errors.New("AVATAR_MAX_BYTES: must be positive"))
+	}
+
+	switch c.Cache.Driver {
+	case CacheDriverNone:
+	case CacheDriverMemory:
+		if c.Cache.Size <= 0 {
+			errs = append(errs, errors.New("CACHE_SIZE: must be positive"))
+		}
+	case CacheDriverRedis:
+		if u, err := url.Parse(c.Cache.RedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+			errs = append(errs, errors.New("REDIS_URL: must be a redis:// or rediss:// URL"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("CACHE_DRIVER: %q must be one of none, memory or redis", c.Cache.Driver))
+	}
+	if c.Cache.Driver != CacheDriverNone {
+		if c.Cache.TTL <= 0 {
+			errs = append(errs, errors.New("CACHE_TTL: must be positive"))
+		}
+		if c.Cache.NegativeTTL < 0 {
+			errs = append(errs, errors.New("CACHE_NEGATIVE_TTL: must not be negative"))
+		}
+	}
+
+	switch c.Tracing.Exporter {
+	case ExporterNone, ExporterStdout:
+	case ExporterFile:
+		if c.Tracing.FilePath == "" {
+			errs = append(errs, errors.New("OTEL_TRACES_FILE: is required for the file exporter"))
+		}
+	case ExporterOTLP:
+		if c.Tracing.OTLPEndpoint == "" {
+			errs = append(errs, errors.New("OTEL_EXPORTER_OTLP_ENDPOINT: is required for the otlp exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("OTEL_TRACES_EXPORTER: %q must be one of none, stdout, file or otlp", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("OTEL_TRACES_SAMPLE_RATIO: must be between 0 and 1"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// isLoopback reports whether host only accepts connections from this
+// machine.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// envReader reads typed environment variables and collects parse errors so
+// they can be reported together.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) String(key, def string) string {
+	if value, ok := os.LookupEnv(key); ok && strings.TrimSpace(value) != "" {
+		return strings.TrimSpace(value)
+	}
+	return def
+}
+
+// List reads a comma-separated list, dropping empty items.
+func (e *envReader) List(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (e *envReader) Int(key string, def int) int {
+	value := e.String(key, "")
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not an integer", key, value))
+		return def
+	}
+	return n
+}
+
+func (e *envReader) Bool(key string, def bool) bool {
+	value := e.String(key, "")
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a boolean", key, value))
+		return def
+	}
+	return b
+}
+
+func (e *envReader) Float(key string, def float64) float64 {
+	value := e.String(key, "")
+	if value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a number", key, value))
+		return def
+	}
+	return f
+}
+
+func (e *envReader) Level(key string, def slog.Level) slog.Level {
+	value := e.String(key, "")
+	if value == "" {
+		return def
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a log level", key, value))
+		return def
+	}
+	return level
+}
+
+// Levels parses a comma separated list of component=level pairs.
+func (e *envReader) Levels(key string, def map[string]slog.Level) map[string]slog.Level {
+	value := e.String(key, "")
+	if value == "" {
+		return def
+	}
+	levels := map[string]slog.Level{}
+	for _, pair := range strings.Split(value, ",") {
+		component, name, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		var level slog.Level
+		if !ok || component == "" || level.UnmarshalText([]byte(name)) != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: %q is not a component=level pair", key, pair))
+			continue
+		}
+		levels[component] = level
+	}
+	return levels
+}
+
+func (e *envReader) Duration(key string, def time.Duration) time.Duration {
+	value := e.String(key, "")
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a duration", key, value))
+		return def
+	}
+	return d
+}
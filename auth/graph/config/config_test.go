@@ -0,0 +1,155 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// dotenvKeys are the variables the tests set through .env or the
+// environment.
+var dotenvKeys = []string{"PORT", "GRPC_PORT", "JWT_ISSUER", "JWT_SECRET", "DB_DRIVER", "ACCESS_TOKEN_TTL"}
+
+// cleanEnv runs the test in an empty directory, so that no .env is read,
+// with dotenvKeys unset. godotenv sets the variables of .env in the process
+// environment; the cleanup of t.Setenv removes them again.
+func cleanEnv(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, key := range dotenvKeys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	return dir
+}
+
+// validConfig loads the defaults with the settings that have none.
+func validConfig(t *testing.T) *Config {
+	t.Helper()
+	cleanEnv(t)
+	cfg, err := Load([]string{"-db-driver", DriverMemory, "-jwt-secret", testSecret})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   string
+	}{
+		{"defaults", func(c *Config) {}, ""},
+		{"missing JWT secret", func(c *Config) { c.JWT.Secret = "" }, "JWT_SECRET: must be at least 32 bytes"},
+		{"short JWT secret", func(c *Config) { c.JWT.Secret = "short" }, "JWT_SECRET: must be at least 32 bytes"},
+		{"missing issuer", func(c *Config) { c.JWT.Issuer = "" }, "JWT_ISSUER: is required"},
+		{"zero access token TTL", func(c *Config) { c.JWT.AccessTokenTTL = 0 }, "ACCESS_TOKEN_TTL: must be positive"},
+		{"refresh shorter than access", func(c *Config) { c.JWT.RefreshTokenTTL = time.Hour }, "REFRESH_TOKEN_TTL: must not be shorter"},
+		{"impersonation longer than access", func(c *Config) { c.JWT.ImpersonationTTL = 48 * time.Hour }, "IMPERSONATION_TTL"},
+		{"negative shutdown timeout", func(c *Config) { c.ShutdownTimeout = -time.Second }, "SHUTDOWN_TIMEOUT: must be positive"},
+		{"negative drain delay", func(c *Config) { c.DrainDelay = -time.Second }, "DRAIN_DELAY: must not be negative"},
+		{"long magic links", func(c *Config) { c.MagicLinkTTL = 2 * time.Hour }, "MAGIC_LINK_TTL"},
+		{"no background workers", func(c *Config) { c.BackgroundWorkers = 0 }, "BACKGROUND_WORKERS: must be positive"},
+		{"negative background queue", func(c *Config) { c.BackgroundQueue = -1 }, "BACKGROUND_QUEUE"},
+		{"bad port", func(c *Config) { c.Port = "http" }, `PORT: "http" is not a valid port`},
+		{"same ports", func(c *Config) { c.GRPCPort = c.Port }, "GRPC_PORT: must differ from PORT"},
+		{"gRPC off loopback without a token", func(c *Config) { c.GRPCAddr = "0.0.0.0" }, "GRPC_TOKEN: is required"},
+		{"gRPC off loopback with a token", func(c *Config) { c.GRPCAddr, c.GRPCToken = "0.0.0.0", "token" }, ""},
+		{"gRPC on localhost", func(c *Config) { c.GRPCAddr = "localhost" }, ""},
+		{"gRPC on IPv6 loopback", func(c *Config) { c.GRPCAddr = "::1" }, ""},
+		{"relative public URL", func(c *Config) { c.PublicURL = "/auth" }, "PUBLIC_URL"},
+		{"unknown SameSite", func(c *Config) { c.Cookies.SameSite = "sometimes" }, "COOKIE_SAMESITE"},
+		{"unknown DB driver", func(c *Config) { c.DB.Driver = "mysql" }, `DB_DRIVER: "mysql" must be one of`},
+		{"postgres without URL", func(c *Config) { c.DB.Driver = DriverPostgres }, "DB_URL: is required"},
+		{"postgres with another URL", func(c *Config) { c.DB.Driver, c.DB.URL = DriverPostgres, "mysql://db" }, "DB_URL: must be a postgres:// URL"},
+		{"sqlite without URL", func(c *Config) { c.DB.Driver = DriverSQLite }, "use :memory:"},
+		{"more idle than open connections", func(c *Config) { c.DB.MaxOpenConns, c.DB.MaxIdleConns = 2, 3 }, "DB_MAX_IDLE_CONNS: must not exceed"},
+		{"unknown mail driver", func(c *Config) { c.Mail.Driver = "pigeon" }, "MAIL_DRIVER"},
+		{"smtp without address", func(c *Config) { c.Mail.Driver = MailDriverSMTP }, "SMTP_ADDR: is required"},
+		{"unknown blob driver", func(c *Config) { c.Blob.Driver = "s3" }, "BLOB_DRIVER"},
+		{"unknown cache driver", func(c *Config) { c.Cache.Driver = "memcached" }, "CACHE_DRIVER"},
+		{"redis without URL", func(c *Config) { c.Cache.Driver = CacheDriverRedis }, "REDIS_URL"},
+		{"zero cache TTL", func(c *Config) { c.Cache.Driver, c.Cache.TTL = CacheDriverMemory, 0 }, "CACHE_TTL: must be positive"},
+		{"unknown exporter", func(c *Config) { c.Tracing.Exporter = "zipkin" }, "OTEL_TRACES_EXPORTER"},
+		{"sample ratio above 1", func(c *Config) { c.Tracing.SampleRatio = 2 }, "OTEL_TRACES_SAMPLE_RATIO"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig(t)
+			tt.change(cfg)
+			err := cfg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Validate = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate = %v; want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryError(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.JWT.Secret = ""
+	cfg.DB.Driver = "mysql"
+	err := cfg.Validate()
+	for _, want := range []string{"JWT_SECRET", "DB_DRIVER"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate = %v; want an error for %s", err, want)
+		}
+	}
+}
+
+func TestLoadRejectsBadValues(t *testing.T) {
+	cleanEnv(t)
+	t.Setenv("ACCESS_TOKEN_TTL", "a day")
+	t.Setenv("PORT", "80a")
+	_, err := Load([]string{"-db-driver", DriverMemory, "-jwt-secret", testSecret})
+	if err == nil || !strings.Contains(err.Error(), `ACCESS_TOKEN_TTL: "a day" is not a duration`) {
+		t.Errorf("Load = %v; want the bad duration reported", err)
+	}
+
+	cleanEnv(t)
+	_, err = Load([]string{"-db-driver", DriverMemory, "-jwt-secret", testSecret, "-access-token-ttl", "a day"})
+	if err == nil {
+		t.Error("Load with a bad duration flag succeeded")
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := cleanEnv(t)
+	dotenv := "PORT=1111\nGRPC_PORT=2222\nJWT_ISSUER=dotenv\nDB_DRIVER=memory\nJWT_SECRET=" + testSecret + "\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(dotenv), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PORT", "3333")
+	t.Setenv("JWT_ISSUER", "env")
+
+	cfg, err := Load([]string{"-port", "4444"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// Flags win over the environment, which wins over .env.
+	if cfg.Port != "4444" {
+		t.Errorf("Port = %q; want the flag", cfg.Port)
+	}
+	if cfg.JWT.Issuer != "env" {
+		t.Errorf("Issuer = %q; want the environment", cfg.JWT.Issuer)
+	}
+	if cfg.GRPCPort != "2222" || cfg.DB.Driver != DriverMemory {
+		t.Errorf("GRPCPort = %q, DB driver = %q; want .env", cfg.GRPCPort, cfg.DB.Driver)
+	}
+	// Unset everywhere, the default applies.
+	if cfg.JWT.AccessTokenTTL != 24*time.Hour {
+		t.Errorf("AccessTokenTTL = %v; want the default", cfg.JWT.AccessTokenTTL)
+	}
+}
@@ -2,11 +2,11 @@ package config
 
 import (
 	"fmt"
-	"log"
-	"os"
+	"strings"
 	"time"
 
-	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/glebarez/sqlite"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -17,19 +17,54 @@ var (
 	DB *gorm.DB
 )
 
-func InitDB() *gorm.DB {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		"localhost", "5432", "admin", "admin123", "app", "disable",
-	)
-	DB, err := gorm.Open(postgres.Open(dsn), initConfig())
+// InitDB opens the database described by cfg, applies the pool settings and
+// checks that the server is reachable. The memory driver has no database.
+func InitDB(cfg DBConfig) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case DriverPostgres:
+		dialector = postgres.Open(cfg.URL)
+	case DriverSQLite:
+		dialector = sqlite.Open(sqliteDSN(cfg.URL))
+		// Every connection to :memory: is a separate, empty database.
+		if cfg.URL == ":memory:" {
+			cfg.MaxOpenConns, cfg.MaxIdleConns, cfg.ConnMaxLifetime = 1, 1, 0
+		}
+	default:
+		return nil, fmt.Errorf("driver %q has no database", cfg.Driver)
+	}
+
+	db, err := gorm.Open(dialector, initConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("failed to get database pool: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	if err := sqlDB.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	DB.AutoMigrate(&model.User{})
-	fmt.Println("Database migrated")
-	return DB
+	DB = db
+	return db, nil
+}
+
+// sqliteDSN turns on foreign keys for every connection, which SQLite leaves
+// off by default, so ON DELETE CASCADE behaves as it does on Postgres.
+func sqliteDSN(url string) string {
+	if strings.Contains(url, "foreign_keys") {
+		return url
+	}
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+	return url + sep + "_pragma=foreign_keys(1)"
 }
 
 // InitConfig Initialize Config
@@ -42,14 +77,7 @@ func initConfig() *gorm.Config {
 
 // InitLog Connection Log Configuration
 func initLog() logger.Interface {
-
-	newLogger := logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags),
-		logger.Config{
-			Colorful:      true,
-			LogLevel:      logger.Info,
-			SlowThreshold: time.Second,
-		})
-	return newLogger
+	return logging.NewGormLogger(time.Second)
 }
 // InitNamingStrategy Init NamingStrategy
 func initNamingStrategy() *schema.NamingStrategy {
@@ -0,0 +1,189 @@
+package graph_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// browser is a client of the harness in the cookie session mode that keeps
+// cookies like a browser does.
+type browser struct {
+	t      *testing.T
+	srv    *httptest.Server
+	client *http.Client
+	// origin, if set, is sent as the Origin of requests, as by a page there.
+	origin string
+}
+
+func newBrowser(t *testing.T, h *graphtest.Harness) *browser {
+	t.Helper()
+	srv := httptest.NewTLSServer(middleware.CookieSessions(middleware.CookieOptions{SameSite: http.SameSiteStrictMode})(h.Handler))
+	t.Cleanup(srv.Close)
+	client := srv.Client()
+	client.Jar, _ = cookiejar.New(nil)
+	return &browser{t: t, srv: srv, client: client}
+}
+
+// post runs query and returns the response, sending csrf in the CSRF header
+// if it is set.
+func (b *browser) post(query string, vars map[string]any, csrf string) (*http.Response, map[string]any, []string) {
+	b.t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": vars})
+	req, _ := http.NewRequest(http.MethodPost, b.srv.URL+"/query", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if csrf != "" {
+		req.Header.Set(middleware.CSRFHeader, csrf)
+	}
+	if b.origin != "" {
+		req.Header.Set("Origin", b.origin)
+	}
+	res, err := b.client.Do(req)
+	if err != nil {
+		b.t.Fatalf("post: %v", err)
+	}
+	defer res.Body.Close()
+	var resp struct {
+		Data   map[string]any
+		Errors []struct{ Message string }
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		b.t.Fatalf("decode: %v", err)
+	}
+	var errs []string
+	for _, e := range resp.Errors {
+		errs = append(errs, e.Message)
+	}
+	return res, resp.Data, errs
+}
+
+func (b *browser) cookie(name string) string {
+	u, _ := url.Parse(b.srv.URL)
+	for _, c := range b.client.Jar.Cookies(u) {
+		if c.Name == name {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+const loginMutation = `mutation($email: String!, $password: String!) {
+	login(email: $email, password: $password) { token refreshToken }
+}`
+
+func TestLoginSetsSessionCookies(t *testing.T) {
+	h := graphtest.New(t)
+	b := newBrowser(t, h)
+	h.CreateUser("ada@example.com", model.RoleUser)
+
+	res, data, errs := b.post(loginMutation, map[string]any{"email": "ada@example.com", "password": graphtest.Password}, "")
+	if len(errs) > 0 {
+		t.Fatalf("login: %v", errs)
+	}
+	// The tokens are only in the HttpOnly cookies, out of reach of scripts.
+	if login := data["login"].(map[string]any); login["token"] != "" || login["refreshToken"] != "" {
+		t.Errorf("login payload = %v; want no tokens", login)
+	}
+	cookies := map[string]*http.Cookie{}
+	for _, c := range res.Cookies() {
+		cookies[c.Name] = c
+	}
+	for name, httpOnly := range map[string]bool{middleware.AccessCookie: true, middleware.RefreshCookie: true, middleware.CSRFCookie: false} {
+		c := cookies[name]
+		if c == nil || c.Value == "" {
+			t.Errorf("cookie %s not set", name)
+			continue
+		}
+		if !c.Secure || c.HttpOnly != httpOnly || c.SameSite != http.SameSiteStrictMode || c.MaxAge <= 0 {
+			t.Errorf("cookie %s = %+v", name, c)
+		}
+	}
+}
+
+func TestCookieSessionNeedsCSRFForMutations(t *testing.T) {
+	h := graphtest.New(t)
+	b := newBrowser(t, h)
+	h.CreateUser("ada@example.com", model.RoleUser)
+	b.post(loginMutation, map[string]any{"email": "ada@example.com", "password": graphtest.Password}, "")
+
+	// Queries are authenticated by the cookie alone.
+	_, data, errs := b.post(`query { getMe { email } }`, nil, "")
+	if len(errs) > 0 || data["getMe"].(map[string]any)["email"] != "ada@example.com" {
+		t.Fatalf("getMe = %v, %v", data, errs)
+	}
+
+	const rename = `mutation { updateUser(email: "ada@example.com", input: {firstName: "Ada", lastName: "Lovelace", email: "ada@example.com", password: "correct horse battery staple"}) }`
+	for name, csrf := range map[string]string{"without": "", "with a wrong": "forged"} {
+		if _, _, errs := b.post(rename, nil, csrf); len(errs) == 0 || !strings.Contains(errs[0], "CSRF") {
+			t.Errorf("mutation %s CSRF token: %v", name, errs)
+		}
+	}
+	if _, _, errs := b.post(rename, nil, b.cookie(middleware.CSRFCookie)); len(errs) > 0 {
+		t.Errorf("mutation with the CSRF token: %v", errs)
+	}
+
+	// Logging out clears the cookies; the browser is then anonymous.
+	if _, _, errs := b.post(`mutation { logout }`, nil, b.cookie(middleware.CSRFCookie)); len(errs) > 0 {
+		t.Fatalf("logout: %v", errs)
+	}
+	if b.cookie(middleware.AccessCookie) != "" || b.cookie(middleware.CSRFCookie) != "" {
+		t.Errorf("cookies left after logout")
+	}
+	if _, _, errs := b.post(`query { getMe { email } }`, nil, ""); len(errs) == 0 {
+		t.Errorf("getMe after logout succeeded")
+	}
+}
+
+func TestCookiesIgnoredWhenDisabled(t *testing.T) {
+	h := graphtest.New(t)
+	s := h.LoginAsUser()
+
+	var resp map[string]any
+	err := h.Post(`query { getMe { id } }`, &resp, func(bd *client.Request) {
+		bd.HTTP.AddCookie(&http.Cookie{Name: middleware.AccessCookie, Value: s.Token})
+	})
+	if err == nil {
+		t.Errorf("getMe with a cookie succeeded without the cookie session mode")
+	}
+}
+
+func TestCrossSiteLoginIsRefused(t *testing.T) {
+	h := graphtest.New(t)
+	h.CreateUser("ada@example.com", model.RoleUser)
+	b := newBrowser(t, h)
+	vars := map[string]any{"email": "ada@example.com", "password": graphtest.Password}
+
+	// A form on another site must not sign the browser in.
+	b.origin = "https://attacker.example"
+	if _, _, errs := b.post(loginMutation, vars, ""); len(errs) == 0 || !strings.Contains(errs[0], "cross-site sign-in refused") {
+		t.Errorf("cross-site login errors = %v", errs)
+	}
+	register := `mutation { register(input: {firstName: "Eve", lastName: "E", email: "eve@example.com", password: "secret"}) { token } }`
+	if _, _, errs := b.post(register, nil, ""); len(errs) == 0 {
+		t.Error("cross-site register succeeded")
+	}
+	if b.cookie(middleware.AccessCookie) != "" {
+		t.Fatal("cross-site sign-in set the session cookie")
+	}
+	if user, _ := h.Repo.UserByEmail(t.Context(), "eve@example.com"); user != nil {
+		t.Error("cross-site register created an account")
+	}
+
+	b.origin = b.srv.URL
+	if _, _, errs := b.post(loginMutation, vars, ""); len(errs) > 0 {
+		t.Fatalf("same-origin login: %v", errs)
+	}
+	if b.cookie(middleware.AccessCookie) == "" {
+		t.Error("same-origin login did not set the session cookie")
+	}
+}
@@ -0,0 +1,37 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// csrfProtection is a gqlgen extension that refuses mutations authenticated
+// by the session cookie without the double-submitted CSRF token. Queries
+// still run: a cross-site page cannot read their responses.
+type csrfProtection struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = csrfProtection{}
+
+func (csrfProtection) ExtensionName() string {
+	return "CSRFProtection"
+}
+
+func (csrfProtection) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (csrfProtection) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !middleware.CSRFFailed(ctx) || !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	if oc := graphql.GetOperationContext(ctx); oc.Operation != nil && oc.Operation.Operation == ast.Mutation {
+		return graphql.ErrorResponse(ctx, "missing or invalid CSRF token: send the %s cookie in the %s header", middleware.CSRFCookie, middleware.CSRFHeader)
+	}
+	return next(ctx)
+}
@@ -0,0 +1,65 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.80
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// FindManyUserByIDs is the resolver for the findManyUserByIDs field.
+func (r *entityResolver) FindManyUserByIDs(ctx context.Context, reps []*model.UserByIDsInput) ([]*model.User, error) {
+	// _entities is not covered by @auth, so the gateway must forward the
+	// caller's token, as user(id) requires.
+	if middleware.CtxValue(ctx) == nil {
+		return nil, apperr.Unauthenticated("Access Denied")
+	}
+	// maxEntities is the most users one query resolves, matching the batch
+	// limit of the gRPC API.
+	const maxEntities = 100
+	if len(reps) > maxEntities {
+		return nil, apperr.Validation("at most %d users can be resolved at once", maxEntities)
+	}
+	ids := make([]string, len(reps))
+	for i, rep := range reps {
+		ids[i] = rep.ID
+	}
+	users, err := r.UserByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users by ids: %w", err)
+	}
+	byID := make(map[string]*model.UserModel, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	// Entities are returned in the order of the representations, with nil
+	// for users that do not exist or, as with user(id), are outside the
+	// active organization.
+	entities := make([]*model.User, len(reps))
+	for i, id := range ids {
+		user := byID[id]
+		if user == nil {
+			continue
+		}
+		if err := r.checkVisible(ctx, id); err != nil {
+			if apperr.CodeOf(err) == apperr.CodeNotFound {
+				continue
+			}
+			return nil, err
+		}
+		entities[i] = model.ConvertToGraphQLUser(*user)
+	}
+	return entities, nil
+}
+
+// Entity returns EntityResolver implementation.
+func (r *Resolver) Entity() EntityResolver { return &entityResolver{r} }
+
+type entityResolver struct{ *Resolver }
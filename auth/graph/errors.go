@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+var errorLogger = logging.For("graphql")
+
+// internalMessage replaces the message of errors that are not meant for
+// clients.
+const internalMessage = "internal server error"
+
+// presentError sets extensions.code on every error. apperr errors keep
+// their message, and so do errors raised by gqlgen itself, such as invalid
+// arguments, which are BAD_USER_INPUT. Any other error is internal: it is
+// logged and its message hidden, so that database and other failures do not
+// leak to clients.
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	presented := graphql.DefaultErrorPresenter(ctx, err)
+	code := apperr.CodeOf(err)
+	if !apperr.Is(err) {
+		var gqlErr *gqlerror.Error
+		if errors.As(err, &gqlErr) && gqlErr.Err == nil || argumentError(ctx) {
+			code = apperr.CodeValidation
+			if c, ok := presented.Extensions["code"].(string); ok {
+				code = apperr.Code(c)
+			}
+		} else {
+			errorLogger.ErrorContext(ctx, "resolver failed", slog.String("path", presented.Path.String()), slog.Any("error", err))
+			presented.Message = internalMessage
+		}
+	}
+	if presented.Extensions == nil {
+		presented.Extensions = map[string]any{}
+	}
+	presented.Extensions["code"] = string(code)
+	return presented
+}
+
+// argumentError reports whether err was raised while gqlgen parsed the
+// arguments of the field, which are only set on its context once parsed.
+func argumentError(ctx context.Context) bool {
+	fc := graphql.GetFieldContext(ctx)
+	return fc != nil && fc.Field.Field != nil && len(fc.Field.Arguments) > 0 && fc.Args == nil
+}
+
+// recoverPanic logs a panic in a resolver with its stack and fails the
+// field with an internal error.
+func recoverPanic(ctx context.Context, p any) error {
+	errorLogger.ErrorContext(ctx, "resolver panicked", slog.String("panic", fmt.Sprint(p)), slog.String("stack", string(debug.Stack())))
+	return apperr.Internal(internalMessage)
+}
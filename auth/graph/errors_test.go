@@ -0,0 +1,96 @@
+package graph_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/memory"
+)
+
+type presentedError struct {
+	Message    string
+	Extensions struct{ Code string }
+}
+
+// postError runs query, which must fail, and returns its first error.
+func postError(t *testing.T, h *graphtest.Harness, query string, opts ...client.Option) presentedError {
+	t.Helper()
+	var resp map[string]any
+	err := h.Post(query, &resp, opts...)
+	if err == nil {
+		t.Fatalf("%s succeeded", query)
+	}
+	var errs []presentedError
+	if jsonErr := json.Unmarshal([]byte(err.Error()), &errs); jsonErr != nil || len(errs) == 0 {
+		t.Fatalf("%s: unexpected error %v", query, err)
+	}
+	return errs[0]
+}
+
+func TestErrorCodes(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.LoginAsUser()
+	admin := h.LoginAsAdmin()
+
+	tests := []struct {
+		name, query string
+		opts        []client.Option
+		code, msg   string
+	}{
+		{"unauthenticated", `query { getMe { id } }`, nil, "UNAUTHENTICATED", "Access Denied"},
+		{"not found", `query { user(id: "missing") { id } }`, []client.Option{admin.Auth()}, "NOT_FOUND", "user not found"},
+		{"unknown email", `query { userEmail(email: "nobody@example.com") { id } }`, []client.Option{admin.Auth()}, "NOT_FOUND", "user not found"},
+		{"delete unknown email", `mutation { deleteUser(email: "nobody@example.com") }`, []client.Option{admin.Auth()}, "NOT_FOUND", "user not found"},
+		{"update unknown email", `mutation { updateUser(email: "nobody@example.com", input: {firstName: "A", lastName: "B", email: "nobody@example.com", password: "pw"}) }`, []client.Option{admin.Auth()}, "NOT_FOUND", "user not found"},
+		{"update without input", `mutation { updateUser(email: "` + user.User.Email + `") }`, []client.Option{user.Auth()}, "BAD_USER_INPUT", "input is required"},
+		{"forbidden", `query { auditLog { id } }`, []client.Option{user.Auth()}, "FORBIDDEN", "only admins can do this"},
+		{"conflict", `mutation { register(input: {firstName: "A", lastName: "B", email: "` + user.User.Email + `", password: "pw"}) { token } }`, nil, "CONFLICT", "an account with this email already exists"},
+		{"validation", `mutation { createOrganization(input: {name: " "}) { role } }`, []client.Option{user.Auth()}, "BAD_USER_INPUT", "organization name is required"},
+		{"invalid argument", `mutation { setUserStatus(userId: "x", status: SUSPENDED, reason: "r", until: "tomorrow") { id } }`, []client.Option{admin.Auth()}, "BAD_USER_INPUT", ""},
+	}
+	for _, tt := range tests {
+		got := postError(t, h, tt.query, tt.opts...)
+		if got.Extensions.Code != tt.code || (tt.msg != "" && got.Message != tt.msg) {
+			t.Errorf("%s: got %s %q, want %s %q", tt.name, got.Extensions.Code, got.Message, tt.code, tt.msg)
+		}
+	}
+}
+
+// brokenRepo fails or panics on user lookups by ID.
+type brokenRepo struct {
+	repos.Repository
+	panic bool
+}
+
+func (b *brokenRepo) UserByID(ctx context.Context, id string) (*model.UserModel, error) {
+	if id != "broken" {
+		return b.Repository.UserByID(ctx, id)
+	}
+	if b.panic {
+		panic("connection pool exhausted at 10.0.0.5")
+	}
+	return nil, errors.New("dial tcp 10.0.0.5:5432: connection refused")
+}
+
+func TestInternalErrorsAreHidden(t *testing.T) {
+	for _, panics := range []bool{false, true} {
+		repo := &brokenRepo{Repository: memory.NewStore(), panic: panics}
+		h := graphtest.NewWithRepository(t, repo)
+		admin := h.LoginAsAdmin()
+
+		got := postError(t, h, `query { user(id: "broken") { id } }`, admin.Auth())
+		if got.Extensions.Code != "INTERNAL_SERVER_ERROR" || got.Message != "internal server error" {
+			t.Errorf("panic=%v: got %s %q", panics, got.Extensions.Code, got.Message)
+		}
+
+		// The server keeps serving after a panic.
+		var resp struct{ GetMe struct{ ID string } }
+		h.MustPost(`query { getMe { id } }`, &resp, admin.Auth())
+	}
+}
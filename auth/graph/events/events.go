@@ -0,0 +1,47 @@
+// Package events publishes things that happened to accounts, such as a
+// sign-in from a new device, for other services to react to.
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+)
+
+// Event types.
+const (
+	// NewDeviceLogin is published when a user signs in from a device they
+	// have not signed in from before. Data holds the login ID, IP and user
+	// agent.
+	NewDeviceLogin = "login.new_device"
+)
+
+// Event is something that happened to the account of UserID.
+type Event struct {
+	Type   string
+	UserID string
+	Data   map[string]string
+	Time   time.Time
+}
+
+// Publisher delivers events. Implementations must be safe for concurrent
+// use.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+var logger = logging.For("events")
+
+// Log writes events to the log. It is the default publisher.
+type Log struct{}
+
+func (Log) Publish(ctx context.Context, e Event) error {
+	attrs := []any{slog.String("type", e.Type), slog.String("user_id", e.UserID)}
+	for k, v := range e.Data {
+		attrs = append(attrs, slog.String(k, v))
+	}
+	logger.InfoContext(ctx, "event", attrs...)
+	return nil
+}
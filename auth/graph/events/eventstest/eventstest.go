@@ -0,0 +1,29 @@
+// Package eventstest provides an events.Publisher for tests.
+package eventstest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tabed23/cloudmarket-auth/graph/events"
+)
+
+// Recorder keeps published events in memory so tests can read them back.
+type Recorder struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (r *Recorder) Publish(ctx context.Context, e events.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+// Events returns the events published so far.
+func (r *Recorder) Events() []events.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]events.Event(nil), r.events...)
+}
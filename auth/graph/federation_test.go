@@ -0,0 +1,154 @@
+package graph_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/memory"
+)
+
+const entitiesQuery = `query($reps: [_Any!]!) {
+	_entities(representations: $reps) { ... on User { id firstName } }
+}`
+
+type entitiesResp struct {
+	Entities []*struct {
+		ID        string
+		FirstName string
+	} `json:"_entities"`
+}
+
+func TestServiceSDL(t *testing.T) {
+	h := graphtest.New(t)
+
+	var resp struct {
+		Service struct{ SDL string } `json:"_service"`
+	}
+	h.MustPost(`query { _service { sdl } }`, &resp)
+	if !strings.Contains(resp.Service.SDL, `type User @key(fields: "id")`) {
+		t.Errorf("sdl does not declare User as an entity:\n%s", resp.Service.SDL)
+	}
+}
+
+// countingRepo counts the user lookups of the entity resolver.
+type countingRepo struct {
+	repos.Repository
+	byIDs, byID int
+}
+
+func (c *countingRepo) UserByIDs(ctx context.Context, ids []string) ([]*model.UserModel, error) {
+	c.byIDs++
+	return c.Repository.UserByIDs(ctx, ids)
+}
+
+func (c *countingRepo) UserByID(ctx context.Context, id string) (*model.UserModel, error) {
+	c.byID++
+	return c.Repository.UserByID(ctx, id)
+}
+
+func TestEntitiesResolvesUsersInOneLookup(t *testing.T) {
+	repo := &countingRepo{Repository: memory.NewStore()}
+	h := graphtest.NewWithRepository(t, repo)
+	ada := h.CreateUser("ada@example.com", model.RoleUser)
+	bob := h.CreateUser("bob@example.com", model.RoleUser)
+	session := h.LoginAsUser()
+	repo.byIDs, repo.byID = 0, 0
+
+	reps := []map[string]any{
+		{"__typename": "User", "id": bob.ID},
+		{"__typename": "User", "id": "missing"},
+		{"__typename": "User", "id": ada.ID},
+	}
+	var resp entitiesResp
+	h.MustPost(entitiesQuery, &resp, session.Auth(), client.Var("reps", reps))
+
+	if len(resp.Entities) != 3 {
+		t.Fatalf("got %d entities, want 3", len(resp.Entities))
+	}
+	if e := resp.Entities[0]; e == nil || e.ID != bob.ID {
+		t.Errorf("entity 0 = %+v, want %s", e, bob.ID)
+	}
+	if e := resp.Entities[1]; e != nil {
+		t.Errorf("entity 1 = %+v, want null for a missing user", e)
+	}
+	if e := resp.Entities[2]; e == nil || e.ID != ada.ID || e.FirstName != ada.FirstName {
+		t.Errorf("entity 2 = %+v, want %s", e, ada.ID)
+	}
+	// Authenticating the caller looks up their account once.
+	if repo.byIDs != 1 || repo.byID != 1 {
+		t.Errorf("lookups: %d UserByIDs, %d UserByID; want one batched lookup", repo.byIDs, repo.byID)
+	}
+}
+
+func TestEntitiesHideSelfFields(t *testing.T) {
+	h := graphtest.New(t)
+	ada := h.CreateUser("ada@example.com", model.RoleUser)
+	session := h.LoginAsUser()
+
+	var resp struct {
+		Entities []*struct{ Email *string } `json:"_entities"`
+	}
+	h.MustPost(`query($reps: [_Any!]!) { _entities(representations: $reps) { ... on User { email } } }`, &resp,
+		session.Auth(), client.Var("reps", []map[string]any{{"__typename": "User", "id": ada.ID}}))
+	if len(resp.Entities) != 1 || resp.Entities[0] == nil || resp.Entities[0].Email != nil {
+		t.Errorf("_entities by another user = %+v, want the user without email", resp.Entities)
+	}
+}
+
+func TestEntitiesRequireAuthentication(t *testing.T) {
+	h := graphtest.New(t)
+	ada := h.CreateUser("ada@example.com", model.RoleUser)
+
+	var resp entitiesResp
+	err := h.Post(entitiesQuery, &resp, client.Var("reps", []map[string]any{{"__typename": "User", "id": ada.ID}}))
+	expectError(t, err, "Access Denied")
+	for _, e := range resp.Entities {
+		if e != nil {
+			t.Errorf("anonymous _entities resolved %+v", e)
+		}
+	}
+}
+
+func TestEntitiesBatchLimit(t *testing.T) {
+	h := graphtest.New(t)
+	session := h.LoginAsUser()
+
+	reps := make([]map[string]any, 101)
+	for i := range reps {
+		reps[i] = map[string]any{"__typename": "User", "id": fmt.Sprint(i)}
+	}
+	var resp entitiesResp
+	err := h.Post(entitiesQuery, &resp, session.Auth(), client.Var("reps", reps))
+	expectError(t, err, "at most 100 users can be resolved at once")
+}
+
+func TestEntitiesScopedToActiveOrganization(t *testing.T) {
+	h := graphtest.New(t)
+	owner := h.LoginAsUser()
+	acme := createOrganization(t, h, owner, "Acme").Organization
+	member := h.CreateUser("member@example.com", model.RoleUser)
+	addMember(t, h, acme.ID, member, model.OrgRoleMember)
+	outsider := h.CreateUser("outsider@example.com", model.RoleUser)
+
+	reps := []map[string]any{
+		{"__typename": "User", "id": member.ID},
+		{"__typename": "User", "id": outsider.ID},
+	}
+	var resp entitiesResp
+	h.MustPost(entitiesQuery, &resp, h.SwitchOrganization(owner, acme.ID).Auth(), client.Var("reps", reps))
+	if len(resp.Entities) != 2 {
+		t.Fatalf("got %d entities, want 2", len(resp.Entities))
+	}
+	if e := resp.Entities[0]; e == nil || e.ID != member.ID {
+		t.Errorf("member = %+v, want %s", e, member.ID)
+	}
+	if e := resp.Entities[1]; e != nil {
+		t.Errorf("user outside the organization resolved to %+v", e)
+	}
+}
@@ -0,0 +1,231 @@
+// Package graphtest runs GraphQL documents against the auth schema in
+// process, backed by the in-memory repository.
+package graphtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph"
+	"github.com/tabed23/cloudmarket-auth/graph/config"
+	"github.com/tabed23/cloudmarket-auth/graph/events/eventstest"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/mailer/mailertest"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/migrations"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/memory"
+	"github.com/tabed23/cloudmarket-auth/graph/storage"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+	"gorm.io/gorm"
+)
+
+// Password is the password of every user created by the harness.
+const Password = "correct horse battery staple"
+
+// PublicURL is the base of links in emails sent by the harness.
+const PublicURL = "https://cloudmarket.test"
+
+// BlobURL is the base URL of files in the harness blob store.
+const BlobURL = "https://cloudmarket.test/blobs"
+
+// AvatarMaxBytes is the avatar upload limit of the harness.
+const AvatarMaxBytes = 256 << 10
+
+const testSecret = "graphtest-secret-at-least-32-bytes-long"
+
+var userSeq atomic.Int64
+
+// Harness serves the executable schema through the same HTTP middleware as
+// the server.
+type Harness struct {
+	t       *testing.T
+	Repo    repos.Repository
+	Handler http.Handler
+	Client  *client.Client
+	// Mail holds every email the resolvers sent.
+	Mail *mailertest.Recorder
+	// Blobs stores uploads in a directory removed when the test ends.
+	Blobs *storage.LocalFS
+	// Events holds every account event the resolvers published.
+	Events *eventstest.Recorder
+}
+
+// Session is a logged in user and the token issued to it.
+type Session struct {
+	User  *model.UserModel
+	Token string
+}
+
+// New returns a harness over an empty in-memory repository.
+func New(t *testing.T) *Harness {
+	t.Helper()
+	return NewWithRepository(t, memory.NewStore())
+}
+
+// OpenSQLite returns a migrated, private in-memory SQLite database that is
+// closed when the test ends.
+func OpenSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := config.InitDB(config.DBConfig{Driver: config.DriverSQLite, URL: ":memory:"})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return db
+}
+
+// NewWithRepository returns a harness over repo.
+func NewWithRepository(t *testing.T, repo repos.Repository) *Harness {
+	t.Helper()
+	jwt.Configure(testSecret, "cloudmarket", time.Hour, 24*time.Hour, 72*time.Hour, 15*time.Minute)
+
+	mail := &mailertest.Recorder{}
+	blobs, err := storage.NewLocalFS(t.TempDir(), BlobURL)
+	if err != nil {
+		t.Fatalf("blob store: %v", err)
+	}
+	published := &eventstest.Recorder{}
+	var h http.Handler = graph.NewHandler(repo,
+		graph.WithMailer(mail),
+		graph.WithPublicURL(PublicURL),
+		graph.WithBlobStore(blobs),
+		graph.WithAvatarMaxBytes(AvatarMaxBytes),
+		graph.WithEvents(published),
+		// Tests read sent emails right after the request.
+		graph.WithBackground(func(f func()) { f() }),
+	)
+	h = middleware.AuthMiddleware(repo)(h)
+	h = middleware.ClientInfo(false)(h)
+	return &Harness{
+		t:       t,
+		Repo:    repo,
+		Handler: h,
+		Client:  client.New(h),
+		Mail:    mail,
+		Blobs:   blobs,
+		Events:  published,
+	}
+}
+
+// Post runs query and decodes its data into resp. GraphQL errors are
+// returned, with whatever partial data was decoded.
+func (h *Harness) Post(query string, resp any, opts ...client.Option) error {
+	return h.Client.Post(query, resp, opts...)
+}
+
+// MustPost is Post that fails the test on any error.
+func (h *Harness) MustPost(query string, resp any, opts ...client.Option) {
+	h.t.Helper()
+	if err := h.Post(query, resp, opts...); err != nil {
+		h.t.Fatalf("query failed: %v", err)
+	}
+}
+
+// CreateUser stores a user with Password directly in the repository.
+func (h *Harness) CreateUser(email, role string) *model.UserModel {
+	h.t.Helper()
+	hash, err := utils.HashPassword(Password)
+	if err != nil {
+		h.t.Fatalf("hash password: %v", err)
+	}
+	user, err := h.Repo.UserCreation(context.Background(), &model.NewUserModel{
+		FirstName: "Test",
+		LastName:  role,
+		Email:     email,
+		Password:  hash,
+		Role:      role,
+	})
+	if err != nil {
+		h.t.Fatalf("create user %s: %v", email, err)
+	}
+	return user
+}
+
+// Login runs the login mutation and returns the issued access token.
+func (h *Harness) Login(email, password string) string {
+	h.t.Helper()
+	var resp struct {
+		Login struct {
+			Token string
+		}
+	}
+	h.MustPost(`mutation($email: String!, $password: String!) {
+		login(email: $email, password: $password) { token }
+	}`, &resp, client.Var("email", email), client.Var("password", password))
+	return resp.Login.Token
+}
+
+// LoginAsUser creates a USER and logs it in through the login mutation.
+func (h *Harness) LoginAsUser() Session {
+	h.t.Helper()
+	return h.loginAs(model.RoleUser)
+}
+
+// LoginAsAdmin creates an ADMIN and logs it in through the login mutation.
+func (h *Harness) LoginAsAdmin() Session {
+	h.t.Helper()
+	return h.loginAs(model.RoleAdmin)
+}
+
+func (h *Harness) loginAs(role string) Session {
+	h.t.Helper()
+	email := fmt.Sprintf("%s-%d@example.com", strings.ToLower(role), userSeq.Add(1))
+	user := h.CreateUser(email, role)
+	return Session{User: user, Token: h.Login(email, Password)}
+}
+
+// Auth sends the session token as a bearer token.
+func (s Session) Auth() client.Option {
+	return client.AddHeader("Authorization", "Bearer "+s.Token)
+}
+
+// SwitchOrganization makes orgID the active organization of s and returns
+// the session with the reissued token.
+func (h *Harness) SwitchOrganization(s Session, orgID string) Session {
+	h.t.Helper()
+	var resp struct {
+		SwitchOrganization struct {
+			Token string
+		}
+	}
+	h.MustPost(`mutation($id: ID) { switchOrganization(organizationId: $id) { token } }`,
+		&resp, client.Var("id", orgID), s.Auth())
+	return Session{User: s.User, Token: resp.SwitchOrganization.Token}
+}
+
+// Impersonate runs the impersonate mutation as admin and returns a session
+// acting as userID.
+func (h *Harness) Impersonate(admin Session, userID string) Session {
+	h.t.Helper()
+	var resp struct {
+		Impersonate struct {
+			Token string
+		}
+	}
+	h.MustPost(`mutation($id: ID!) { impersonate(userId: $id, reason: "support ticket") { token } }`,
+		&resp, client.Var("id", userID), admin.Auth())
+	user, err := h.Repo.UserByID(context.Background(), userID)
+	if err != nil || user == nil {
+		h.t.Fatalf("impersonated user %s: %v", userID, err)
+	}
+	return Session{User: user, Token: resp.Impersonate.Token}
+}
@@ -0,0 +1,104 @@
+// Package health serves the liveness and readiness probes. Readiness fails
+// while the database is unreachable, while migrations are pending and once
+// the server starts shutting down.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/migrations"
+	"gorm.io/gorm"
+)
+
+const checkTimeout = 2 * time.Second
+
+// Checker serves the liveness and readiness probes.
+type Checker struct {
+	db       *gorm.DB
+	draining atomic.Bool
+}
+
+// New returns a Checker for db, which is nil for the memory backend.
+func New(db *gorm.DB) *Checker {
+	return &Checker{db: db}
+}
+
+// SetDraining makes Readiness fail so load balancers stop sending traffic
+// while the server shuts down.
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Liveness reports that the process is up and serving HTTP.
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readiness reports whether the server can take traffic: it is not shutting
+// down, the database answers a ping and no migration is pending.
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{}
+	ready := true
+	fail := func(name, reason string) {
+		checks[name] = reason
+		ready = false
+	}
+
+	if c.draining.Load() {
+		fail("server", "shutting down")
+	}
+
+	if c.db != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+		c.checkDatabase(ctx, checks, fail)
+	}
+
+	status := http.StatusOK
+	body := map[string]any{"status": "ok", "checks": checks}
+	if !ready {
+		status = http.StatusServiceUnavailable
+		body["status"] = "unavailable"
+	}
+	writeStatus(w, status, body)
+}
+
+func (c *Checker) checkDatabase(ctx context.Context, checks map[string]string, fail func(name, reason string)) {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		fail("database", err.Error())
+		return
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		fail("database", "ping failed")
+		return
+	}
+	checks["database"] = "ok"
+
+	migrator, err := migrations.New(c.db)
+	if err != nil {
+		fail("migrations", err.Error())
+		return
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		fail("migrations", "status unavailable")
+		return
+	}
+	if pending > 0 {
+		fail("migrations", "pending migrations")
+		return
+	}
+	checks["migrations"] = "ok"
+}
+
+func writeStatus(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
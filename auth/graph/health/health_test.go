@@ -0,0 +1,119 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tabed23/cloudmarket-auth/graph/config"
+	"github.com/tabed23/cloudmarket-auth/graph/health"
+	"github.com/tabed23/cloudmarket-auth/graph/migrations"
+	"gorm.io/gorm"
+)
+
+type probe struct {
+	Status string
+	Checks map[string]string
+}
+
+func get(t *testing.T, h http.HandlerFunc) (int, probe) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var body probe
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Cache-Control = %q", rec.Header().Get("Cache-Control"))
+	}
+	return rec.Code, body
+}
+
+func unmigrated(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := config.InitDB(config.DBConfig{Driver: config.DriverSQLite, URL: ":memory:"})
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func migrated(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := unmigrated(t)
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return db
+}
+
+func TestLiveness(t *testing.T) {
+	c := health.New(nil)
+	c.SetDraining()
+	// Draining servers are still alive.
+	if code, body := get(t, c.Liveness); code != http.StatusOK || body.Status != "ok" {
+		t.Errorf("Liveness = %d %+v", code, body)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		name   string
+		db     func(t *testing.T) *gorm.DB
+		drain  bool
+		code   int
+		checks map[string]string
+	}{
+		{"ready", migrated, false, http.StatusOK,
+			map[string]string{"database": "ok", "migrations": "ok"}},
+		{"no database", func(*testing.T) *gorm.DB { return nil }, false, http.StatusOK,
+			map[string]string{}},
+		{"pending migrations", unmigrated, false, http.StatusServiceUnavailable,
+			map[string]string{"database": "ok", "migrations": "pending migrations"}},
+		{"database down", func(t *testing.T) *gorm.DB {
+			db := migrated(t)
+			sqlDB, _ := db.DB()
+			sqlDB.Close()
+			return db
+		}, false, http.StatusServiceUnavailable,
+			map[string]string{"database": "ping failed"}},
+		{"draining", migrated, true, http.StatusServiceUnavailable,
+			map[string]string{"server": "shutting down", "database": "ok", "migrations": "ok"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := health.New(tt.db(t))
+			if tt.drain {
+				c.SetDraining()
+			}
+			code, body := get(t, c.Readiness)
+			wantStatus := "ok"
+			if tt.code != http.StatusOK {
+				wantStatus = "unavailable"
+			}
+			if code != tt.code || body.Status != wantStatus {
+				t.Errorf("Readiness = %d %q; want %d %q", code, body.Status, tt.code, wantStatus)
+			}
+			if len(body.Checks) != len(tt.checks) {
+				t.Errorf("checks = %v; want %v", body.Checks, tt.checks)
+			}
+			for name, want := range tt.checks {
+				if body.Checks[name] != want {
+					t.Errorf("check %s = %q; want %q", name, body.Checks[name], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.80
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// Impersonate is the resolver for the impersonate field.
+func (r *mutationResolver) Impersonate(ctx context.Context, userID string, reason *string) (*model.ImpersonationPayload, error) {
+	actor, err := r.currentAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if userID == actor.ID {
+		return nil, apperr.Forbidden("cannot impersonate yourself")
+	}
+	subject, err := r.UserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if subject == nil {
+		return nil, apperr.NotFound("user not found")
+	}
+	if subject.Role == model.RoleAdmin {
+		return nil, apperr.Forbidden("admins cannot be impersonated")
+	}
+	detail := ""
+	if reason != nil {
+		detail = strings.TrimSpace(*reason)
+	}
+	if len(detail) > maxImpersonationReason {
+		return nil, apperr.Validation("reason must be at most %d characters", maxImpersonationReason)
+	}
+
+	// Record the start before handing out the token.
+	_, err = r.AuditLogCreation(ctx, &model.NewAuditLogModel{
+		ActorID:   actor.ID,
+		SubjectID: subject.ID,
+		Action:    model.AuditImpersonationStart,
+		Operation: "impersonate",
+		Detail:    detail,
+		RequestID: logging.RequestID(ctx),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	token, expiresAt, err := jwt.GenerateImpersonationJwt(ctx, subject.ID, subject.Email, subject.Role, actor.ID, actor.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT: %w", err)
+	}
+	return &model.ImpersonationPayload{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      model.ConvertToGraphQLUser(*subject),
+	}, nil
+}
+
+// AuditLog is the resolver for the auditLog field.
+func (r *queryResolver) AuditLog(ctx context.Context, actorID *string, subjectID *string, limit *int32) ([]*model.AuditLogEntry, error) {
+	if _, err := r.currentAdmin(ctx); err != nil {
+		return nil, err
+	}
+	filter := model.AuditLogFilter{Limit: 50}
+	if actorID != nil {
+		filter.ActorID = *actorID
+	}
+	if subjectID != nil {
+		filter.SubjectID = *subjectID
+	}
+	if limit != nil {
+		if *limit < 1 || *limit > maxAuditLogLimit {
+			return nil, apperr.Validation("limit must be between 1 and %d", maxAuditLogLimit)
+		}
+		filter.Limit = int(*limit)
+	}
+
+	entries, err := r.AuditLogs(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audit log: %w", err)
+	}
+	result := make([]*model.AuditLogEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, model.ConvertToGraphQLAuditLogEntry(*entry))
+	}
+	return result, nil
+}
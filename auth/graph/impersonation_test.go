@@ -0,0 +1,229 @@
+package graph_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/memory"
+)
+
+type auditEntryResp struct {
+	ActorID   string
+	SubjectID string
+	Action    string
+	Operation string
+	Detail    string
+}
+
+func auditLog(t *testing.T, h *graphtest.Harness, admin graphtest.Session, subjectID string) []auditEntryResp {
+	t.Helper()
+	var resp struct{ AuditLog []auditEntryResp }
+	h.MustPost(`query($subject: ID) { auditLog(subjectId: $subject) { actorId subjectId action operation detail } }`,
+		&resp, client.Var("subject", subjectID), admin.Auth())
+	return resp.AuditLog
+}
+
+func TestImpersonateActsAsUser(t *testing.T) {
+	h := graphtest.New(t)
+	admin := h.LoginAsAdmin()
+	user := h.LoginAsUser()
+
+	var resp struct {
+		Impersonate struct {
+			Token     string
+			ExpiresAt string
+			User      struct{ ID string }
+		}
+	}
+	h.MustPost(`mutation($id: ID!) { impersonate(userId: $id, reason: "ticket 42") { token expiresAt user { id } } }`,
+		&resp, client.Var("id", user.User.ID), admin.Auth())
+	if resp.Impersonate.User.ID != user.User.ID {
+		t.Fatalf("impersonated %s, want %s", resp.Impersonate.User.ID, user.User.ID)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, resp.Impersonate.ExpiresAt)
+	if err != nil || time.Until(expiresAt) > 15*time.Minute || time.Until(expiresAt) <= 0 {
+		t.Errorf("expiresAt = %q, want within 15 minutes", resp.Impersonate.ExpiresAt)
+	}
+
+	claims, err := jwt.ValidateJwt(context.Background(), resp.Impersonate.Token)
+	if err != nil {
+		t.Fatalf("impersonation token: %v", err)
+	}
+	if claims.ID != user.User.ID || claims.Role != model.RoleUser || claims.Act == nil || claims.Act.ID != admin.User.ID {
+		t.Fatalf("claims = %+v, act = %+v", claims, claims.Act)
+	}
+
+	session := graphtest.Session{User: user.User, Token: resp.Impersonate.Token}
+	var me struct{ GetMe struct{ ID, Email string } }
+	h.MustPost(`query Me { getMe { id email } }`, &me, session.Auth())
+	if me.GetMe.ID != user.User.ID || me.GetMe.Email != user.User.Email {
+		t.Fatalf("getMe while impersonating = %+v", me.GetMe)
+	}
+
+	entries := auditLog(t, h, admin, user.User.ID)
+	if len(entries) != 2 {
+		t.Fatalf("audit log = %+v, want start and one request", entries)
+	}
+	request, start := entries[0], entries[1]
+	if start.Action != model.AuditImpersonationStart || start.ActorID != admin.User.ID || start.Detail != "ticket 42" {
+		t.Errorf("start entry = %+v", start)
+	}
+	if request.Action != model.AuditImpersonatedRequest || request.ActorID != admin.User.ID ||
+		request.SubjectID != user.User.ID || request.Operation != "Me" || request.Detail != "query getMe" {
+		t.Errorf("request entry = %+v", request)
+	}
+}
+
+func TestImpersonationBlocksAccountOperations(t *testing.T) {
+	h := graphtest.New(t)
+	admin := h.LoginAsAdmin()
+	user := h.LoginAsUser()
+	session := h.Impersonate(admin, user.User.ID)
+	other := h.LoginAsUser()
+
+	blocked := map[string]struct {
+		query string
+		vars  []client.Option
+	}{
+		"updateUser": {`mutation($email: String!) { updateUser(email: $email, input: {firstName: "X", lastName: "Y", email: $email, password: "hijacked password"}) }`,
+			[]client.Option{client.Var("email", user.User.Email)}},
+		"deleteUser":         {`mutation($email: String!) { deleteUser(email: $email) }`, []client.Option{client.Var("email", user.User.Email)}},
+		"switchOrganization": {`mutation { switchOrganization(organizationId: null) { token } }`, nil},
+		"impersonate":        {`mutation($id: ID!) { impersonate(userId: $id) { token } }`, []client.Option{client.Var("id", other.User.ID)}},
+		"acceptInvitation":   {`mutation { acceptInvitation(token: "x") { token } }`, nil},
+	}
+	for name, tc := range blocked {
+		t.Run(name, func(t *testing.T) {
+			var resp map[string]any
+			err := h.Post(tc.query, &resp, append(tc.vars, session.Auth())...)
+			if err == nil || !strings.Contains(err.Error(), "impersonating") {
+				t.Fatalf("%s while impersonating: %v", name, err)
+			}
+		})
+	}
+
+	stored, _ := h.Repo.UserByID(context.Background(), user.User.ID)
+	if stored == nil || stored.Password != user.User.Password {
+		t.Fatal("account was changed while impersonating")
+	}
+	// The refused attempts are in the audit log too.
+	if entries := auditLog(t, h, admin, user.User.ID); len(entries) != 1+len(blocked) {
+		t.Errorf("audit log has %d entries, want %d", len(entries), 1+len(blocked))
+	}
+}
+
+func TestImpersonateRules(t *testing.T) {
+	h := graphtest.New(t)
+	admin := h.LoginAsAdmin()
+	otherAdmin := h.LoginAsAdmin()
+	user := h.LoginAsUser()
+
+	tests := []struct {
+		name    string
+		caller  graphtest.Session
+		subject string
+		want    string
+	}{
+		{"user", user, otherAdmin.User.ID, "only admins"},
+		{"self", admin, admin.User.ID, "yourself"},
+		{"admin", admin, otherAdmin.User.ID, "admins cannot be impersonated"},
+		{"missing", admin, "missing", "user not found"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var resp map[string]any
+			err := h.Post(`mutation($id: ID!) { impersonate(userId: $id) { token } }`,
+				&resp, client.Var("id", tc.subject), tc.caller.Auth())
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("impersonate = %v, want %q", err, tc.want)
+			}
+		})
+	}
+	if entries := auditLog(t, h, admin, ""); len(entries) != 0 {
+		t.Errorf("refused impersonations were logged as started: %+v", entries)
+	}
+}
+
+func TestAuditLogIsAdminOnly(t *testing.T) {
+	h := graphtest.New(t)
+	admin := h.LoginAsAdmin()
+	user := h.LoginAsUser()
+	session := h.Impersonate(admin, user.User.ID)
+
+	for name, s := range map[string]graphtest.Session{"user": user, "impersonated": session} {
+		var resp map[string]any
+		if err := h.Post(`query { auditLog { id } }`, &resp, s.Auth()); err == nil || !strings.Contains(err.Error(), "only admins") {
+			t.Errorf("auditLog as %s: %v", name, err)
+		}
+	}
+}
+
+// failingAudit is a repository whose audit log cannot be written.
+type failingAudit struct {
+	repos.Repository
+	fail bool
+}
+
+func (f *failingAudit) AuditLogCreation(ctx context.Context, input *model.NewAuditLogModel) (*model.AuditLogModel, error) {
+	if f.fail {
+		return nil, errors.New("disk full")
+	}
+	return f.Repository.AuditLogCreation(ctx, input)
+}
+
+func TestImpersonatedRequestsFailClosed(t *testing.T) {
+	repo := &failingAudit{Repository: memory.NewStore()}
+	h := graphtest.NewWithRepository(t, repo)
+	admin := h.LoginAsAdmin()
+	user := h.LoginAsUser()
+	session := h.Impersonate(admin, user.User.ID)
+
+	repo.fail = true
+	var resp map[string]any
+	if err := h.Post(`query { getMe { id } }`, &resp, session.Auth()); err == nil || !strings.Contains(err.Error(), "audit log") {
+		t.Fatalf("getMe without an audit log = %v, want refused", err)
+	}
+	// Requests that do not impersonate are unaffected.
+	h.MustPost(`query { getMe { id } }`, &resp, user.Auth())
+}
+
+func TestImpersonationEndsWhenTheAdminLosesAccess(t *testing.T) {
+	ctx := context.Background()
+	for name, revoke := range map[string]func(h *graphtest.Harness, admin *model.UserModel) error{
+		"demoted": func(h *graphtest.Harness, admin *model.UserModel) error {
+			_, err := h.Repo.UserUpdate(ctx, admin.Email, &model.NewUserModel{
+				FirstName: admin.FirstName, LastName: admin.LastName, Email: admin.Email, Password: admin.Password, Role: model.RoleUser,
+			})
+			return err
+		},
+		"suspended": func(h *graphtest.Harness, admin *model.UserModel) error {
+			_, err := h.Repo.UserStatusUpdate(ctx, admin.ID, model.StatusSuspended, "offboarded", nil)
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			h := graphtest.New(t)
+			admin := h.LoginAsAdmin()
+			user := h.LoginAsUser()
+			session := h.Impersonate(admin, user.User.ID)
+
+			var resp map[string]any
+			h.MustPost(`query { getMe { id } }`, &resp, session.Auth())
+			stored, _ := h.Repo.UserByEmail(ctx, admin.User.Email)
+			if err := revoke(h, stored); err != nil {
+				t.Fatal(err)
+			}
+			expectError(t, h.Post(`query { getMe { id } }`, &resp, session.Auth()), "Access Denied")
+			// The user's own session is unaffected.
+			h.MustPost(`query { getMe { id } }`, &resp, user.Auth())
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/mailer"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// sendInvitation mails the signed invitation link to the invitee.
+func (r *Resolver) sendInvitation(ctx context.Context, inv *model.InvitationModel, org *model.OrganizationModel) error {
+	token, err := jwt.GenerateInvitationJwt(ctx, inv.ID, inv.OrganizationID, inv.Email, inv.Role, inv.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	link := r.PublicURL + "/invitations/accept?" + url.Values{"token": {token}}.Encode()
+
+	return r.Mailer.Send(ctx, mailer.Message{
+		To:      inv.Email,
+		Subject: fmt.Sprintf("You have been invited to join %s on CloudMarket", org.Name),
+		Body: fmt.Sprintf("You have been invited to join %s as %s.\n\n"+
+			"Accept the invitation:\n%s\n\n"+
+			"The link expires on %s. If you were not expecting this email you can ignore it.\n",
+			org.Name, inv.Role, link, inv.ExpiresAt.UTC().Format(time.RFC1123)),
+	})
+}
+
+// invitationView loads the organization an invitation belongs to.
+func (r *Resolver) invitationView(ctx context.Context, inv *model.InvitationModel) (*model.Invitation, error) {
+	org, err := r.OrganizationByID(ctx, inv.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch organization: %w", err)
+	}
+	if org == nil {
+		return nil, apperr.NotFound("organization %s not found", inv.OrganizationID)
+	}
+	return model.ConvertToGraphQLInvitation(*inv, *org), nil
+}
+
+// invitationManager returns the caller's membership in the active
+// organization if it may manage invitations.
+func (r *Resolver) invitationManager(ctx context.Context) (*model.MembershipModel, error) {
+	actor, err := r.activeMembership(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageMembers(actor.Role) {
+		return nil, apperr.Forbidden("only organization owners and admins can manage invitations")
+	}
+	return actor, nil
+}
@@ -0,0 +1,202 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.80
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+)
+
+// InviteMember is the resolver for the inviteMember field.
+func (r *mutationResolver) InviteMember(ctx context.Context, email string, role model.OrganizationRole) (*model.Invitation, error) {
+	actor, err := r.invitationManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if role == model.OrganizationRoleOwner && actor.Role != model.OrgRoleOwner {
+		return nil, apperr.Forbidden("only organization owners can invite owners")
+	}
+
+	addr, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || addr.Name != "" {
+		return nil, apperr.Validation("invalid email address %q", email)
+	}
+	email = addr.Address
+
+	if user, err := r.UserByEmail(ctx, email); err != nil {
+		return nil, fmt.Errorf("failed to fetch user by email: %w", err)
+	} else if user != nil {
+		member, err := r.MembershipByUser(ctx, actor.OrganizationID, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch membership: %w", err)
+		}
+		if member != nil {
+			return nil, apperr.Conflict("%s is already a member of the organization", email)
+		}
+	}
+
+	existing, err := r.InvitationsByOrganization(ctx, actor.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch invitations: %w", err)
+	}
+	now := time.Now()
+	for _, inv := range existing {
+		if inv.Pending(now) && utils.SameEmail(inv.Email, email) {
+			return nil, apperr.Conflict("%s already has a pending invitation", email)
+		}
+	}
+
+	org, err := r.OrganizationByID(ctx, actor.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch organization: %w", err)
+	}
+	if org == nil {
+		return nil, apperr.NotFound("organization not found")
+	}
+	inv, err := r.InvitationCreation(ctx, &model.NewInvitationModel{
+		OrganizationID: org.ID,
+		Email:          email,
+		Role:           role.String(),
+		InvitedBy:      actor.UserID,
+		ExpiresAt:      now.Add(jwt.InvitationTTL()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	// An invitation nobody received must not block a retry.
+	if err := r.sendInvitation(ctx, inv, org); err != nil {
+		if _, revokeErr := r.InvitationRevoke(ctx, inv.ID); revokeErr != nil {
+			return nil, fmt.Errorf("failed to send invitation: %w (revoking it also failed: %v)", err, revokeErr)
+		}
+		return nil, fmt.Errorf("failed to send invitation: %w", err)
+	}
+	return model.ConvertToGraphQLInvitation(*inv, *org), nil
+}
+
+// RevokeInvitation is the resolver for the revokeInvitation field.
+func (r *mutationResolver) RevokeInvitation(ctx context.Context, id string) (*model.Invitation, error) {
+	actor, err := r.invitationManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inv, err := r.InvitationByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch invitation: %w", err)
+	}
+	// Invitations of other organizations are reported as missing.
+	if inv == nil || inv.OrganizationID != actor.OrganizationID {
+		return nil, apperr.NotFound("invitation not found")
+	}
+
+	revoked, err := r.InvitationRevoke(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+	if revoked == nil {
+		return nil, apperr.NotFound("invitation not found")
+	}
+	return r.invitationView(ctx, revoked)
+}
+
+// AcceptInvitation is the resolver for the acceptInvitation field.
+func (r *mutationResolver) AcceptInvitation(ctx context.Context, token string, account *model.NewUser) (*model.AuthPayload, error) {
+	claims, err := jwt.ValidateInvitationJwt(ctx, token)
+	if err != nil {
+		return nil, apperr.Validation("invalid or expired invitation")
+	}
+	inv, err := r.InvitationByID(ctx, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch invitation: %w", err)
+	}
+	if inv == nil || !inv.Pending(time.Now()) || inv.OrganizationID != claims.OrgID {
+		return nil, apperr.Validation("invalid or expired invitation")
+	}
+
+	var user *model.UserModel
+	var membership *model.MembershipModel
+	caller := middleware.CtxValue(ctx)
+	if caller != nil {
+		user, err = r.UserByID(ctx, caller.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch user by id: %w", err)
+		}
+		if user == nil {
+			return nil, apperr.NotFound("user not found")
+		}
+		if !utils.SameEmail(user.Email, inv.Email) {
+			return nil, apperr.Forbidden("the invitation was sent to a different email address")
+		}
+		if membership, err = r.InvitationAccept(ctx, inv.ID, user.ID); err != nil {
+			return nil, fmt.Errorf("failed to accept invitation: %w", err)
+		}
+	} else {
+		existing, err := r.UserByEmail(ctx, inv.Email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch user by email: %w", err)
+		}
+		if existing != nil {
+			return nil, apperr.Conflict("an account with this email already exists, log in to accept the invitation")
+		}
+		if account == nil {
+			return nil, apperr.Validation("account details are required to accept the invitation")
+		}
+		if !utils.SameEmail(account.Email, inv.Email) {
+			return nil, apperr.Forbidden("the invitation was sent to a different email address")
+		}
+		// The account is only created if the invitation is accepted.
+		if user, membership, err = r.Accounts.CreateInvitedUser(ctx, inv.ID, registration(*account)); err != nil {
+			return nil, err
+		}
+	}
+
+	if membership == nil {
+		return nil, apperr.Validation("invalid or expired invitation")
+	}
+	orgClaims := jwt.WithOrganization(membership.OrganizationID, membership.Role)
+	if caller == nil {
+		// The new account signs in for the first time.
+		return authPayload(ctx)(r.Accounts.StartSession(ctx, user, model.LoginMethodPassword, orgClaims))
+	}
+	return issueTokens(ctx, user, orgClaims)
+}
+
+// PendingInvitations is the resolver for the pendingInvitations field.
+func (r *queryResolver) PendingInvitations(ctx context.Context) ([]*model.Invitation, error) {
+	actor, err := r.invitationManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	org, err := r.OrganizationByID(ctx, actor.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch organization: %w", err)
+	}
+	if org == nil {
+		return nil, apperr.NotFound("organization not found")
+	}
+	invitations, err := r.InvitationsByOrganization(ctx, actor.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch invitations: %w", err)
+	}
+
+	now := time.Now()
+	pending := []*model.Invitation{}
+	for _, inv := range invitations {
+		if inv.Pending(now) {
+			pending = append(pending, model.ConvertToGraphQLInvitation(*inv, *org))
+		}
+	}
+	return pending, nil
+}
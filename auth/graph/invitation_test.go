@@ -0,0 +1,217 @@
+package graph_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+type invitationResp struct {
+	ID           string
+	Email        string
+	Role         string
+	ExpiresAt    string
+	Organization struct{ ID string }
+}
+
+const inviteMutation = `mutation($email: String!, $role: OrganizationRole!) {
+	inviteMember(email: $email, role: $role) { id email role expiresAt organization { id } }
+}`
+
+const acceptMutation = `mutation($token: String!, $account: NewUser) {
+	acceptInvitation(token: $token, account: $account) { token user { id email } }
+}`
+
+// ownerSession creates an organization and returns its owner with the
+// organization active.
+func ownerSession(t *testing.T, h *graphtest.Harness, name string) (graphtest.Session, string) {
+	t.Helper()
+	owner := h.LoginAsUser()
+	org := createOrganization(t, h, owner, name).Organization
+	return h.SwitchOrganization(owner, org.ID), org.ID
+}
+
+func invite(t *testing.T, h *graphtest.Harness, s graphtest.Session, email, role string) invitationResp {
+	t.Helper()
+	var resp struct{ InviteMember invitationResp }
+	h.MustPost(inviteMutation, &resp, client.Var("email", email), client.Var("role", role), s.Auth())
+	return resp.InviteMember
+}
+
+// invitationToken reads the token out of the last invitation mailed to email.
+func invitationToken(t *testing.T, h *graphtest.Harness, email string) string {
+	t.Helper()
+	msg, ok := h.Mail.Last(email)
+	if !ok {
+		t.Fatalf("no email sent to %s", email)
+	}
+	start := strings.Index(msg.Body, graphtest.PublicURL+"/invitations/accept?")
+	if start < 0 {
+		t.Fatalf("no invitation link in %q", msg.Body)
+	}
+	link, err := url.Parse(strings.Fields(msg.Body[start:])[0])
+	if err != nil {
+		t.Fatalf("parse invitation link: %v", err)
+	}
+	return link.Query().Get("token")
+}
+
+func TestInviteMemberSendsSignedLink(t *testing.T) {
+	h := graphtest.New(t)
+	owner, orgID := ownerSession(t, h, "Acme")
+
+	got := invite(t, h, owner, " New@Example.com ", "ADMIN")
+	if got.Email != "New@Example.com" || got.Role != model.OrgRoleAdmin || got.Organization.ID != orgID {
+		t.Fatalf("inviteMember = %+v", got)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, got.ExpiresAt)
+	if err != nil {
+		t.Fatalf("parse expiresAt: %v", err)
+	}
+	if ttl := time.Until(expiresAt); ttl < 71*time.Hour || ttl > 72*time.Hour {
+		t.Fatalf("invitation expires in %v, want the 72h invitation TTL", ttl)
+	}
+
+	claims, err := jwt.ValidateInvitationJwt(t.Context(), invitationToken(t, h, "New@Example.com"))
+	if err != nil {
+		t.Fatalf("validate invitation token: %v", err)
+	}
+	if claims.ID != got.ID || claims.OrgID != orgID || claims.OrgRole != model.OrgRoleAdmin {
+		t.Fatalf("invitation claims = %+v", claims)
+	}
+	if _, err := jwt.ValidateJwt(t.Context(), invitationToken(t, h, "New@Example.com")); err == nil {
+		t.Fatal("an invitation token was accepted as an access token")
+	}
+
+	var pending struct{ PendingInvitations []invitationResp }
+	h.MustPost(`query { pendingInvitations { id } }`, &pending, owner.Auth())
+	if len(pending.PendingInvitations) != 1 || pending.PendingInvitations[0].ID != got.ID {
+		t.Fatalf("pendingInvitations = %+v", pending.PendingInvitations)
+	}
+
+	var resp map[string]any
+	err = h.Post(inviteMutation, &resp, client.Var("email", "new@example.com"), client.Var("role", "MEMBER"), owner.Auth())
+	expectError(t, err, "already has a pending invitation")
+}
+
+func TestInviteMemberPermissions(t *testing.T) {
+	h := graphtest.New(t)
+	owner, orgID := ownerSession(t, h, "Acme")
+	member := h.LoginAsUser()
+	addMember(t, h, orgID, member.User, model.OrgRoleMember)
+	memberSession := h.SwitchOrganization(member, orgID)
+
+	var resp map[string]any
+	err := h.Post(inviteMutation, &resp, client.Var("email", "new@example.com"), client.Var("role", "MEMBER"), memberSession.Auth())
+	expectError(t, err, "only organization owners and admins")
+
+	err = h.Post(`query { pendingInvitations { id } }`, &resp, memberSession.Auth())
+	expectError(t, err, "only organization owners and admins")
+
+	err = h.Post(inviteMutation, &resp, client.Var("email", member.User.Email), client.Var("role", "MEMBER"), owner.Auth())
+	expectError(t, err, "already a member")
+
+	err = h.Post(inviteMutation, &resp, client.Var("email", "not-an-email"), client.Var("role", "MEMBER"), owner.Auth())
+	expectError(t, err, "invalid email address")
+}
+
+func TestAcceptInvitationRegistersNewAccount(t *testing.T) {
+	h := graphtest.New(t)
+	owner, orgID := ownerSession(t, h, "Acme")
+	invite(t, h, owner, "new@example.com", "MEMBER")
+	token := invitationToken(t, h, "new@example.com")
+
+	account := map[string]any{"firstName": "New", "lastName": "Member", "email": "other@example.com", "password": "secret"}
+	var errResp map[string]any
+	err := h.Post(acceptMutation, &errResp, client.Var("token", token), client.Var("account", account))
+	expectError(t, err, "different email address")
+
+	err = h.Post(acceptMutation, &errResp, client.Var("token", token))
+	expectError(t, err, "account details are required")
+
+	account["email"] = "new@example.com"
+	var resp struct {
+		AcceptInvitation struct {
+			Token string
+			User  userResp
+		}
+	}
+	h.MustPost(acceptMutation, &resp, client.Var("token", token), client.Var("account", account))
+
+	claims, err := jwt.ValidateJwt(t.Context(), resp.AcceptInvitation.Token)
+	if err != nil || claims.OrgID != orgID || claims.OrgRole != model.OrgRoleMember || claims.SessionID == "" {
+		t.Fatalf("accepted session claims = %+v, %v", claims, err)
+	}
+	if m, _ := h.Repo.MembershipByUser(t.Context(), orgID, resp.AcceptInvitation.User.ID); m == nil {
+		t.Fatal("accepting did not create a membership")
+	}
+	if h.Login("new@example.com", "secret") == "" {
+		t.Fatal("registered account cannot log in")
+	}
+
+	err = h.Post(acceptMutation, &errResp, client.Var("token", token), client.Var("account", account))
+	expectError(t, err, "invalid or expired invitation")
+}
+
+func TestAcceptInvitationLinksExistingAccount(t *testing.T) {
+	h := graphtest.New(t)
+	owner, orgID := ownerSession(t, h, "Acme")
+	existing := h.LoginAsUser()
+	invite(t, h, owner, existing.User.Email, "ADMIN")
+	token := invitationToken(t, h, existing.User.Email)
+
+	var errResp map[string]any
+	err := h.Post(acceptMutation, &errResp, client.Var("token", token))
+	expectError(t, err, "log in to accept")
+
+	stranger := h.LoginAsUser()
+	err = h.Post(acceptMutation, &errResp, client.Var("token", token), stranger.Auth())
+	expectError(t, err, "different email address")
+
+	var resp struct {
+		AcceptInvitation struct {
+			Token string
+			User  userResp
+		}
+	}
+	h.MustPost(acceptMutation, &resp, client.Var("token", token), existing.Auth())
+	if resp.AcceptInvitation.User.ID != existing.User.ID {
+		t.Fatalf("accepted as %q, want %q", resp.AcceptInvitation.User.ID, existing.User.ID)
+	}
+	m, _ := h.Repo.MembershipByUser(t.Context(), orgID, existing.User.ID)
+	if m == nil || m.Role != model.OrgRoleAdmin {
+		t.Fatalf("membership = %+v", m)
+	}
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	h := graphtest.New(t)
+	owner, _ := ownerSession(t, h, "Acme")
+	inv := invite(t, h, owner, "new@example.com", "MEMBER")
+	token := invitationToken(t, h, "new@example.com")
+
+	other, _ := ownerSession(t, h, "Globex")
+	revoke := `mutation($id: ID!) { revokeInvitation(id: $id) { id } }`
+	var errResp map[string]any
+	err := h.Post(revoke, &errResp, client.Var("id", inv.ID), other.Auth())
+	expectError(t, err, "invitation not found")
+
+	var resp struct{ RevokeInvitation invitationResp }
+	h.MustPost(revoke, &resp, client.Var("id", inv.ID), owner.Auth())
+
+	var pending struct{ PendingInvitations []invitationResp }
+	h.MustPost(`query { pendingInvitations { id } }`, &pending, owner.Auth())
+	if len(pending.PendingInvitations) != 0 {
+		t.Fatalf("revoked invitation still pending: %+v", pending.PendingInvitations)
+	}
+
+	account := map[string]any{"firstName": "New", "lastName": "Member", "email": "new@example.com", "password": "secret"}
+	err = h.Post(acceptMutation, &errResp, client.Var("token", token), client.Var("account", account))
+	expectError(t, err, "invalid or expired invitation")
+}
@@ -8,45 +8,183 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var jwtSecret = []byte("cooki")
+const (
+	accessTokenType     = "access"
+	refreshTokenType    = "refresh"
+	invitationTokenType = "invite"
+)
+
+var (
+	jwtSecret       = []byte("cooki")
+	issuer          = "cloudmarket"
+	accessTokenTTL  = 24 * time.Hour
+	refreshTokenTTL = 7 * 24 * time.Hour
+	invitationTTL   = 72 * time.Hour
+	impersonateTTL  = 15 * time.Minute
+)
+
+// Configure sets the signing secret, issuer and token lifetimes. It is called
+// once at startup, before any token is issued or validated.
+func Configure(secret, iss string, accessTTL, refreshTTL, inviteTTL, impersonationTTL time.Duration) {
+	jwtSecret = []byte(secret)
+	issuer = iss
+	accessTokenTTL = accessTTL
+	refreshTokenTTL = refreshTTL
+	invitationTTL = inviteTTL
+	impersonateTTL = impersonationTTL
+}
+
+// AccessTokenTTL is the lifetime of access tokens.
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
+}
+
+// RefreshTokenTTL is the lifetime of refresh tokens.
+func RefreshTokenTTL() time.Duration {
+	return refreshTokenTTL
+}
+
+// InvitationTTL is how long an invitation stays valid after it is sent.
+func InvitationTTL() time.Duration {
+	return invitationTTL
+}
 
 // JwtClaims represents the JWT claims for authentication
 type JwtClaims struct {
 	ID    string `json:"id"`
 	Email string `json:"email"`
 	Role  string `json:"role"` // Added role for authorization
+	Type  string `json:"typ,omitempty"`
+	// OrgID and OrgRole describe the active organization, if one was selected
+	// with switchOrganization.
+	OrgID   string `json:"org,omitempty"`
+	OrgRole string `json:"org_role,omitempty"`
+	// Act is set on impersonation tokens: ID and Email are then the
+	// impersonated user and Act the admin acting as them.
+	Act *Actor `json:"act,omitempty"`
+	// SessionID ties the token to the login that started the session, so
+	// the session can be revoked from the login history.
+	SessionID string `json:"sid,omitempty"`
 	jwt.StandardClaims
 }
 
+// Actor identifies who really acts behind an impersonation token, after the
+// "act" claim of RFC 8693.
+type Actor struct {
+	ID    string `json:"sub"`
+	Email string `json:"email,omitempty"`
+}
+
+// Impersonated reports whether the token was issued by impersonate.
+func (c *JwtClaims) Impersonated() bool {
+	return c.Act != nil
+}
+
+// ClaimOption adds optional claims to an issued token.
+type ClaimOption func(*JwtClaims)
+
+// WithOrganization sets the active organization and the user's role in it.
+func WithOrganization(orgID, orgRole string) ClaimOption {
+	return func(c *JwtClaims) {
+		c.OrgID = orgID
+		c.OrgRole = orgRole
+	}
+}
+
+// WithSession sets the session the token belongs to.
+func WithSession(id string) ClaimOption {
+	return func(c *JwtClaims) {
+		c.SessionID = id
+	}
+}
+
 // GenreateJwt generates a JWT token with claims including user ID, email, and role
-func GenreateJwt(ctx context.Context, id, email, role string) (string, error) {
+func GenreateJwt(ctx context.Context, id, email, role string, opts ...ClaimOption) (string, error) {
+	return generate(id, email, role, accessTokenType, accessTokenTTL, opts)
+}
+
+// GenerateRefreshJwt generates a longer lived refresh token for the same claims
+func GenerateRefreshJwt(ctx context.Context, id, email, role string, opts ...ClaimOption) (string, error) {
+	return generate(id, email, role, refreshTokenType, refreshTokenTTL, opts)
+}
+
+// GenerateInvitationJwt signs the token of an organization invitation. The ID
+// claim holds the invitation ID and the token expires with the invitation.
+func GenerateInvitationJwt(ctx context.Context, invitationID, orgID, email, orgRole string, expiresAt time.Time) (string, error) {
+	return generate(invitationID, email, "", invitationTokenType, time.Until(expiresAt), []ClaimOption{
+		WithOrganization(orgID, orgRole),
+		func(c *JwtClaims) { c.ExpiresAt = expiresAt.Unix() },
+	})
+}
+
+// GenerateImpersonationJwt signs a short-lived access token for the user
+// with id, email and role on behalf of the admin actorID. No refresh token
+// goes with it, so the session ends when it expires.
+func GenerateImpersonationJwt(ctx context.Context, id, email, role, actorID, actorEmail string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(impersonateTTL)
+	token, err := generate(id, email, role, accessTokenType, impersonateTTL, []ClaimOption{
+		func(c *JwtClaims) {
+			c.Act = &Actor{ID: actorID, Email: actorEmail}
+			c.ExpiresAt = expiresAt.Unix()
+		},
+	})
+	return token, expiresAt, err
+}
+
+func generate(id, email, role, typ string, ttl time.Duration, opts []ClaimOption) (string, error) {
 	claims := JwtClaims{
 		ID:    id,
 		Email: email,
 		Role:  role, // Adding role to claims
+		Type:  typ,
 		StandardClaims: jwt.StandardClaims{
-			Issuer:    "cloudmarket",
-			ExpiresAt: time.Now().Add(24 * time.Hour).Unix(), // Token expires in 24 hours
+			Issuer:    issuer,
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(ttl).Unix(),
 		},
 	}
+	for _, opt := range opts {
+		opt(&claims)
+	}
 
 	// Generate the token with the claims
+	key := signingKey(time.Now())
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString(jwtSecret)
+	token.Header["kid"] = key.ID
+	signedToken, err := token.SignedString(key.Secret)
 	if err != nil {
 		return "", err
 	}
 	return signedToken, nil
 }
 
-// ValidateJwt validates the JWT token and returns the claims
+// ValidateJwt validates an access token and returns the claims
 func ValidateJwt(ctx context.Context, tokenString string) (*JwtClaims, error) {
+	return validate(tokenString, accessTokenType)
+}
+
+// ValidateRefreshJwt validates a refresh token and returns the claims
+func ValidateRefreshJwt(ctx context.Context, tokenString string) (*JwtClaims, error) {
+	return validate(tokenString, refreshTokenType)
+}
+
+// ValidateInvitationJwt validates an invitation token and returns the claims
+func ValidateInvitationJwt(ctx context.Context, tokenString string) (*JwtClaims, error) {
+	return validate(tokenString, invitationTokenType)
+}
+
+func validate(tokenString, typ string) (*JwtClaims, error) {
 	// Parse the token
 	token, err := jwt.ParseWithClaims(tokenString, &JwtClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, jwt.ErrSignatureInvalid
 		}
-		return jwtSecret, nil
+		kid, _ := token.Header["kid"].(string)
+		key, ok := verificationKey(kid, time.Now())
+		if !ok {
+			return nil, fmt.Errorf("unknown or retired signing key")
+		}
+		return key.Secret, nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %v", err)
@@ -63,5 +201,12 @@ func ValidateJwt(ctx context.Context, tokenString string) (*JwtClaims, error) {
 		return nil, fmt.Errorf("token has expired")
 	}
 
+	if claims.Issuer != issuer {
+		return nil, fmt.Errorf("invalid token issuer")
+	}
+	if claims.Type != typ {
+		return nil, fmt.Errorf("invalid token type")
+	}
+
 	return claims, nil
 }
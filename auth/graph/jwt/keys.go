@@ -0,0 +1,173 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyRefreshInterval is how often servers reload the signing keys. A rotated
+// key starts signing after KeyPropagationDelay, once every server has
+// loaded it and can verify its tokens.
+const (
+	KeyRefreshInterval  = time.Minute
+	KeyPropagationDelay = 2 * KeyRefreshInterval
+)
+
+// Key is a rotated HMAC signing key. Tokens name the key that signed them in
+// their "kid" header.
+type Key struct {
+	ID     string
+	Secret []byte
+	// ActiveFrom is when the key starts signing tokens. It verifies tokens
+	// as soon as it is loaded.
+	ActiveFrom time.Time
+	// ExpiresAt, if set, is when the key stops verifying tokens.
+	ExpiresAt *time.Time
+}
+
+// sealedPrefix marks secrets encrypted by SealSecret. Stored secrets without
+// it are plain hex, as written before they were encrypted.
+const sealedPrefix = "aesgcm:"
+
+var (
+	keysMu sync.RWMutex
+	keys   []Key
+)
+
+// NewKey generates a key with a random secret that starts signing at
+// activeFrom.
+func NewKey(activeFrom time.Time) (Key, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Key{}, fmt.Errorf("failed to generate key id: %w", err)
+	}
+	return Key{ID: hex.EncodeToString(id), Secret: secret, ActiveFrom: activeFrom}, nil
+}
+
+// SealSecret encrypts the secret of key for storage, with AES-GCM under a
+// key derived from the configured secret, so that a copy of the signing_keys
+// table alone cannot forge tokens. Changing JWT_SECRET makes the stored keys
+// unreadable: rotate again after changing it.
+func SealSecret(key Key) (string, error) {
+	aead, err := storageCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, key.Secret, []byte(key.ID))
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenSecret decrypts a secret sealed by SealSecret for the key with id.
+// Plain hex secrets are still read.
+func OpenSecret(id, stored string) ([]byte, error) {
+	encoded, ok := strings.CutPrefix(stored, sealedPrefix)
+	if !ok {
+		return hex.DecodeString(stored)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode secret: %w", err)
+	}
+	aead, err := storageCipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("sealed secret is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	secret, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret, was JWT_SECRET changed? %w", err)
+	}
+	return secret, nil
+}
+
+// storageCipher is the AEAD of stored secrets. Its key is derived from the
+// configured secret rather than being the secret itself.
+func storageCipher() (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte("cloudmarket-auth signing key storage"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// SetKeys replaces the rotated keys. Until one of them is active, tokens are
+// signed with the configured secret, which keeps verifying tokens for
+// MaxTokenTTL after the first rotated key becomes active.
+func SetKeys(rotated []Key) {
+	sorted := append([]Key(nil), rotated...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ActiveFrom.Before(sorted[j].ActiveFrom) })
+	keysMu.Lock()
+	keys = sorted
+	keysMu.Unlock()
+}
+
+// MaxTokenTTL is the longest lifetime of an issued token: a retired key must
+// verify tokens for that long after it stops signing.
+func MaxTokenTTL() time.Duration {
+	return max(accessTokenTTL, refreshTokenTTL, invitationTTL, impersonateTTL)
+}
+
+// configuredKey is the key of the secret passed to Configure.
+func configuredKey() Key {
+	sum := sha256.Sum256(jwtSecret)
+	key := Key{ID: hex.EncodeToString(sum[:6]), Secret: jwtSecret}
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	if len(keys) > 0 {
+		retired := keys[0].ActiveFrom.Add(MaxTokenTTL())
+		key.ExpiresAt = &retired
+	}
+	return key
+}
+
+// signingKey is the most recently activated key.
+func signingKey(now time.Time) Key {
+	keysMu.RLock()
+	for i := len(keys) - 1; i >= 0; i-- {
+		if !keys[i].ActiveFrom.After(now) && (keys[i].ExpiresAt == nil || keys[i].ExpiresAt.After(now)) {
+			keysMu.RUnlock()
+			return keys[i]
+		}
+	}
+	keysMu.RUnlock()
+	return configuredKey()
+}
+
+// verificationKey finds the unexpired key with id. Tokens without a key ID
+// were signed with the configured secret.
+func verificationKey(id string, now time.Time) (Key, bool) {
+	if base := configuredKey(); id == "" || id == base.ID {
+		return base, base.ExpiresAt == nil || base.ExpiresAt.After(now)
+	}
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+	for _, key := range keys {
+		if key.ID == id {
+			return key, key.ExpiresAt == nil || key.ExpiresAt.After(now)
+		}
+	}
+	return Key{}, false
+}
@@ -0,0 +1,99 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v4"
+)
+
+func TestKeyRotation(t *testing.T) {
+	t.Cleanup(func() { SetKeys(nil) })
+	ctx := context.Background()
+	now := time.Now()
+
+	old, err := GenreateJwt(ctx, "u1", "ada@example.com", "USER")
+	if err != nil {
+		t.Fatalf("GenreateJwt: %v", err)
+	}
+	pending, err := NewKey(now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+	SetKeys([]Key{pending})
+	if kid := signingKey(now).ID; kid != configuredKey().ID {
+		t.Errorf("signing with %s before the rotated key is active", kid)
+	}
+
+	active, _ := NewKey(now.Add(-time.Minute))
+	SetKeys([]Key{active, pending})
+	token, err := GenreateJwt(ctx, "u1", "ada@example.com", "USER")
+	if err != nil {
+		t.Fatalf("GenreateJwt: %v", err)
+	}
+	if _, err := ValidateJwt(ctx, token); err != nil {
+		t.Errorf("token of the rotated key: %v", err)
+	}
+	if _, err := ValidateJwt(ctx, old); err != nil {
+		t.Errorf("token of the configured secret within its retirement: %v", err)
+	}
+
+	// Once the configured secret retires, its tokens stop verifying; so do
+	// tokens of keys that are no longer loaded.
+	retired, _ := NewKey(now.Add(-MaxTokenTTL() - time.Minute))
+	SetKeys([]Key{retired, active})
+	if _, err := ValidateJwt(ctx, old); err == nil || !strings.Contains(err.Error(), "signing key") {
+		t.Errorf("token of the retired configured secret: %v", err)
+	}
+	SetKeys([]Key{pending})
+	if _, err := ValidateJwt(ctx, token); err == nil {
+		t.Error("token of an unloaded key verified")
+	}
+}
+
+func TestTokensWithoutKeyID(t *testing.T) {
+	t.Cleanup(func() { SetKeys(nil) })
+	claims := JwtClaims{ID: "u1", Type: accessTokenType}
+	claims.Issuer = issuer
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+	// Tokens issued before keys were rotated carry no "kid" header.
+	token, err := gojwt.NewWithClaims(gojwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if _, err := ValidateJwt(context.Background(), token); err != nil {
+		t.Errorf("token without kid: %v", err)
+	}
+}
+
+func TestSealSecret(t *testing.T) {
+	t.Cleanup(func() { Configure("cooki", issuer, accessTokenTTL, refreshTokenTTL, invitationTTL, impersonateTTL) })
+	key, err := NewKey(time.Now())
+	if err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+	sealed, err := SealSecret(key)
+	if err != nil {
+		t.Fatalf("SealSecret: %v", err)
+	}
+	if strings.Contains(sealed, hex.EncodeToString(key.Secret)) {
+		t.Errorf("sealed secret %q holds the plain secret", sealed)
+	}
+	if secret, err := OpenSecret(key.ID, sealed); err != nil || !bytes.Equal(secret, key.Secret) {
+		t.Errorf("OpenSecret = %x, %v; want %x", secret, err, key.Secret)
+	}
+	if secret, err := OpenSecret(key.ID, hex.EncodeToString(key.Secret)); err != nil || !bytes.Equal(secret, key.Secret) {
+		t.Errorf("OpenSecret of a plain hex secret = %x, %v", secret, err)
+	}
+	if _, err := OpenSecret("other", sealed); err == nil {
+		t.Error("OpenSecret under another key id succeeded")
+	}
+	Configure("another-secret", issuer, accessTokenTTL, refreshTokenTTL, invitationTTL, impersonateTTL)
+	if _, err := OpenSecret(key.ID, sealed); err == nil {
+		t.Error("OpenSecret under another JWT secret succeeded")
+	}
+}
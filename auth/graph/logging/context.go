@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type fieldsKey struct{}
+
+// Fields are the request-scoped values added to every record logged with the
+// request context. Inner layers fill them in as they learn them, so the
+// access log written by Middleware sees the user and the operation too.
+type Fields struct {
+	mu        sync.Mutex
+	requestID string
+	userID    string
+	operation string
+}
+
+// WithFields returns ctx carrying fresh Fields for requestID.
+func WithFields(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, &Fields{requestID: requestID})
+}
+
+func fieldsFrom(ctx context.Context) *Fields {
+	f, _ := ctx.Value(fieldsKey{}).(*Fields)
+	return f
+}
+
+// RequestID returns the ID of the request ctx belongs to, if any.
+func RequestID(ctx context.Context) string {
+	if f := fieldsFrom(ctx); f != nil {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.requestID
+	}
+	return ""
+}
+
+// SetUserID records the authenticated user of the request.
+func SetUserID(ctx context.Context, userID string) {
+	if f := fieldsFrom(ctx); f != nil {
+		f.mu.Lock()
+		f.userID = userID
+		f.mu.Unlock()
+	}
+}
+
+// SetOperation records the GraphQL operation name of the request.
+func SetOperation(ctx context.Context, operation string) {
+	if f := fieldsFrom(ctx); f != nil {
+		f.mu.Lock()
+		f.operation = operation
+		f.mu.Unlock()
+	}
+}
+
+func contextAttrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	var attrs []slog.Attr
+	if f := fieldsFrom(ctx); f != nil {
+		f.mu.Lock()
+		if f.requestID != "" {
+			attrs = append(attrs, slog.String("request_id", f.requestID))
+		}
+		if f.userID != "" {
+			attrs = append(attrs, slog.String("user_id", f.userID))
+		}
+		if f.operation != "" {
+			attrs = append(attrs, slog.String("operation", f.operation))
+		}
+		f.mu.Unlock()
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs,
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return attrs
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// GormLogger writes GORM logs to the "gorm" component. Statements are logged
+// with placeholders only; bound parameters never reach the log.
+type GormLogger struct {
+	log           *slog.Logger
+	SlowThreshold time.Duration
+}
+
+var (
+	_ logger.Interface  = GormLogger{}
+	_ gorm.ParamsFilter = GormLogger{}
+)
+
+// NewGormLogger returns a GORM logger that reports statements slower than
+// slowThreshold as warnings.
+func NewGormLogger(slowThreshold time.Duration) GormLogger {
+	return GormLogger{log: For("gorm"), SlowThreshold: slowThreshold}
+}
+
+// LogMode is a no-op: the level is configured per component instead.
+func (l GormLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (l GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.log.InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.log.WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.log.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (l GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	level := slog.LevelDebug
+	msg := "query"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, msg = slog.LevelError, "query failed"
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold:
+		level, msg = slog.LevelWarn, "slow query"
+	}
+	if !l.log.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Duration("duration", elapsed),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	l.log.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// ParamsFilter drops bound parameters before GORM renders the statement.
+func (l GormLogger) ParamsFilter(_ context.Context, sql string, _ ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
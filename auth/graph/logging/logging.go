@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+var (
+	mu              sync.RWMutex
+	base            slog.Handler = newJSONHandler(os.Stdout)
+	defaultLevel                 = slog.LevelInfo
+	componentLevels              = map[string]slog.Level{}
+)
+
+// Setup sends JSON logs to w. level applies to every component without an
+// entry in levels.
+func Setup(w io.Writer, level slog.Level, levels map[string]slog.Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	base = newJSONHandler(w)
+	defaultLevel = level
+	componentLevels = make(map[string]slog.Level, len(levels))
+	for component, l := range levels {
+		componentLevels[component] = l
+	}
+	slog.SetDefault(For("app"))
+}
+
+// For returns the logger of a component such as "http", "graphql" or "gorm".
+// It is safe to call before Setup; records are routed through whatever
+// configuration is current when they are written.
+func For(component string) *slog.Logger {
+	return slog.New(&handler{component: component})
+}
+
+func newJSONHandler(w io.Writer) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       slog.LevelDebug,
+		ReplaceAttr: Redact,
+	})
+}
+
+func levelFor(component string) slog.Level {
+	mu.RLock()
+	defer mu.RUnlock()
+	if l, ok := componentLevels[component]; ok {
+		return l
+	}
+	return defaultLevel
+}
+
+func current() slog.Handler {
+	mu.RLock()
+	defer mu.RUnlock()
+	return base
+}
+
+// handler filters by the component level, adds the component and the
+// request-scoped fields, and delegates to the current JSON handler.
+type handler struct {
+	component string
+	// ops replays With and WithGroup calls on the delegate, in order.
+	ops []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= levelFor(h.component)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := append([]slog.Attr{slog.String("component", h.component)}, contextAttrs(ctx)...)
+	next := current().WithAttrs(attrs)
+	for _, op := range h.ops {
+		next = op(next)
+	}
+	return next.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{component: h.component, ops: append(ops, op)}
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are never logged, compared
+// after lowercasing and dropping '_' and '-'.
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"newpassword":   true,
+	"token":         true,
+	"accesstoken":   true,
+	"refreshtoken":  true,
+	"authorization": true,
+	"cookie":        true,
+	"setcookie":     true,
+	"secret":        true,
+	"jwtsecret":     true,
+	"dsn":           true,
+	"dburl":         true,
+	"smtppassword":  true,
+}
+
+var (
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`)
+	bearerPattern = regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._\-]+`)
+	dsnPattern    = regexp.MustCompile(`://([^:/@\s]+):([^@\s]+)@`)
+)
+
+// Redact is a slog ReplaceAttr function. It drops secrets by key, masks
+// emails, and scrubs emails, tokens and connection passwords out of strings
+// and errors, including the message itself.
+func Redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(a.Key))
+	if sensitiveKeys[key] {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if key == "email" {
+			return slog.String(a.Key, MaskEmail(a.Value.String()))
+		}
+		return slog.String(a.Key, Scrub(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			return slog.String(a.Key, Scrub(v.Error()))
+		case interface{ String() string }:
+			return slog.String(a.Key, Scrub(v.String()))
+		}
+	}
+	return a
+}
+
+// Scrub removes emails, bearer tokens, JWTs and URL passwords from s.
+func Scrub(s string) string {
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+redacted)
+	s = dsnPattern.ReplaceAllString(s, "://$1:"+redacted+"@")
+	return emailPattern.ReplaceAllStringFunc(s, MaskEmail)
+}
+
+// MaskEmail keeps the first character of the local part and the domain, so
+// "bob@example.com" becomes "b***@example.com".
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return redacted
+	}
+	return email[:1] + "***" + email[at:]
+}
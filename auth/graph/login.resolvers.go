@@ -0,0 +1,55 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.80
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// MarkLoginNotMe is the resolver for the markLoginNotMe field.
+func (r *mutationResolver) MarkLoginNotMe(ctx context.Context, id string) (*model.LoginEvent, error) {
+	claims := middleware.CtxValue(ctx)
+	if claims == nil {
+		return nil, apperr.Unauthenticated("user not authenticated")
+	}
+	login, err := r.LoginEventReport(ctx, id, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if login == nil {
+		return nil, apperr.NotFound("login not found")
+	}
+	return model.ConvertToGraphQLLoginEvent(*login, claims.SessionID), nil
+}
+
+// MyLoginHistory is the resolver for the myLoginHistory field.
+func (r *queryResolver) MyLoginHistory(ctx context.Context, limit *int32) ([]*model.LoginEvent, error) {
+	claims := middleware.CtxValue(ctx)
+	if claims == nil {
+		return nil, apperr.Unauthenticated("user not authenticated")
+	}
+	filter := model.LoginEventFilter{UserID: claims.ID, Limit: 20}
+	if limit != nil {
+		if *limit < 1 || *limit > maxLoginHistory {
+			return nil, apperr.Validation("limit must be between 1 and %d", maxLoginHistory)
+		}
+		filter.Limit = int(*limit)
+	}
+
+	logins, err := r.LoginEvents(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch login history: %w", err)
+	}
+	result := make([]*model.LoginEvent, 0, len(logins))
+	for _, login := range logins {
+		result = append(result, model.ConvertToGraphQLLoginEvent(*login, claims.SessionID))
+	}
+	return result, nil
+}
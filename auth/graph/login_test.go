@@ -0,0 +1,203 @@
+package graph_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph/events"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+)
+
+const (
+	laptop = "Mozilla/5.0 (X11; Linux x86_64; rv:130.0) Gecko/20100101 Firefox/130.0"
+	phone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+)
+
+const myLoginHistory = `query { myLoginHistory { id method outcome ip userAgent fingerprint current reportedAt } }`
+
+type loginEventResp struct {
+	ID          string
+	Method      string
+	Outcome     string
+	IP          string
+	UserAgent   string
+	Fingerprint string
+	Current     bool
+	ReportedAt  *string
+}
+
+// loginFrom logs in with the browser userAgent and returns the session.
+func loginFrom(t *testing.T, h *graphtest.Harness, email, userAgent string) graphtest.Session {
+	t.Helper()
+	var resp struct{ Login struct{ Token string } }
+	h.MustPost(`mutation($email: String!, $password: String!) { login(email: $email, password: $password) { token } }`,
+		&resp, client.Var("email", email), client.Var("password", graphtest.Password), client.AddHeader("User-Agent", userAgent))
+	return graphtest.Session{Token: resp.Login.Token}
+}
+
+func loginHistory(t *testing.T, h *graphtest.Harness, s graphtest.Session) []loginEventResp {
+	t.Helper()
+	var resp struct{ MyLoginHistory []loginEventResp }
+	h.MustPost(myLoginHistory, &resp, s.Auth())
+	return resp.MyLoginHistory
+}
+
+func newDeviceEvents(h *graphtest.Harness) []events.Event {
+	var out []events.Event
+	for _, e := range h.Events.Events() {
+		if e.Type == events.NewDeviceLogin {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func TestLoginHistoryRecordsAttempts(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.CreateUser("ada@example.com", "USER")
+
+	var failed struct{ Login struct{ Token string } }
+	err := h.Post(`mutation { login(email: "ada@example.com", password: "wrong") { token } }`, &failed, client.AddHeader("User-Agent", phone))
+	if err == nil {
+		t.Fatal("login with a wrong password succeeded")
+	}
+	session := loginFrom(t, h, user.Email, laptop)
+
+	history := loginHistory(t, h, session)
+	if len(history) != 2 {
+		t.Fatalf("history = %+v, want 2 entries", history)
+	}
+	ok, bad := history[0], history[1]
+	if ok.Outcome != "SUCCESS" || ok.Method != "PASSWORD" || ok.UserAgent != laptop || ok.IP != "192.0.2.1" || !ok.Current {
+		t.Errorf("successful login = %+v", ok)
+	}
+	if bad.Outcome != "BAD_PASSWORD" || bad.UserAgent != phone || bad.Current {
+		t.Errorf("failed login = %+v", bad)
+	}
+	if ok.Fingerprint == "" || ok.Fingerprint == bad.Fingerprint {
+		t.Errorf("fingerprints %q and %q should differ", ok.Fingerprint, bad.Fingerprint)
+	}
+
+	var anon struct{ MyLoginHistory []loginEventResp }
+	if err := h.Post(myLoginHistory, &anon); err == nil {
+		t.Error("myLoginHistory worked without a token")
+	}
+}
+
+func TestRegistrationStartsASession(t *testing.T) {
+	h := graphtest.New(t)
+	var resp struct{ Register struct{ Token string } }
+	h.MustPost(`mutation { register(input: {firstName: "Ada", lastName: "Lovelace", email: "ada@example.com", password: "secret"}) { token } }`,
+		&resp, client.AddHeader("User-Agent", laptop))
+	session := graphtest.Session{Token: resp.Register.Token}
+
+	history := loginHistory(t, h, session)
+	if len(history) != 1 || !history[0].Current || history[0].Outcome != "SUCCESS" {
+		t.Fatalf("history after registering = %+v", history)
+	}
+	// A first session is not a new device.
+	if got := newDeviceEvents(h); len(got) != 0 {
+		t.Errorf("new device events = %+v", got)
+	}
+}
+
+func TestLoginFromNewDeviceNotifiesUser(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.CreateUser("ada@example.com", "USER")
+
+	// The first sign-in has nothing to compare with.
+	loginFrom(t, h, user.Email, laptop)
+	loginFrom(t, h, user.Email, laptop)
+	if got := newDeviceEvents(h); len(got) != 0 {
+		t.Fatalf("events after signing in from one device: %+v", got)
+	}
+	if _, ok := h.Mail.Last(user.Email); ok {
+		t.Fatal("a known device triggered an email")
+	}
+
+	session := loginFrom(t, h, user.Email, phone)
+	got := newDeviceEvents(h)
+	if len(got) != 1 || got[0].UserID != user.ID || got[0].Data["user_agent"] != phone {
+		t.Fatalf("new device events = %+v", got)
+	}
+	if current := loginHistory(t, h, session)[0]; got[0].Data["login_id"] != current.ID {
+		t.Errorf("event login_id = %s, want %s", got[0].Data["login_id"], current.ID)
+	}
+	msg, ok := h.Mail.Last(user.Email)
+	if !ok || !strings.Contains(msg.Subject, "New sign-in") || !strings.Contains(msg.Body, phone) {
+		t.Fatalf("notification = %+v", msg)
+	}
+
+	loginFrom(t, h, user.Email, phone)
+	if got := newDeviceEvents(h); len(got) != 1 {
+		t.Fatalf("a known device was reported again: %+v", got)
+	}
+}
+
+func TestMarkLoginNotMeRevokesTheSession(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.CreateUser("ada@example.com", "USER")
+	mine := loginFrom(t, h, user.Email, laptop)
+	theirs := loginFrom(t, h, user.Email, phone)
+
+	history := loginHistory(t, h, mine)
+	intruder := history[0]
+	if intruder.UserAgent != phone || intruder.Current {
+		t.Fatalf("newest login = %+v", intruder)
+	}
+
+	var resp struct{ MarkLoginNotMe loginEventResp }
+	h.MustPost(`mutation($id: ID!) { markLoginNotMe(id: $id) { id reportedAt } }`, &resp, client.Var("id", intruder.ID), mine.Auth())
+	if resp.MarkLoginNotMe.ReportedAt == nil {
+		t.Fatalf("markLoginNotMe = %+v", resp.MarkLoginNotMe)
+	}
+
+	var me struct{ GetMe struct{ ID string } }
+	if err := h.Post(`query { getMe { id } }`, &me, theirs.Auth()); err == nil {
+		t.Fatal("the revoked session still works")
+	}
+	h.MustPost(`query { getMe { id } }`, &me, mine.Auth())
+
+	// Signing in again from the reported device counts as a new device.
+	loginFrom(t, h, user.Email, phone)
+	if got := newDeviceEvents(h); len(got) != 2 {
+		t.Fatalf("new device events = %+v, want the reported device flagged again", got)
+	}
+}
+
+func TestSessionSurvivesReissuedTokens(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.CreateUser("ada@example.com", "USER")
+	session := loginFrom(t, h, user.Email, laptop)
+
+	var resp struct{ SwitchOrganization struct{ Token string } }
+	h.MustPost(`mutation { switchOrganization(organizationId: null) { token } }`, &resp, session.Auth())
+	reissued := graphtest.Session{Token: resp.SwitchOrganization.Token}
+
+	var report struct{ MarkLoginNotMe struct{ ID string } }
+	h.MustPost(`mutation($id: ID!) { markLoginNotMe(id: $id) { id } }`, &report,
+		client.Var("id", loginHistory(t, h, session)[0].ID), session.Auth())
+
+	var me struct{ GetMe struct{ ID string } }
+	if err := h.Post(`query { getMe { id } }`, &me, reissued.Auth()); err == nil {
+		t.Fatal("a token reissued in a revoked session still works")
+	}
+}
+
+func TestMarkLoginNotMeOnlyForOwnLogins(t *testing.T) {
+	h := graphtest.New(t)
+	ada := h.CreateUser("ada@example.com", "USER")
+	grace := h.CreateUser("grace@example.com", "USER")
+	adaSession := loginFrom(t, h, ada.Email, laptop)
+	graceSession := loginFrom(t, h, grace.Email, laptop)
+
+	var resp struct{ MarkLoginNotMe struct{ ID string } }
+	err := h.Post(`mutation($id: ID!) { markLoginNotMe(id: $id) { id } }`, &resp,
+		client.Var("id", loginHistory(t, h, adaSession)[0].ID), graceSession.Auth())
+	if err == nil || !strings.Contains(err.Error(), "login not found") {
+		t.Fatalf("reporting another user's login: %v", err)
+	}
+	var me struct{ GetMe struct{ ID string } }
+	h.MustPost(`query { getMe { id } }`, &me, adaSession.Auth())
+}
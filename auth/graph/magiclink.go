@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/mailer"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+)
+
+const (
+	// maxMagicLinks bounds the links sent to one account per
+	// magicLinkWindow, so the mutation cannot be used to flood an inbox.
+	maxMagicLinks   = 5
+	magicLinkWindow = 15 * time.Minute
+	// magicLinkBytes is the entropy of link tokens and nonces.
+	magicLinkBytes = 32
+	invalidLink    = "invalid or expired sign-in link"
+)
+
+var magicLinkLogger = logging.For("magiclink")
+
+// sendMagicLink stores a link bound to nonce for the active account of email
+// and emails it. Errors are only logged: the caller answers the same whether
+// or not this worked, so the response never tells whether the email has an
+// account.
+func (r *Resolver) sendMagicLink(ctx context.Context, email, nonce string, expiresAt time.Time) {
+	user, err := r.UserByEmail(ctx, email)
+	if err != nil {
+		magicLinkLogger.ErrorContext(ctx, "failed to fetch user by email", slog.Any("error", err))
+		return
+	}
+	if user == nil || !user.Active(time.Now()) {
+		return
+	}
+
+	recent, err := r.MagicLinkCountSince(ctx, user.ID, time.Now().Add(-magicLinkWindow))
+	if err != nil {
+		magicLinkLogger.ErrorContext(ctx, "failed to count sign-in links", slog.Any("error", err))
+		return
+	}
+	if recent >= maxMagicLinks {
+		magicLinkLogger.WarnContext(ctx, "sign-in link limit reached", slog.String("user_id", user.ID))
+		return
+	}
+
+	token, err := utils.RandomToken(magicLinkBytes)
+	if err != nil {
+		magicLinkLogger.ErrorContext(ctx, "failed to create sign-in link", slog.Any("error", err))
+		return
+	}
+	_, err = r.MagicLinkCreation(ctx, &model.NewMagicLinkModel{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		NonceHash: utils.HashToken(nonce),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		magicLinkLogger.ErrorContext(ctx, "failed to store sign-in link", slog.Any("error", err))
+		return
+	}
+
+	link := r.PublicURL + "/login/magic-link?token=" + url.QueryEscape(token)
+	err = r.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your CloudMarket sign-in link",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Use this link to sign in to CloudMarket:\n\n%s\n\n"+
+			"It works once, within %s, and only in the browser where you asked for it.\n"+
+			"If you did not ask to sign in, you can ignore this email.\n",
+			user.FirstName, link, r.MagicLinkTTL),
+	})
+	if err != nil {
+		magicLinkLogger.ErrorContext(ctx, "failed to send sign-in link", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,64 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.80
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/accounts"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/metrics"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+)
+
+// RequestMagicLink is the resolver for the requestMagicLink field.
+func (r *mutationResolver) RequestMagicLink(ctx context.Context, email string) (*model.MagicLinkRequest, error) {
+	nonce, err := utils.RandomToken(magicLinkBytes)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(r.MagicLinkTTL)
+
+	// The account lookup and the email happen after the response, so that
+	// it takes as long whether or not the email has an account.
+	background := context.WithoutCancel(ctx)
+	r.Background(func() { r.sendMagicLink(background, strings.TrimSpace(email), nonce, expiresAt) })
+	return &model.MagicLinkRequest{Nonce: nonce, ExpiresAt: expiresAt}, nil
+}
+
+// LoginWithMagicLink is the resolver for the loginWithMagicLink field.
+func (r *mutationResolver) LoginWithMagicLink(ctx context.Context, token string, nonce string) (*model.AuthPayload, error) {
+	// The link is spent even when the nonce does not match, so a stolen
+	// link cannot be tried again.
+	link, err := r.MagicLinkConsume(ctx, utils.HashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check sign-in link: %w", err)
+	}
+	if link == nil || !utils.TokenMatches(nonce, link.NonceHash) {
+		metrics.LoginsTotal.WithLabelValues(metrics.LoginInvalidLink).Inc()
+		return nil, apperr.Unauthenticated(invalidLink)
+	}
+
+	user, err := r.UserByID(ctx, link.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if user == nil {
+		metrics.LoginsTotal.WithLabelValues(metrics.LoginInvalidLink).Inc()
+		return nil, apperr.Unauthenticated(invalidLink)
+	}
+	if err := accounts.StatusError(user, time.Now()); err != nil {
+		metrics.LoginsTotal.WithLabelValues(metrics.LoginLocked).Inc()
+		r.Accounts.RecordFailedLogin(ctx, user, model.LoginMethodMagicLink, model.LoginOutcomeAccountInactive)
+		return nil, err
+	}
+
+	metrics.LoginsTotal.WithLabelValues(metrics.LoginSuccess).Inc()
+	return authPayload(ctx)(r.Accounts.StartSession(ctx, user, model.LoginMethodMagicLink))
+}
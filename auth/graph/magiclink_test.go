@@ -0,0 +1,158 @@
+package graph_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+	"github.com/tabed23/cloudmarket-auth/graph/mailer"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+func requestMagicLink(t *testing.T, h *graphtest.Harness, email string) string {
+	t.Helper()
+	var resp struct {
+		RequestMagicLink struct {
+			Nonce     string
+			ExpiresAt string
+		}
+	}
+	h.MustPost(`mutation($email: String!) { requestMagicLink(email: $email) { nonce expiresAt } }`,
+		&resp, client.Var("email", email))
+	if resp.RequestMagicLink.Nonce == "" || resp.RequestMagicLink.ExpiresAt == "" {
+		t.Fatalf("requestMagicLink = %+v", resp.RequestMagicLink)
+	}
+	return resp.RequestMagicLink.Nonce
+}
+
+func magicLinkToken(t *testing.T, h *graphtest.Harness, email string) string {
+	t.Helper()
+	msg, ok := h.Mail.Last(email)
+	if !ok {
+		t.Fatalf("no email sent to %s", email)
+	}
+	start := strings.Index(msg.Body, graphtest.PublicURL+"/login/magic-link?")
+	if start < 0 {
+		t.Fatalf("no sign-in link in %q", msg.Body)
+	}
+	link, err := url.Parse(strings.Fields(msg.Body[start:])[0])
+	if err != nil {
+		t.Fatalf("parse sign-in link: %v", err)
+	}
+	return link.Query().Get("token")
+}
+
+func loginWithMagicLink(h *graphtest.Harness, token, nonce string) (string, error) {
+	var resp struct {
+		LoginWithMagicLink struct {
+			Token string
+			User  struct{ ID string }
+		}
+	}
+	err := h.Post(`mutation($token: String!, $nonce: String!) { loginWithMagicLink(token: $token, nonce: $nonce) { token user { id } } }`,
+		&resp, client.Var("token", token), client.Var("nonce", nonce))
+	return resp.LoginWithMagicLink.User.ID, err
+}
+
+func TestMagicLinkLogin(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.CreateUser("buyer@example.com", model.RoleUser)
+
+	nonce := requestMagicLink(t, h, user.Email)
+	token := magicLinkToken(t, h, user.Email)
+	if strings.Contains(h.Mail.Messages()[0].Body, nonce) {
+		t.Fatal("the nonce was emailed along with the link")
+	}
+
+	id, err := loginWithMagicLink(h, token, nonce)
+	if err != nil || id != user.ID {
+		t.Fatalf("loginWithMagicLink = %q, %v; want %s", id, err, user.ID)
+	}
+
+	if _, err := loginWithMagicLink(h, token, nonce); err == nil || !strings.Contains(err.Error(), "invalid or expired") {
+		t.Fatalf("second use of the link: %v", err)
+	}
+}
+
+func TestMagicLinkIsBoundToTheRequestingDevice(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.CreateUser("buyer@example.com", model.RoleUser)
+
+	nonce := requestMagicLink(t, h, user.Email)
+	token := magicLinkToken(t, h, user.Email)
+	otherNonce := requestMagicLink(t, h, "someone-else@example.com")
+
+	if _, err := loginWithMagicLink(h, token, otherNonce); err == nil {
+		t.Fatal("link accepted with another device's nonce")
+	}
+	// A failed attempt spends the link, so whoever tried it cannot retry.
+	if _, err := loginWithMagicLink(h, token, nonce); err == nil {
+		t.Fatal("link still usable after a wrong nonce")
+	}
+}
+
+func TestMagicLinkRequestDoesNotRevealAccounts(t *testing.T) {
+	h := graphtest.New(t)
+
+	requestMagicLink(t, h, "nobody@example.com")
+	if msgs := h.Mail.Messages(); len(msgs) != 0 {
+		t.Fatalf("mail sent for an unknown email: %+v", msgs)
+	}
+	if _, err := loginWithMagicLink(h, "made-up", "made-up"); err == nil {
+		t.Fatal("made-up link accepted")
+	}
+}
+
+// blockingMailer holds every email until released.
+type blockingMailer struct {
+	release chan struct{}
+	sent    chan mailer.Message
+}
+
+func (m blockingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	<-m.release
+	m.sent <- msg
+	return nil
+}
+
+func TestMagicLinkRequestDoesNotWaitForTheEmail(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.CreateUser("buyer@example.com", model.RoleUser)
+	mail := blockingMailer{release: make(chan struct{}), sent: make(chan mailer.Message, 1)}
+	c := client.New(middleware.AuthMiddleware(h.Repo)(graph.NewHandler(h.Repo, graph.WithMailer(mail))))
+
+	// The request answers while the email is still being sent, so a known
+	// address takes as long as an unknown one.
+	var resp struct{ RequestMagicLink struct{ Nonce string } }
+	if err := c.Post(`mutation($email: String!) { requestMagicLink(email: $email) { nonce } }`,
+		&resp, client.Var("email", user.Email)); err != nil {
+		t.Fatalf("requestMagicLink: %v", err)
+	}
+	close(mail.release)
+	select {
+	case msg := <-mail.sent:
+		if msg.To != user.Email {
+			t.Errorf("sign-in link sent to %s", msg.To)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sign-in link was not sent")
+	}
+}
+
+func TestMagicLinkRequestsAreLimited(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.CreateUser("buyer@example.com", model.RoleUser)
+
+	for i := 0; i < 7; i++ {
+		requestMagicLink(t, h, user.Email)
+	}
+	if got := len(h.Mail.Messages()); got != 5 {
+		t.Fatalf("%d sign-in emails sent, want 5", got)
+	}
+}
@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File writes every message to its own .eml file in a directory instead of
+// delivering it. It stands in for a mail server during development: links in
+// the messages can be followed straight from the files.
+type File struct {
+	dir  string
+	from string
+}
+
+// NewFile returns a mailer that writes messages below dir, creating it.
+func NewFile(dir, from string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &File{dir: dir, from: from}, nil
+}
+
+func (f *File) Send(ctx context.Context, msg Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+	_, _, data, err := msg.encode(f.from)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("failed to name message file: %w", err)
+	}
+	name := filepath.Join(f.dir, fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix)))
+
+	// Write under a temporary name so readers never see half a message.
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	log.InfoContext(ctx, "mail written to file",
+		slog.String("email", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("file", name),
+	)
+	return nil
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileWritesOneMessagePerFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	f, err := NewFile(dir, "CloudMarket <no-reply@cloudmarket.test>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, to := range []string{"ada@example.com", "grace@example.com"} {
+		if err := f.Send(context.Background(), Message{To: to, Subject: "Hello", Body: "Link: https://x.test/a?token=t\n"}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if err := f.Send(context.Background(), Message{To: "ada@example.com", Subject: "Hi\r\nBcc: evil@example.com"}); err == nil {
+		t.Error("header injection accepted")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("files = %v, %v; want 2", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"From: \"CloudMarket\" <no-reply@cloudmarket.test>\r\n", "To: <ada@example.com>\r\n", "Subject: Hello\r\n", "\r\n\r\nLink: https://x.test/a?token=t\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s lacks %q:\n%s", files[0], want, data)
+		}
+	}
+	if !strings.HasSuffix(files[0], ".eml") {
+		t.Errorf("file name %s", files[0])
+	}
+}
@@ -0,0 +1,25 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+)
+
+var log = logging.For("mailer")
+
+// Log records that a message was sent without delivering it. The body is not
+// logged because it usually carries a single-use link.
+type Log struct{}
+
+func (Log) Send(ctx context.Context, msg Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+	log.InfoContext(ctx, "mail not delivered, log driver",
+		slog.String("email", msg.To),
+		slog.String("subject", msg.Subject),
+	)
+	return nil
+}
@@ -0,0 +1,75 @@
+// Package mailer delivers the emails the auth service sends, such as
+// organization invitations. The transport is chosen by MAIL_DRIVER.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/config"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends messages. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer selected by cfg.
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case config.MailDriverLog:
+		return Log{}, nil
+	case config.MailDriverSMTP:
+		return NewSMTP(cfg.SMTPAddr, cfg.From, cfg.SMTPUsername, cfg.SMTPPassword), nil
+	case config.MailDriverFile:
+		return NewFile(cfg.Dir, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// Validate rejects messages without a recipient and line breaks that would
+// let a caller inject headers. Mailers check messages with it before sending.
+func (m Message) Validate() error {
+	if m.To == "" {
+		return fmt.Errorf("message has no recipient")
+	}
+	if strings.ContainsAny(m.To+m.Subject, "\r\n") {
+		return fmt.Errorf("message headers must not contain line breaks")
+	}
+	return nil
+}
+
+// encode renders the message as an RFC 5322 email from the sender from. It
+// returns the bare sender and recipient addresses along with it.
+func (m Message) encode(sender string) (from, to string, data []byte, err error) {
+	fromAddr, err := mail.ParseAddress(sender)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+	toAddr, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", fromAddr.String())
+	fmt.Fprintf(&body, "To: %s\r\n", toAddr.String())
+	fmt.Fprintf(&body, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(m.Body)
+	return fromAddr.Address, toAddr.Address, body.Bytes(), nil
+}
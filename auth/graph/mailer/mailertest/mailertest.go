@@ -0,0 +1,44 @@
+// Package mailertest provides a mailer.Mailer for tests.
+package mailertest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tabed23/cloudmarket-auth/graph/mailer"
+)
+
+// Recorder keeps sent messages in memory so tests can read them back.
+type Recorder struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (r *Recorder) Send(ctx context.Context, msg mailer.Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far.
+func (r *Recorder) Messages() []mailer.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]mailer.Message(nil), r.messages...)
+}
+
+// Last returns the most recent message sent to to.
+func (r *Recorder) Last(to string) (mailer.Message, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.messages) - 1; i >= 0; i-- {
+		if r.messages[i].To == to {
+			return r.messages[i], true
+		}
+	}
+	return mailer.Message{}, false
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// otherOperation labels operations whose name is not in Tracer.Operations.
+const otherOperation = "other"
+
+// Tracer is a gqlgen extension that records operation and resolver latency
+// and error counts.
+//
+// Clients choose operation names, so only those in Operations are used as
+// labels and any other name is recorded as "other"; otherwise every new
+// name would add series until Prometheus runs out of memory.
+type Tracer struct {
+	Operations map[string]bool
+}
+
+// NewTracer returns a Tracer labelling the given operation names.
+func NewTracer(operations ...string) Tracer {
+	t := Tracer{Operations: make(map[string]bool, len(operations))}
+	for _, name := range operations {
+		t.Operations[name] = true
+	}
+	return t
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = Tracer{}
+
+func (Tracer) ExtensionName() string {
+	return "Metrics"
+}
+
+func (Tracer) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (t Tracer) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if !graphql.HasOperationContext(ctx) {
+		return resp
+	}
+
+	oc := graphql.GetOperationContext(ctx)
+	name, typ := t.operationLabels(oc)
+	OperationDuration.WithLabelValues(name, typ).Observe(time.Since(oc.Stats.OperationStart).Seconds())
+	if resp != nil && len(resp.Errors) > 0 {
+		OperationErrors.WithLabelValues(name, typ).Inc()
+	}
+	return resp
+}
+
+func (Tracer) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	// Plain struct fields are not worth a histogram sample each.
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	start := time.Now()
+	res, err := next(ctx)
+	FieldDuration.WithLabelValues(fc.Object, fc.Field.Name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		FieldErrors.WithLabelValues(fc.Object, fc.Field.Name).Inc()
+	}
+	return res, err
+}
+
+func (t Tracer) operationLabels(oc *graphql.OperationContext) (name, typ string) {
+	name, typ = oc.OperationName, "unknown"
+	if oc.Operation != nil {
+		typ = string(oc.Operation.Operation)
+		if name == "" {
+			name = oc.Operation.Name
+		}
+	}
+	switch {
+	case name == "":
+		name = "anonymous"
+	case !t.Operations[name]:
+		name = otherOperation
+	}
+	return name, typ
+}
@@ -0,0 +1,70 @@
+package metrics_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/tabed23/cloudmarket-auth/graph"
+	"github.com/tabed23/cloudmarket-auth/graph/metrics"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/memory"
+)
+
+// series returns the label values of every series of c, e.g. "GetMe query".
+func series(t *testing.T, c prometheus.Collector) []string {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 100)
+	c.Collect(ch)
+	close(ch)
+	var got []string
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		values := make([]string, len(pb.GetLabel()))
+		for i, l := range pb.GetLabel() {
+			values[i] = l.GetName() + "=" + l.GetValue()
+		}
+		got = append(got, strings.Join(values, " "))
+	}
+	slices.Sort(got)
+	return got
+}
+
+func TestOperationLabels(t *testing.T) {
+	metrics.OperationDuration.Reset()
+	metrics.OperationErrors.Reset()
+	c := client.New(graph.NewHandler(memory.NewStore(), graph.WithMetricOperations("GetMe")))
+
+	var resp map[string]any
+	for _, query := range []string{
+		`query GetMe { __typename }`,
+		`query Probe1 { __typename }`,
+		`query Probe2 { __typename }`,
+		`{ __typename }`,
+		`mutation Logout { logout }`,
+	} {
+		if err := c.Post(query, &resp); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+	// Errors are labelled the same way.
+	c.Post(`query Probe3 { protected }`, &resp)
+
+	want := []string{
+		"operation=GetMe type=query",
+		"operation=anonymous type=query",
+		"operation=other type=mutation",
+		"operation=other type=query",
+	}
+	if got := series(t, metrics.OperationDuration); !slices.Equal(got, want) {
+		t.Errorf("operation_duration_seconds series = %q; want %q", got, want)
+	}
+	if got := series(t, metrics.OperationErrors); !slices.Equal(got, []string{"operation=other type=query"}) {
+		t.Errorf("operation_errors_total series = %q", got)
+	}
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+)
+
+const namespace = "auth"
+
+// Login outcomes recorded by LoginsTotal.
+const (
+	LoginSuccess      = "success"
+	LoginBadPassword  = "bad_password"
+	LoginLocked       = "locked"
+	LoginUnknownEmail = "unknown_email"
+	LoginInvalidLink  = "invalid_link"
+)
+
+// Outcomes recorded by RegistrationsTotal and TokenValidationsTotal.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	TokenValid     = "valid"
+	TokenInvalid   = "invalid"
+	TokenInactive  = "inactive"
+)
+
+// Results recorded by UserCacheLookupsTotal.
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+var (
+	OperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "graphql",
+		Name:      "operation_duration_seconds",
+		Help:      "Latency of GraphQL operations from receipt to response.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "type"})
+
+	OperationErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "graphql",
+		Name:      "operation_errors_total",
+		Help:      "GraphQL operations that returned at least one error.",
+	}, []string{"operation", "type"})
+
+	FieldDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "graphql",
+		Name:      "field_duration_seconds",
+		Help:      "Latency of GraphQL field resolvers.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"object", "field"})
+
+	FieldErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "graphql",
+		Name:      "field_errors_total",
+		Help:      "GraphQL field resolvers that returned an error.",
+	}, []string{"object", "field"})
+
+	LoginsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by outcome.",
+	}, []string{"outcome"})
+
+	RegistrationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Registration attempts by outcome.",
+	}, []string{"outcome"})
+
+	TokenValidationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_validations_total",
+		Help:      "Bearer tokens checked by the auth middleware, by result.",
+	}, []string{"result"})
+
+	UserCacheLookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "user_cache_lookups_total",
+		Help:      "User lookups through the read-through cache, by result.",
+	}, []string{"result"})
+
+	BackgroundDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "background_tasks_dropped_total",
+		Help:      "Background tasks, such as sign-in link emails, dropped because the queue was full.",
+	})
+)
+
+func init() {
+	// Export every outcome from the start so rates work before the first event.
+	for _, outcome := range []string{LoginSuccess, LoginBadPassword, LoginLocked, LoginUnknownEmail, LoginInvalidLink} {
+		LoginsTotal.WithLabelValues(outcome)
+	}
+	for _, outcome := range []string{OutcomeSuccess, OutcomeFailure} {
+		RegistrationsTotal.WithLabelValues(outcome)
+	}
+	for _, result := range []string{TokenValid, TokenInvalid, TokenInactive} {
+		TokenValidationsTotal.WithLabelValues(result)
+	}
+	for _, result := range []string{CacheHit, CacheMiss, CacheError} {
+		UserCacheLookupsTotal.WithLabelValues(result)
+	}
+}
+
+// RegisterDBStats exports the connection pool statistics of db.
+func RegisterDBStats(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database pool: %w", err)
+	}
+	return prometheus.Register(collectors.NewDBStatsCollector(sqlDB, namespace))
+}
@@ -2,34 +2,149 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/metrics"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
 )
 
-// AuthMiddleware checks the Authorization header for a JWT and validates it
-func AuthMiddleware(next http.Handler) http.Handler {
+var authLogger = logging.For("auth")
+
+// Accounts finds the account a token was issued to and the login that
+// started its session.
+type Accounts interface {
+	UserByID(ctx context.Context, id string) (*model.UserModel, error)
+	LoginEventByID(ctx context.Context, id string) (*model.LoginEventModel, error)
+}
+
+// AuthMiddleware checks the Authorization header, or the access cookie if
+// CookieSessions is enabled, for a JWT and validates it. The account the
+// token belongs to must still exist and be active, and its session must not
+// be revoked, so that suspending a user or marking a login as not theirs
+// takes effect before the tokens expire.
+func AuthMiddleware(accounts Accounts) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return authMiddleware(accounts, next)
+	}
+}
+
+func authMiddleware(accounts Accounts, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader != "" {
-			// Extract token from the header
-			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-			if tokenStr != "" {
-				// Validate token and claims
-				claims, err := jwt.ValidateJwt(context.Background(), tokenStr)
-				if err == nil {
-					// Set the claims in the request context
-					ctx := context.WithValue(r.Context(), "auth_claims", claims)
-					r = r.WithContext(ctx)
+		tokenStr, viaCookie := requestToken(r)
+		if tokenStr != "" {
+			// Validate token and claims
+			claims, err := jwt.ValidateJwt(context.Background(), tokenStr)
+			var user *model.UserModel
+			if err == nil {
+				user = activeAccount(r.Context(), accounts, claims)
+			}
+			if err == nil && user == nil {
+				metrics.TokenValidationsTotal.WithLabelValues(metrics.TokenInactive).Inc()
+			} else if err == nil {
+				metrics.TokenValidationsTotal.WithLabelValues(metrics.TokenValid).Inc()
+				logging.SetUserID(r.Context(), claims.ID)
+				// Set the claims in the request context
+				ctx := context.WithValue(r.Context(), "auth_claims", claims)
+				ctx = context.WithValue(ctx, userKey{}, user)
+				if viaCookie {
+					ctx = context.WithValue(ctx, csrfKey{}, !ValidCSRF(r))
 				}
+				r = r.WithContext(ctx)
+			} else {
+				metrics.TokenValidationsTotal.WithLabelValues(metrics.TokenInvalid).Inc()
 			}
 		}
-		
+
 		next.ServeHTTP(w, r)
 	})
 }
 
+// requestToken returns the bearer token of the Authorization header or,
+// in the cookie session mode, of AccessCookie. The header wins when both
+// are sent.
+func requestToken(r *http.Request) (token string, viaCookie bool) {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		return strings.TrimPrefix(authHeader, "Bearer "), false
+	}
+	if !CookieSessionsEnabled(r.Context()) {
+		return "", false
+	}
+	if cookie, err := r.Cookie(AccessCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, true
+	}
+	return "", false
+}
+
+// ErrInactive is returned by Authenticate for valid tokens of accounts that
+// may not use the API, or of revoked sessions.
+var ErrInactive = errors.New("account inactive or session revoked")
+
+// Authenticate validates an access token and checks its account and session
+// like AuthMiddleware does.
+func Authenticate(ctx context.Context, accounts Accounts, token string) (*jwt.JwtClaims, error) {
+	claims, err := jwt.ValidateJwt(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if activeAccount(ctx, accounts, claims) == nil {
+		return nil, ErrInactive
+	}
+	return claims, nil
+}
+
+// activeAccount returns the user of claims if they exist and may use the
+// API with claims, and for impersonation tokens if the acting admin still
+// may; otherwise it returns nil. A failed lookup counts as inactive: the
+// request continues anonymously rather than with a token that may have been
+// revoked.
+func activeAccount(ctx context.Context, accounts Accounts, claims *jwt.JwtClaims) *model.UserModel {
+	user, err := accounts.UserByID(ctx, claims.ID)
+	if err != nil {
+		authLogger.ErrorContext(ctx, "failed to look up token owner", slog.Any("error", err))
+		return nil
+	}
+	if user == nil || !user.Active(time.Now()) {
+		return nil
+	}
+	if claims.Impersonated() {
+		// The admin acting as the user must still be an active admin.
+		actor, err := accounts.UserByID(ctx, claims.Act.ID)
+		if err != nil {
+			authLogger.ErrorContext(ctx, "failed to look up impersonating admin", slog.Any("error", err))
+			return nil
+		}
+		if actor == nil || actor.Role != model.RoleAdmin || !actor.Active(time.Now()) {
+			return nil
+		}
+	}
+	if claims.SessionID == "" {
+		return user
+	}
+	login, err := accounts.LoginEventByID(ctx, claims.SessionID)
+	if err != nil {
+		authLogger.ErrorContext(ctx, "failed to look up session", slog.Any("error", err))
+		return nil
+	}
+	if login == nil || login.UserID != user.ID || login.Revoked() {
+		return nil
+	}
+	return user
+}
+
+type userKey struct{}
+
+// CtxUser returns the account the request's token belongs to, as
+// AuthMiddleware loaded it. Unlike the claims, its role is current.
+func CtxUser(ctx context.Context) *model.UserModel {
+	user, _ := ctx.Value(userKey{}).(*model.UserModel)
+	return user
+}
 
 // CtxValue retrieves JWT claims from the context
 func CtxValue(ctx context.Context) *jwt.JwtClaims {
@@ -4,14 +4,45 @@ import (
 	"context"
 
 	"github.com/99designs/gqlgen/graphql"
-	"github.com/vektah/gqlparser/gqlerror"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
 )
 func Auth(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
 	tokenData := CtxValue(ctx)
 	if tokenData == nil {
-		return nil, &gqlerror.Error{
-			Message: "Access Denied",
-		}
+		return nil, apperr.Unauthenticated("Access Denied")
 	}
 	return next(ctx)
-}
\ No newline at end of file
+}
+
+// Visibility resolves a User field only for admins and, for SELF scoped
+// fields, for the user the object belongs to. Every other caller gets null.
+// The admin role is read from the account, not the token, so a demoted
+// admin loses access before their token expires.
+func Visibility(ctx context.Context, obj interface{}, next graphql.Resolver, scope model.VisibilityScope) (interface{}, error) {
+	claims := CtxValue(ctx)
+	if user := CtxUser(ctx); claims != nil && user != nil && user.Role == model.RoleAdmin {
+		return next(ctx)
+	}
+	if scope != model.VisibilityScopeSelf {
+		return nil, nil
+	}
+	// The user in an AuthPayload is the caller that just logged in or
+	// registered, before any token is attached to the request.
+	if fc := graphql.GetFieldContext(ctx); fc != nil && fc.Parent != nil && fc.Parent.Object == "AuthPayload" {
+		return next(ctx)
+	}
+	if user, ok := obj.(*model.User); ok && claims != nil && user.ID == claims.ID {
+		return next(ctx)
+	}
+	return nil, nil
+}
+
+// NotImpersonated refuses a field to impersonation tokens. It guards what
+// only the account holder may do, such as changing the password.
+func NotImpersonated(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
+	if claims := CtxValue(ctx); claims != nil && claims.Impersonated() {
+		return nil, apperr.Forbidden("Not allowed while impersonating a user")
+	}
+	return next(ctx)
+}
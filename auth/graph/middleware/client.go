@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientKey struct{}
+
+// Client describes the device a request came from.
+type Client struct {
+	IP             string
+	UserAgent      string
+	AcceptLanguage string
+}
+
+// Fingerprint identifies the device across requests. It is derived from
+// the browser headers only, so it stays the same when the device changes
+// networks; it tells devices apart for notifications, not for security.
+func (c Client) Fingerprint() string {
+	sum := sha256.Sum256([]byte(c.UserAgent + "\n" + c.AcceptLanguage))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ClientInfo records the Client of every request. With trustProxy the IP is
+// the last X-Forwarded-For entry, the one added by the proxy in front of the
+// service; otherwise it is the address of the connection.
+func ClientInfo(trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := Client{
+				IP:             remoteIP(r, trustProxy),
+				UserAgent:      r.UserAgent(),
+				AcceptLanguage: r.Header.Get("Accept-Language"),
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientKey{}, client)))
+		})
+	}
+}
+
+// ClientFrom returns the Client recorded by ClientInfo, or the zero Client.
+func ClientFrom(ctx context.Context) Client {
+	client, _ := ctx.Value(clientKey{}).(Client)
+	return client
+}
+
+func remoteIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			hops := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); net.ParseIP(ip) != nil {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
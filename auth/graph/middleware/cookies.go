@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+)
+
+// Cookies of the cookie session mode. The token cookies are HttpOnly; the
+// CSRF cookie is readable by scripts, which echo it in CSRFHeader.
+const (
+	AccessCookie  = "cm_access"
+	RefreshCookie = "cm_refresh"
+	CSRFCookie    = "cm_csrf"
+	CSRFHeader    = "X-CSRF-Token"
+)
+
+// CookieOptions are the attributes of the session cookies. The cookies are
+// always Secure and HttpOnly; browsers accept Secure cookies from
+// http://localhost, so local development still works.
+type CookieOptions struct {
+	Domain   string
+	SameSite http.SameSite
+}
+
+type cookieKey struct{}
+
+type cookieSession struct {
+	opts CookieOptions
+	w    http.ResponseWriter
+	// signInAllowed is whether the request may set new session cookies.
+	signInAllowed bool
+}
+
+// ErrCrossSiteSignIn refuses to set session cookies on a request from
+// another site, which could sign the browser into someone else's account.
+var ErrCrossSiteSignIn = apperr.Forbidden("cross-site sign-in refused: send the request from the same origin or with the %s header", CSRFHeader)
+
+type csrfKey struct{}
+
+// CookieSessions enables the cookie session mode: AuthMiddleware then also
+// accepts the access token from AccessCookie, and SetSessionCookies sets the
+// cookies on sign in. It must run before AuthMiddleware.
+func CookieSessions(opts CookieOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session := &cookieSession{opts: opts, w: w, signInAllowed: ValidCSRF(r) || sameOrigin(r)}
+			ctx := context.WithValue(r.Context(), cookieKey{}, session)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// CookieSessionsEnabled reports whether the request passed through
+// CookieSessions.
+func CookieSessionsEnabled(ctx context.Context) bool {
+	return ctx.Value(cookieKey{}) != nil
+}
+
+// SignInAllowed reports whether the request may sign in: in the cookie
+// session mode, only requests from the same origin or with a valid CSRF
+// token may, so that a cross-site form cannot plant a session.
+func SignInAllowed(ctx context.Context) bool {
+	session, _ := ctx.Value(cookieKey{}).(*cookieSession)
+	return session == nil || session.signInAllowed
+}
+
+// SetSessionCookies sets the token cookies and a new CSRF token on the
+// response, and reports whether it did. It does nothing unless the cookie
+// session mode is enabled, fails with ErrCrossSiteSignIn unless
+// SignInAllowed, and must be called before the response is written. When it
+// sets the cookies, callers leave the tokens out of the response body, where
+// scripts could read them.
+func SetSessionCookies(ctx context.Context, token, refreshToken string) (bool, error) {
+	session, _ := ctx.Value(cookieKey{}).(*cookieSession)
+	if session == nil {
+		return false, nil
+	}
+	if !session.signInAllowed {
+		return false, ErrCrossSiteSignIn
+	}
+	csrf, err := utils.RandomToken(32)
+	if err != nil {
+		return false, err
+	}
+	session.set(AccessCookie, token, jwt.AccessTokenTTL(), true)
+	session.set(RefreshCookie, refreshToken, jwt.RefreshTokenTTL(), true)
+	session.set(CSRFCookie, csrf, jwt.RefreshTokenTTL(), false)
+	return true, nil
+}
+
+// ClearSessionCookies expires the session cookies, signing the browser out.
+func ClearSessionCookies(ctx context.Context) {
+	session, _ := ctx.Value(cookieKey{}).(*cookieSession)
+	if session == nil {
+		return
+	}
+	for _, name := range []string{AccessCookie, RefreshCookie, CSRFCookie} {
+		session.set(name, "", -1, name != CSRFCookie)
+	}
+}
+
+func (s *cookieSession) set(name, value string, ttl time.Duration, httpOnly bool) {
+	maxAge := int(ttl / time.Second)
+	if ttl < 0 {
+		maxAge = -1
+	}
+	http.SetCookie(s.w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   s.opts.Domain,
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: httpOnly,
+		SameSite: s.opts.SameSite,
+	})
+}
+
+// ValidCSRF reports whether the request echoes its CSRF cookie in
+// CSRFHeader. A cross-site page can make the browser send the cookies but
+// cannot read them to set the header.
+func ValidCSRF(r *http.Request) bool {
+	cookie, err := r.Cookie(CSRFCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	header := r.Header.Get(CSRFHeader)
+	return subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) == 1
+}
+
+// sameOrigin reports whether the request does not come from another site.
+// Browsers mark cross-site requests with Sec-Fetch-Site or, before they
+// sent it, with an Origin that differs from the host; clients that send
+// neither are not browsers and cannot be made to send a request.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "same-site", "cross-site":
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// CSRFFailed reports whether the request was authenticated by AccessCookie
+// without a valid CSRF token. Such requests may read but not change
+// anything.
+func CSRFFailed(ctx context.Context) bool {
+	failed, _ := ctx.Value(csrfKey{}).(bool)
+	return failed
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+var auditLogger = logging.For("audit")
+
+// ErrAuditUnavailable refuses requests made while impersonating that could
+// not be written to the audit log.
+var ErrAuditUnavailable = errors.New("request refused: the audit log is unavailable")
+
+// Auditor writes audit log entries.
+type Auditor interface {
+	AuditLogCreation(ctx context.Context, input *model.NewAuditLogModel) (*model.AuditLogModel, error)
+}
+
+// AuditImpersonation records a request made with the impersonation token of
+// claims, such as a GraphQL operation or a REST endpoint. It does nothing
+// for other tokens. Every API calls it before serving the request and
+// refuses the request if it fails, so that nothing done while impersonating
+// goes unrecorded.
+func AuditImpersonation(ctx context.Context, audit Auditor, claims *jwt.JwtClaims, operation, detail string) error {
+	if claims == nil || !claims.Impersonated() {
+		return nil
+	}
+	_, err := audit.AuditLogCreation(ctx, &model.NewAuditLogModel{
+		ActorID:   claims.Act.ID,
+		SubjectID: claims.ID,
+		Action:    model.AuditImpersonatedRequest,
+		Operation: operation,
+		Detail:    detail,
+		RequestID: logging.RequestID(ctx),
+	})
+	if err != nil {
+		auditLogger.ErrorContext(ctx, "failed to write audit log", slog.Any("error", err))
+		return ErrAuditUnavailable
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+	"gorm.io/gorm"
+)
+
+// EmailCollision is a set of users whose email addresses are spellings of
+// the same mailbox, such as "Bob@x.com" and "bob@x.com". They must be merged
+// or renamed before addresses can be made unique under normalization.
+type EmailCollision struct {
+	Normalized string
+	Users      []CollidingUser
+}
+
+// CollidingUser is one account of an EmailCollision.
+type CollidingUser struct {
+	ID    string
+	Email string
+}
+
+func (c EmailCollision) String() string {
+	users := make([]string, len(c.Users))
+	for i, u := range c.Users {
+		users[i] = fmt.Sprintf("%s (%s)", u.ID, u.Email)
+	}
+	return c.Normalized + ": " + strings.Join(users, ", ")
+}
+
+// FindEmailCollisions lists the users of db that share a normalized email
+// address, ordered by address.
+func FindEmailCollisions(ctx context.Context, db *gorm.DB) ([]EmailCollision, error) {
+	var users []CollidingUser
+	if err := db.WithContext(ctx).Table("users").Select("id, email").Order("created_at, id").Scan(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to read user emails: %w", err)
+	}
+
+	byKey := map[string][]CollidingUser{}
+	for _, u := range users {
+		key := utils.EmailKey(u.Email)
+		byKey[key] = append(byKey[key], u)
+	}
+	var collisions []EmailCollision
+	for key, group := range byKey {
+		if len(group) > 1 {
+			collisions = append(collisions, EmailCollision{Normalized: key, Users: group})
+		}
+	}
+	sort.Slice(collisions, func(i, j int) bool { return collisions[i].Normalized < collisions[j].Normalized })
+	return collisions, nil
+}
+
+// normalizeEmails fills users.email_normalized. It refuses to run, listing
+// every collision, while two users share a normalized address.
+func normalizeEmails(ctx context.Context, tx *gorm.DB) error {
+	collisions, err := FindEmailCollisions(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if len(collisions) > 0 {
+		lines := make([]string, len(collisions))
+		for i, c := range collisions {
+			lines[i] = "  " + c.String()
+		}
+		return fmt.Errorf("%d email addresses are shared by several users; merge or rename them and migrate again:\n%s",
+			len(collisions), strings.Join(lines, "\n"))
+	}
+
+	var users []CollidingUser
+	if err := tx.WithContext(ctx).Table("users").Select("id, email").Scan(&users).Error; err != nil {
+		return fmt.Errorf("failed to read user emails: %w", err)
+	}
+	for _, u := range users {
+		err := tx.WithContext(ctx).Table("users").Where("id = ?", u.ID).Update("email_normalized", utils.EmailKey(u.Email)).Error
+		if err != nil {
+			return fmt.Errorf("failed to normalize email of user %s: %w", u.ID, err)
+		}
+	}
+	return nil
+}
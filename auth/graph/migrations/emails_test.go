@@ -0,0 +1,104 @@
+package migrations
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/config"
+	"gorm.io/gorm"
+)
+
+// emptyDB opens an empty SQLite database and its migrator.
+func emptyDB(t *testing.T) (*gorm.DB, *Migrator) {
+	t.Helper()
+	db, err := config.InitDB(config.DBConfig{Driver: config.DriverSQLite, URL: ":memory:"})
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	m, err := New(db)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return db, m
+}
+
+// migratedTo opens an empty SQLite database with the migrations before
+// version applied.
+func migratedTo(t *testing.T, version int) (*gorm.DB, *Migrator) {
+	t.Helper()
+	db, m := emptyDB(t)
+	before := &Migrator{db: db}
+	for _, mig := range m.migrations {
+		if mig.Version < version {
+			before.migrations = append(before.migrations, mig)
+		}
+	}
+	if _, err := before.Up(context.Background()); err != nil {
+		t.Fatalf("migrate to %d: %v", version, err)
+	}
+	return db, m
+}
+
+func insertUser(t *testing.T, db *gorm.DB, id, email string) {
+	t.Helper()
+	err := db.Exec("INSERT INTO users (id, email, created_at) VALUES (?, ?, ?)", id, email, time.Now()).Error
+	if err != nil {
+		t.Fatalf("insert %s: %v", email, err)
+	}
+}
+
+func TestNormalizeEmailsReportsCollisions(t *testing.T) {
+	ctx := context.Background()
+	db, m := migratedTo(t, 10)
+	insertUser(t, db, "u1", "Bob@Example.com")
+	insertUser(t, db, "u2", "bob@example.com ")
+	insertUser(t, db, "u3", "carol@bücher.de")
+	insertUser(t, db, "u4", "Carol@xn--bcher-kva.de")
+	insertUser(t, db, "u5", "dave@example.com")
+
+	collisions, err := FindEmailCollisions(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collisions) != 2 || collisions[0].String() != "bob@example.com: u1 (Bob@Example.com), u2 (bob@example.com )" ||
+		collisions[1].Normalized != "carol@xn--bcher-kva.de" || len(collisions[1].Users) != 2 {
+		t.Fatalf("collisions = %v", collisions)
+	}
+
+	_, err = m.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "2 email addresses are shared") || !strings.Contains(err.Error(), "u4 (Carol@xn--bcher-kva.de)") {
+		t.Fatalf("Up with collisions: %v", err)
+	}
+	if pending, _ := m.Pending(ctx); pending == 0 {
+		t.Fatal("the failed migration was recorded as applied")
+	}
+
+	if err := db.Exec("DELETE FROM users WHERE id IN ('u2', 'u4')").Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up after resolving collisions: %v", err)
+	}
+	var keys []string
+	db.Raw("SELECT email_normalized FROM users ORDER BY id").Scan(&keys)
+	if strings.Join(keys, ",") != "bob@example.com,carol@xn--bcher-kva.de,dave@example.com" {
+		t.Fatalf("email_normalized = %v", keys)
+	}
+	if err := db.Exec("INSERT INTO users (id, email, email_normalized) VALUES ('u6', 'BOB@example.com', 'bob@example.com')").Error; err == nil {
+		t.Fatal("a duplicate normalized address was accepted")
+	}
+
+	if _, err := m.Down(ctx, 1); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up again: %v", err)
+	}
+}
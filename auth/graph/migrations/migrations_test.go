@@ -0,0 +1,152 @@
+package migrations
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func versions(migrations []Migration) []int {
+	out := make([]int, len(migrations))
+	for i, m := range migrations {
+		out[i] = m.Version
+	}
+	return out
+}
+
+func TestLoadMatchesAcrossDialects(t *testing.T) {
+	postgres, err := Load("postgres")
+	if err != nil {
+		t.Fatalf("Load(postgres): %v", err)
+	}
+	sqlite, err := Load("sqlite")
+	if err != nil {
+		t.Fatalf("Load(sqlite): %v", err)
+	}
+	if len(postgres) == 0 || len(postgres) != len(sqlite) {
+		t.Fatalf("%d postgres and %d sqlite migrations", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+			t.Errorf("migration %d: postgres %d_%s, sqlite %d_%s", i,
+				postgres[i].Version, postgres[i].Name, sqlite[i].Version, sqlite[i].Name)
+		}
+		if i > 0 && postgres[i].Version <= postgres[i-1].Version {
+			t.Errorf("versions out of order: %v", versions(postgres))
+		}
+	}
+	if _, err := Load("mysql"); err == nil {
+		t.Error("Load(mysql) succeeded")
+	}
+}
+
+func TestUpDownRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db, m := emptyDB(t)
+	all := len(m.migrations)
+
+	// Reading the status of an empty database does not create anything.
+	if pending, err := m.Pending(ctx); err != nil || pending != all {
+		t.Fatalf("Pending before Up = %d, %v; want %d", pending, err, all)
+	}
+	if db.Migrator().HasTable(trackingTable) {
+		t.Fatal("Pending created the tracking table")
+	}
+
+	done, err := m.Up(ctx)
+	if err != nil || len(done) != all {
+		t.Fatalf("Up = %v, %v; want %d migrations", versions(done), err, all)
+	}
+	var rows []appliedMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		t.Fatalf("read %s: %v", trackingTable, err)
+	}
+	if len(rows) != all {
+		t.Fatalf("%s has %d rows; want %d", trackingTable, len(rows), all)
+	}
+	for i, row := range rows {
+		if row.Version != m.migrations[i].Version || row.Name != m.migrations[i].Name || row.AppliedAt.IsZero() {
+			t.Errorf("%s row %d = %+v; want %d_%s", trackingTable, i, row, m.migrations[i].Version, m.migrations[i].Name)
+		}
+	}
+	if again, err := m.Up(ctx); err != nil || len(again) != 0 {
+		t.Errorf("second Up = %v, %v; want nothing to apply", versions(again), err)
+	}
+
+	undone, err := m.Down(ctx, all)
+	if err != nil || len(undone) != all {
+		t.Fatalf("Down = %v, %v; want %d migrations", versions(undone), err, all)
+	}
+	if undone[0].Version != m.migrations[all-1].Version {
+		t.Errorf("Down rolled back %v; want the newest first", versions(undone))
+	}
+	if db.Migrator().HasTable("users") {
+		t.Error("users table survived Down")
+	}
+	if pending, err := m.Pending(ctx); err != nil || pending != all {
+		t.Errorf("Pending after Down = %d, %v; want %d", pending, err, all)
+	}
+
+	if done, err := m.Up(ctx); err != nil || len(done) != all {
+		t.Fatalf("Up after Down = %v, %v", versions(done), err)
+	}
+	if pending, err := m.Pending(ctx); err != nil || pending != 0 {
+		t.Errorf("Pending after Up = %d, %v; want 0", pending, err)
+	}
+}
+
+func TestStatusAfterPartialApply(t *testing.T) {
+	ctx := context.Background()
+	_, m := emptyDB(t)
+	third := m.migrations[2].Version
+	_, m = migratedTo(t, third)
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(statuses) != len(m.migrations) {
+		t.Fatalf("Status lists %d migrations; want %d", len(statuses), len(m.migrations))
+	}
+	for _, s := range statuses {
+		want := s.Version < third
+		if s.Applied != want || (s.AppliedAt != nil) != want {
+			t.Errorf("status of %d_%s = %+v; want applied %v", s.Version, s.Name, s, want)
+		}
+	}
+	if pending, err := m.Pending(ctx); err != nil || pending != len(m.migrations)-2 {
+		t.Errorf("Pending = %d, %v; want %d", pending, err, len(m.migrations)-2)
+	}
+
+	// Down only rolls back applied migrations, newest first.
+	undone, err := m.Down(ctx, 1)
+	if err != nil || len(undone) != 1 || undone[0].Version != m.migrations[1].Version {
+		t.Fatalf("Down(1) = %v, %v; want %d", versions(undone), err, m.migrations[1].Version)
+	}
+	if pending, _ := m.Pending(ctx); pending != len(m.migrations)-1 {
+		t.Errorf("Pending after Down(1) = %d; want %d", pending, len(m.migrations)-1)
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	db, _ := emptyDB(t)
+	m := &Migrator{db: db, migrations: []Migration{
+		{Version: 1, Name: "good", Up: "CREATE TABLE good (id INTEGER)", Down: "DROP TABLE good"},
+		{Version: 2, Name: "bad", Up: "CREATE TABLE half (id INTEGER); INSERT INTO missing VALUES (1)", Down: "DROP TABLE half"},
+	}}
+
+	done, err := m.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "2_bad") {
+		t.Fatalf("Up = %v; want the failure of 2_bad", err)
+	}
+	if len(done) != 1 || done[0].Version != 1 {
+		t.Errorf("Up applied %v; want [1]", versions(done))
+	}
+	if db.Migrator().HasTable("half") {
+		t.Error("the failed migration left its table behind")
+	}
+	if pending, _ := m.Pending(ctx); pending != 1 {
+		t.Errorf("Pending = %d; want the failed migration", pending)
+	}
+}
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN status TEXT NOT NULL DEFAULT 'ACTIVE';
+ALTER TABLE users ADD COLUMN status_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN status_until TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_status ON users (status);
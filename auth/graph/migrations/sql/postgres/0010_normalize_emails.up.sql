@@ -0,0 +1,5 @@
+-- The Go step of this migration fills email_normalized, after checking that
+-- no two users share a normalized address.
+ALTER TABLE users ADD COLUMN email_normalized TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_normalized ON users (email_normalized);
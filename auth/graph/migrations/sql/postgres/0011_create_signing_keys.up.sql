@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id          TEXT PRIMARY KEY,
+    secret      TEXT NOT NULL,
+    active_from TIMESTAMPTZ NOT NULL,
+    expires_at  TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS users (
+    id            TEXT PRIMARY KEY,
+    first_name    TEXT NOT NULL DEFAULT '',
+    last_name     TEXT NOT NULL DEFAULT '',
+    email         TEXT NOT NULL,
+    password      TEXT NOT NULL DEFAULT '',
+    token         TEXT NOT NULL DEFAULT '',
+    refresh_token TEXT NOT NULL DEFAULT '',
+    role          TEXT NOT NULL DEFAULT 'USER',
+    created_at    DATETIME,
+    updated_at    DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE INDEX IF NOT EXISTS idx_users_role ON users (role);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    id         TEXT PRIMARY KEY,
+    name       TEXT NOT NULL,
+    slug       TEXT NOT NULL,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_slug ON organizations (slug);
+
+CREATE TABLE IF NOT EXISTS memberships (
+    id              TEXT PRIMARY KEY,
+    organization_id TEXT NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id         TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role            TEXT NOT NULL DEFAULT 'MEMBER',
+    created_at      DATETIME,
+    updated_at      DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_memberships_organization_user ON memberships (organization_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_memberships_user ON memberships (user_id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS invitations (
+    id              TEXT PRIMARY KEY,
+    organization_id TEXT NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    email           TEXT NOT NULL,
+    role            TEXT NOT NULL DEFAULT 'MEMBER',
+    invited_by      TEXT NOT NULL DEFAULT '',
+    expires_at      DATETIME NOT NULL,
+    accepted_at     DATETIME,
+    revoked_at      DATETIME,
+    created_at      DATETIME,
+    updated_at      DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_invitations_organization ON invitations (organization_id);
+CREATE INDEX IF NOT EXISTS idx_invitations_email ON invitations (email);
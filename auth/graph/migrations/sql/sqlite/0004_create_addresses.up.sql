@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS addresses (
+    id                  TEXT PRIMARY KEY,
+    user_id             TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    full_name           TEXT NOT NULL,
+    street              TEXT NOT NULL,
+    city                TEXT NOT NULL,
+    state               TEXT NOT NULL DEFAULT '',
+    postal_code         TEXT NOT NULL DEFAULT '',
+    country             TEXT NOT NULL,
+    phone               TEXT NOT NULL DEFAULT '',
+    is_default_shipping BOOLEAN NOT NULL DEFAULT 0,
+    is_default_billing  BOOLEAN NOT NULL DEFAULT 0,
+    created_at          DATETIME,
+    updated_at          DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_addresses_user ON addresses (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_addresses_default_shipping ON addresses (user_id) WHERE is_default_shipping;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_addresses_default_billing ON addresses (user_id) WHERE is_default_billing;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id         TEXT PRIMARY KEY,
+    actor_id   TEXT NOT NULL,
+    subject_id TEXT NOT NULL DEFAULT '',
+    action     TEXT NOT NULL,
+    operation  TEXT NOT NULL DEFAULT '',
+    detail     TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_subject ON audit_logs (subject_id, created_at);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS magic_links (
+    id         TEXT PRIMARY KEY,
+    user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL,
+    nonce_hash TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at    DATETIME,
+    created_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_magic_links_token ON magic_links (token_hash);
+CREATE INDEX IF NOT EXISTS idx_magic_links_user ON magic_links (user_id, created_at);
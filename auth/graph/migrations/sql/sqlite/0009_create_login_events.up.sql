@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS login_events (
+    id          TEXT PRIMARY KEY,
+    user_id     TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    method      TEXT NOT NULL,
+    outcome     TEXT NOT NULL,
+    ip          TEXT NOT NULL DEFAULT '',
+    user_agent  TEXT NOT NULL DEFAULT '',
+    fingerprint TEXT NOT NULL DEFAULT '',
+    reported_at DATETIME,
+    created_at  DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user ON login_events (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_events_fingerprint ON login_events (user_id, fingerprint);
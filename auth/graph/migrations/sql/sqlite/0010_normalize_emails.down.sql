@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_email_normalized;
+
+ALTER TABLE users DROP COLUMN email_normalized;
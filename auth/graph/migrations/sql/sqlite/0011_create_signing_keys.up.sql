@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id          TEXT PRIMARY KEY,
+    secret      TEXT NOT NULL,
+    active_from DATETIME NOT NULL,
+    expires_at  DATETIME,
+    created_at  DATETIME NOT NULL
+);
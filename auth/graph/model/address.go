@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// AddressModel is a postal address in a user's address book. A user has at
+// most one default shipping and one default billing address.
+type AddressModel struct {
+	ID                string    `gorm:"primaryKey" json:"id"`
+	UserID            string    `json:"userId"`
+	FullName          string    `json:"fullName"`
+	Street            string    `json:"street"`
+	City              string    `json:"city"`
+	State             string    `json:"state"`
+	PostalCode        string    `json:"postalCode"`
+	Country           string    `json:"country"`
+	Phone             string    `json:"phone"`
+	IsDefaultShipping bool      `json:"isDefaultShipping"`
+	IsDefaultBilling  bool      `json:"isDefaultBilling"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+type NewAddressModel struct {
+	FullName          string `json:"fullName"`
+	Street            string `json:"street"`
+	City              string `json:"city"`
+	State             string `json:"state"`
+	PostalCode        string `json:"postalCode"`
+	Country           string `json:"country"`
+	Phone             string `json:"phone"`
+	IsDefaultShipping bool   `json:"isDefaultShipping"`
+	IsDefaultBilling  bool   `json:"isDefaultBilling"`
+}
+
+func (AddressModel) TableName() string {
+	return "addresses"
+}
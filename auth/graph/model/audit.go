@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// Audit log actions.
+const (
+	// AuditImpersonationStart is written when an admin is issued a token to
+	// act as another user.
+	AuditImpersonationStart = "impersonation.start"
+	// AuditImpersonatedRequest is written for every operation run with such
+	// a token.
+	AuditImpersonatedRequest = "impersonation.request"
+	// AuditUserStatus is written when an admin changes an account status.
+	AuditUserStatus = "user.status"
+)
+
+// AuditLogModel is one entry of the append-only audit log. Entries outlive
+// the users they mention, so the IDs are not foreign keys.
+type AuditLogModel struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	ActorID   string    `json:"actorId"`
+	SubjectID string    `json:"subjectId"`
+	Action    string    `json:"action"`
+	Operation string    `json:"operation"`
+	Detail    string    `json:"detail"`
+	RequestID string    `json:"requestId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type NewAuditLogModel struct {
+	ActorID   string `json:"actorId"`
+	SubjectID string `json:"subjectId"`
+	Action    string `json:"action"`
+	Operation string `json:"operation"`
+	Detail    string `json:"detail"`
+	RequestID string `json:"requestId"`
+}
+
+// AuditLogFilter selects audit log entries. Empty fields match everything;
+// entries come newest first, at most Limit of them.
+type AuditLogFilter struct {
+	ActorID   string
+	SubjectID string
+	Action    string
+	Limit     int
+}
+
+func (AuditLogModel) TableName() string {
+	return "audit_logs"
+}
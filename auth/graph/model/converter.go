@@ -1,16 +1,31 @@
 package model
 
+import "time"
+
+// ConvertToGraphQLUser maps a stored user to the public GraphQL type. Secrets
+// such as the password hash and issued tokens have no counterpart on User and
+// are dropped here.
 func ConvertToGraphQLUser(userModel UserModel) *User {
+	status := AccountStatus(userModel.EffectiveStatus(time.Now()))
+	var reason *string
+	var until *time.Time
+	if status != AccountStatusActive {
+		reason = &userModel.StatusReason
+		until = userModel.StatusUntil
+	}
 	return &User{
-		ID:           userModel.ID,
-		FirstName:    userModel.FirstName,
-		LastName:     userModel.LastName,
-		Email:        userModel.Email,
-		Token:        nil,
-		RefreshToken: nil,
-		Role:         userModel.Role,
-		CreatedAt:    &userModel.CreatedAt,
-		UpdatedAt:    &userModel.UpdatedAt,
+		ID:        userModel.ID,
+		FirstName: userModel.FirstName,
+		LastName:  userModel.LastName,
+		Email:     &userModel.Email,
+		Role:      &userModel.Role,
+		CreatedAt: &userModel.CreatedAt,
+		UpdatedAt: &userModel.UpdatedAt,
+		AvatarKey: userModel.AvatarKey,
+
+		Status:         &status,
+		StatusReason:   reason,
+		SuspendedUntil: until,
 	}
 }
 
@@ -24,18 +39,24 @@ func ConvertToGraphQLNewUser(newUserModel NewUserModel) *NewUser {
 	}
 }
 func ConvertToUserModel(user User) *UserModel {
-	return &UserModel{
-		ID:           user.ID,
-		FirstName:    user.FirstName,
-		LastName:     user.LastName,
-		Email:        user.Email,
-		Password:     user.Password,
-		Token:        *user.Token,
-		RefreshToken: *user.RefreshToken,
-		Role:         user.Role,
-		CreatedAt:    *user.CreatedAt,
-		UpdatedAt:    *user.UpdatedAt,
+	usr := &UserModel{
+		ID:        user.ID,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	}
+	if user.Email != nil {
+		usr.Email = *user.Email
+	}
+	if user.Role != nil {
+		usr.Role = *user.Role
+	}
+	if user.CreatedAt != nil {
+		usr.CreatedAt = *user.CreatedAt
+	}
+	if user.UpdatedAt != nil {
+		usr.UpdatedAt = *user.UpdatedAt
 	}
+	return usr
 }
 
 func ConvertToNewUserModel(newUser NewUser) *NewUserModel {
@@ -46,3 +67,86 @@ func ConvertToNewUserModel(newUser NewUser) *NewUserModel {
 		Password:  newUser.Password,
 	}
 }
+
+func ConvertToGraphQLOrganization(org OrganizationModel) *Organization {
+	return &Organization{
+		ID:        org.ID,
+		Name:      org.Name,
+		Slug:      org.Slug,
+		CreatedAt: &org.CreatedAt,
+		UpdatedAt: &org.UpdatedAt,
+	}
+}
+
+// ConvertToGraphQLMembership maps a membership together with the organization
+// and user it links.
+func ConvertToGraphQLMembership(m MembershipModel, org OrganizationModel, user UserModel) *Membership {
+	return &Membership{
+		ID:           m.ID,
+		Organization: ConvertToGraphQLOrganization(org),
+		User:         ConvertToGraphQLUser(user),
+		Role:         OrganizationRole(m.Role),
+		CreatedAt:    &m.CreatedAt,
+		UpdatedAt:    &m.UpdatedAt,
+	}
+}
+
+func ConvertToGraphQLInvitation(inv InvitationModel, org OrganizationModel) *Invitation {
+	return &Invitation{
+		ID:           inv.ID,
+		Organization: ConvertToGraphQLOrganization(org),
+		Email:        inv.Email,
+		Role:         OrganizationRole(inv.Role),
+		ExpiresAt:    inv.ExpiresAt,
+		CreatedAt:    &inv.CreatedAt,
+	}
+}
+
+func ConvertToGraphQLAddress(address AddressModel) *Address {
+	a := &Address{
+		ID:                address.ID,
+		FullName:          address.FullName,
+		Street:            address.Street,
+		City:              address.City,
+		State:             address.State,
+		PostalCode:        address.PostalCode,
+		Country:           address.Country,
+		IsDefaultShipping: address.IsDefaultShipping,
+		IsDefaultBilling:  address.IsDefaultBilling,
+		CreatedAt:         &address.CreatedAt,
+		UpdatedAt:         &address.UpdatedAt,
+	}
+	if address.Phone != "" {
+		a.Phone = &address.Phone
+	}
+	return a
+}
+
+// ConvertToGraphQLLoginEvent maps a login event; sessionID is the session of
+// the caller, to flag their current sign-in.
+func ConvertToGraphQLLoginEvent(event LoginEventModel, sessionID string) *LoginEvent {
+	return &LoginEvent{
+		ID:          event.ID,
+		Method:      event.Method,
+		Outcome:     event.Outcome,
+		IP:          event.IP,
+		UserAgent:   event.UserAgent,
+		Fingerprint: event.Fingerprint,
+		Current:     sessionID != "" && event.ID == sessionID,
+		ReportedAt:  event.ReportedAt,
+		CreatedAt:   event.CreatedAt,
+	}
+}
+
+func ConvertToGraphQLAuditLogEntry(entry AuditLogModel) *AuditLogEntry {
+	return &AuditLogEntry{
+		ID:        entry.ID,
+		ActorID:   entry.ActorID,
+		SubjectID: entry.SubjectID,
+		Action:    entry.Action,
+		Operation: entry.Operation,
+		Detail:    entry.Detail,
+		RequestID: entry.RequestID,
+		CreatedAt: entry.CreatedAt,
+	}
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// InvitationModel is an offer to join an organization, sent by email. It is
+// pending until it is accepted, revoked or expires.
+type InvitationModel struct {
+	ID             string     `gorm:"primaryKey" json:"id"`
+	OrganizationID string     `json:"organizationId"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	InvitedBy      string     `json:"invitedBy"`
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	AcceptedAt     *time.Time `json:"acceptedAt"`
+	RevokedAt      *time.Time `json:"revokedAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+type NewInvitationModel struct {
+	OrganizationID string    `json:"organizationId"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	InvitedBy      string    `json:"invitedBy"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+}
+
+func (InvitationModel) TableName() string {
+	return "invitations"
+}
+
+// Pending reports whether the invitation can still be accepted at now.
+func (i *InvitationModel) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// LoginEventModel is one sign-in attempt on an existing account. The ID of
+// a successful attempt is also the session ID carried by the tokens it
+// issued, so reporting the attempt revokes them.
+type LoginEventModel struct {
+	ID          string       `gorm:"primaryKey" json:"id"`
+	UserID      string       `json:"userId"`
+	Method      LoginMethod  `json:"method"`
+	Outcome     LoginOutcome `json:"outcome"`
+	IP          string       `json:"ip"`
+	UserAgent   string       `json:"userAgent"`
+	Fingerprint string       `json:"fingerprint"`
+	// ReportedAt is set when the user marks the attempt as not theirs.
+	ReportedAt *time.Time `json:"reportedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type NewLoginEventModel struct {
+	UserID      string       `json:"userId"`
+	Method      LoginMethod  `json:"method"`
+	Outcome     LoginOutcome `json:"outcome"`
+	IP          string       `json:"ip"`
+	UserAgent   string       `json:"userAgent"`
+	Fingerprint string       `json:"fingerprint"`
+}
+
+// LoginEventFilter selects login events. Empty fields match everything;
+// events come newest first, at most Limit of them.
+type LoginEventFilter struct {
+	UserID      string
+	Outcome     LoginOutcome
+	Fingerprint string
+	Limit       int
+}
+
+// Revoked reports whether the session started by this login was revoked.
+func (e *LoginEventModel) Revoked() bool {
+	return e.ReportedAt != nil
+}
+
+func (LoginEventModel) TableName() string {
+	return "login_events"
+}
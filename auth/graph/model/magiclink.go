@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// MagicLinkModel is a single-use sign-in link. Only hashes of the emailed
+// token and of the nonce kept by the requesting device are stored.
+type MagicLinkModel struct {
+	ID        string     `gorm:"primaryKey" json:"id"`
+	UserID    string     `json:"userId"`
+	TokenHash string     `json:"-"`
+	NonceHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type NewMagicLinkModel struct {
+	UserID    string    `json:"userId"`
+	TokenHash string    `json:"-"`
+	NonceHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (MagicLinkModel) TableName() string {
+	return "magic_links"
+}
@@ -3,18 +3,123 @@
 package model
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
 	"time"
 )
 
+type Address struct {
+	ID         string `json:"id"`
+	FullName   string `json:"fullName"`
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	PostalCode string `json:"postalCode"`
+	// ISO 3166-1 alpha-2 country code.
+	Country           string     `json:"country"`
+	Phone             *string    `json:"phone,omitempty"`
+	IsDefaultShipping bool       `json:"isDefaultShipping"`
+	IsDefaultBilling  bool       `json:"isDefaultBilling"`
+	CreatedAt         *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt         *time.Time `json:"updatedAt,omitempty"`
+}
+
+// The postal code is checked against the format of the country and may be
+// omitted only for countries without postal codes. Leaving a default flag out
+// keeps its current value; the first address of a user becomes both defaults.
+type AddressInput struct {
+	FullName          string  `json:"fullName"`
+	Street            string  `json:"street"`
+	City              string  `json:"city"`
+	State             *string `json:"state,omitempty"`
+	PostalCode        *string `json:"postalCode,omitempty"`
+	Country           string  `json:"country"`
+	Phone             *string `json:"phone,omitempty"`
+	IsDefaultShipping *bool   `json:"isDefaultShipping,omitempty"`
+	IsDefaultBilling  *bool   `json:"isDefaultBilling,omitempty"`
+}
+
+type AuditLogEntry struct {
+	ID string `json:"id"`
+	// The admin who acted.
+	ActorID string `json:"actorId"`
+	// The user acted as.
+	SubjectID string    `json:"subjectId"`
+	Action    string    `json:"action"`
+	Operation string    `json:"operation"`
+	Detail    string    `json:"detail"`
+	RequestID string    `json:"requestId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
 type AuthPayload struct {
-	Token        string `json:"token"`
+	// Access token for the Authorization header. Empty in the cookie session
+	// mode, which sets the tokens as HttpOnly cookies instead.
+	Token string `json:"token"`
+	// Empty in the cookie session mode, like token.
 	RefreshToken string `json:"refreshToken"`
 	User         *User  `json:"user"`
 }
 
+type ImpersonationPayload struct {
+	// Short-lived access token acting as user. It cannot be refreshed.
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	User      *User     `json:"user"`
+}
+
+type Invitation struct {
+	ID           string           `json:"id"`
+	Organization *Organization    `json:"organization"`
+	Email        string           `json:"email"`
+	Role         OrganizationRole `json:"role"`
+	ExpiresAt    time.Time        `json:"expiresAt"`
+	CreatedAt    *time.Time       `json:"createdAt,omitempty"`
+}
+
+// A sign-in attempt on the caller's account.
+type LoginEvent struct {
+	ID        string       `json:"id"`
+	Method    LoginMethod  `json:"method"`
+	Outcome   LoginOutcome `json:"outcome"`
+	IP        string       `json:"ip"`
+	UserAgent string       `json:"userAgent"`
+	// Identifies the device. It is the same for every sign-in from one browser.
+	Fingerprint string `json:"fingerprint"`
+	// Whether the request asking belongs to the session this sign-in started.
+	Current bool `json:"current"`
+	// When the user marked the sign-in as not theirs, which revoked its session.
+	ReportedAt *time.Time `json:"reportedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// Returned to the device that asked for a sign-in link. The link only works
+// together with nonce, so a link forwarded to or intercepted by someone else
+// is useless to them.
+type MagicLinkRequest struct {
+	Nonce     string    `json:"nonce"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type Membership struct {
+	ID           string           `json:"id"`
+	Organization *Organization    `json:"organization"`
+	User         *User            `json:"user"`
+	Role         OrganizationRole `json:"role"`
+	CreatedAt    *time.Time       `json:"createdAt,omitempty"`
+	UpdatedAt    *time.Time       `json:"updatedAt,omitempty"`
+}
+
 type Mutation struct {
 }
 
+type NewOrganization struct {
+	Name string  `json:"name"`
+	Slug *string `json:"slug,omitempty"`
+}
+
 type NewUser struct {
 	FirstName string     `json:"firstName"`
 	LastName  string     `json:"lastName"`
@@ -23,18 +128,419 @@ type NewUser struct {
 	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
 }
 
+type Organization struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Slug      string     `json:"slug"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
 type Query struct {
 }
 
-type User struct {
-	ID           string     `json:"id"`
-	FirstName    string     `json:"firstName"`
-	LastName     string     `json:"lastName"`
-	Email        string     `json:"email"`
-	Password     string     `json:"password"`
-	Token        *string    `json:"token,omitempty"`
-	RefreshToken *string    `json:"refreshToken,omitempty"`
-	Role         string     `json:"role"`
-	CreatedAt    *time.Time `json:"createdAt,omitempty"`
-	UpdatedAt    *time.Time `json:"updatedAt,omitempty"`
+type UserByIDsInput struct {
+	ID string `json:"ID"`
+}
+
+type AccountStatus string
+
+const (
+	AccountStatusActive AccountStatus = "ACTIVE"
+	// Temporarily blocked, until suspendedUntil if set.
+	AccountStatusSuspended AccountStatus = "SUSPENDED"
+	// Blocked until an admin reactivates the account.
+	AccountStatusDisabled AccountStatus = "DISABLED"
+	// Waiting for the email address to be confirmed.
+	AccountStatusPendingVerification AccountStatus = "PENDING_VERIFICATION"
+)
+
+var AllAccountStatus = []AccountStatus{
+	AccountStatusActive,
+	AccountStatusSuspended,
+	AccountStatusDisabled,
+	AccountStatusPendingVerification,
+}
+
+func (e AccountStatus) IsValid() bool {
+	switch e {
+	case AccountStatusActive, AccountStatusSuspended, AccountStatusDisabled, AccountStatusPendingVerification:
+		return true
+	}
+	return false
+}
+
+func (e AccountStatus) String() string {
+	return string(e)
+}
+
+func (e *AccountStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AccountStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AccountStatus", str)
+	}
+	return nil
+}
+
+func (e AccountStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *AccountStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e AccountStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type AddressKind string
+
+const (
+	AddressKindShipping AddressKind = "SHIPPING"
+	AddressKindBilling  AddressKind = "BILLING"
+)
+
+var AllAddressKind = []AddressKind{
+	AddressKindShipping,
+	AddressKindBilling,
+}
+
+func (e AddressKind) IsValid() bool {
+	switch e {
+	case AddressKindShipping, AddressKindBilling:
+		return true
+	}
+	return false
+}
+
+func (e AddressKind) String() string {
+	return string(e)
+}
+
+func (e *AddressKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AddressKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AddressKind", str)
+	}
+	return nil
+}
+
+func (e AddressKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *AddressKind) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e AddressKind) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+// Avatars are stored as square JPEG renditions of 64, 128 and 256 pixels.
+type AvatarSize string
+
+const (
+	AvatarSizeSmall  AvatarSize = "SMALL"
+	AvatarSizeMedium AvatarSize = "MEDIUM"
+	AvatarSizeLarge  AvatarSize = "LARGE"
+)
+
+var AllAvatarSize = []AvatarSize{
+	AvatarSizeSmall,
+	AvatarSizeMedium,
+	AvatarSizeLarge,
+}
+
+func (e AvatarSize) IsValid() bool {
+	switch e {
+	case AvatarSizeSmall, AvatarSizeMedium, AvatarSizeLarge:
+		return true
+	}
+	return false
+}
+
+func (e AvatarSize) String() string {
+	return string(e)
+}
+
+func (e *AvatarSize) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AvatarSize(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AvatarSize", str)
+	}
+	return nil
+}
+
+func (e AvatarSize) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *AvatarSize) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e AvatarSize) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type LoginMethod string
+
+const (
+	LoginMethodPassword  LoginMethod = "PASSWORD"
+	LoginMethodMagicLink LoginMethod = "MAGIC_LINK"
+)
+
+var AllLoginMethod = []LoginMethod{
+	LoginMethodPassword,
+	LoginMethodMagicLink,
+}
+
+func (e LoginMethod) IsValid() bool {
+	switch e {
+	case LoginMethodPassword, LoginMethodMagicLink:
+		return true
+	}
+	return false
+}
+
+func (e LoginMethod) String() string {
+	return string(e)
+}
+
+func (e *LoginMethod) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = LoginMethod(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid LoginMethod", str)
+	}
+	return nil
+}
+
+func (e LoginMethod) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *LoginMethod) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e LoginMethod) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type LoginOutcome string
+
+const (
+	LoginOutcomeSuccess     LoginOutcome = "SUCCESS"
+	LoginOutcomeBadPassword LoginOutcome = "BAD_PASSWORD"
+	// The password was right but the account is suspended or disabled.
+	LoginOutcomeAccountInactive LoginOutcome = "ACCOUNT_INACTIVE"
+)
+
+var AllLoginOutcome = []LoginOutcome{
+	LoginOutcomeSuccess,
+	LoginOutcomeBadPassword,
+	LoginOutcomeAccountInactive,
+}
+
+func (e LoginOutcome) IsValid() bool {
+	switch e {
+	case LoginOutcomeSuccess, LoginOutcomeBadPassword, LoginOutcomeAccountInactive:
+		return true
+	}
+	return false
+}
+
+func (e LoginOutcome) String() string {
+	return string(e)
+}
+
+func (e *LoginOutcome) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = LoginOutcome(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid LoginOutcome", str)
+	}
+	return nil
+}
+
+func (e LoginOutcome) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *LoginOutcome) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e LoginOutcome) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type OrganizationRole string
+
+const (
+	OrganizationRoleOwner  OrganizationRole = "OWNER"
+	OrganizationRoleAdmin  OrganizationRole = "ADMIN"
+	OrganizationRoleMember OrganizationRole = "MEMBER"
+)
+
+var AllOrganizationRole = []OrganizationRole{
+	OrganizationRoleOwner,
+	OrganizationRoleAdmin,
+	OrganizationRoleMember,
+}
+
+func (e OrganizationRole) IsValid() bool {
+	switch e {
+	case OrganizationRoleOwner, OrganizationRoleAdmin, OrganizationRoleMember:
+		return true
+	}
+	return false
+}
+
+func (e OrganizationRole) String() string {
+	return string(e)
+}
+
+func (e *OrganizationRole) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrganizationRole(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrganizationRole", str)
+	}
+	return nil
+}
+
+func (e OrganizationRole) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *OrganizationRole) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e OrganizationRole) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+// SELF fields are returned to the owner of the object and to admins, ADMIN
+// fields to admins only. Everyone else gets null.
+type VisibilityScope string
+
+const (
+	VisibilityScopeSelf  VisibilityScope = "SELF"
+	VisibilityScopeAdmin VisibilityScope = "ADMIN"
+)
+
+var AllVisibilityScope = []VisibilityScope{
+	VisibilityScopeSelf,
+	VisibilityScopeAdmin,
+}
+
+func (e VisibilityScope) IsValid() bool {
+	switch e {
+	case VisibilityScopeSelf, VisibilityScopeAdmin:
+		return true
+	}
+	return false
+}
+
+func (e VisibilityScope) String() string {
+	return string(e)
+}
+
+func (e *VisibilityScope) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = VisibilityScope(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid VisibilityScope", str)
+	}
+	return nil
+}
+
+func (e VisibilityScope) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *VisibilityScope) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e VisibilityScope) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// Per-organization roles. They are independent of the platform-wide
+// RoleUser/RoleAdmin stored on the user.
+const (
+	OrgRoleOwner  = "OWNER"
+	OrgRoleAdmin  = "ADMIN"
+	OrgRoleMember = "MEMBER"
+)
+
+type OrganizationModel struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `gorm:"unique" json:"slug"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type NewOrganizationModel struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+func (OrganizationModel) TableName() string {
+	return "organizations"
+}
+
+// MembershipModel links a user to an organization with a role in it.
+type MembershipModel struct {
+	ID             string    `gorm:"primaryKey" json:"id"`
+	OrganizationID string    `json:"organizationId"`
+	UserID         string    `json:"userId"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func (MembershipModel) TableName() string {
+	return "memberships"
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// SigningKeyModel is a rotated token signing key. The ID is the "kid" header
+// of the tokens it signs.
+type SigningKeyModel struct {
+	ID string `gorm:"primaryKey" json:"id"`
+	// Secret is the HMAC secret as sealed by jwt.SealSecret, or hex-encoded
+	// for keys stored before secrets were encrypted.
+	Secret     string    `json:"-"`
+	ActiveFrom time.Time `json:"activeFrom"`
+	// ExpiresAt is set once a newer key replaces this one.
+	ExpiresAt *time.Time `json:"expiresAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type NewSigningKeyModel struct {
+	ID         string    `json:"id"`
+	Secret     string    `json:"-"`
+	ActiveFrom time.Time `json:"activeFrom"`
+}
+
+func (SigningKeyModel) TableName() string {
+	return "signing_keys"
+}
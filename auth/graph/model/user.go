@@ -2,17 +2,38 @@ package model
 
 import "time"
 
+const (
+	RoleUser  = "USER"
+	RoleAdmin = "ADMIN"
+)
+
+// Account statuses. Only ACTIVE accounts can sign in or use their tokens; a
+// suspension with an end date lapses back to ACTIVE on its own.
+const (
+	StatusActive              = "ACTIVE"
+	StatusSuspended           = "SUSPENDED"
+	StatusDisabled            = "DISABLED"
+	StatusPendingVerification = "PENDING_VERIFICATION"
+)
+
 type UserModel struct {
-	ID           string    `gorm:"primaryKey" json:"id"`
-	FirstName    string    `json:"firstName"`
-	LastName     string    `json:"lastName"`
-	Email        string    `gorm:"unique" json:"email"`
-	Password     string    `json:"password"`
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refreshToken"`
-	Role         string    `json:"role"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ID        string `gorm:"primaryKey" json:"id"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `gorm:"unique" json:"email"`
+	// EmailNormalized is Email in the form of utils.EmailKey. It is unique
+	// and is what lookups by email compare.
+	EmailNormalized string     `json:"-"`
+	Password        string     `json:"password"`
+	Token           string     `json:"token"`
+	RefreshToken    string     `json:"refreshToken"`
+	Role            string     `json:"role"`
+	AvatarKey       string     `json:"avatarKey"`
+	Status          string     `json:"status"`
+	StatusReason    string     `json:"statusReason"`
+	StatusUntil     *time.Time `json:"statusUntil"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
 }
 
 type NewUserModel struct {
@@ -22,6 +43,45 @@ type NewUserModel struct {
 	Password  string `json:"password"`
 	Role      string `json:"role"`
 }
+
+// User is the GraphQL User type. It is bound in gqlgen.yml rather than
+// generated so that avatarUrl can be resolved from AvatarKey.
+type User struct {
+	ID        string     `json:"id"`
+	FirstName string     `json:"firstName"`
+	LastName  string     `json:"lastName"`
+	Email     *string    `json:"email,omitempty"`
+	Role      *string    `json:"role,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	AvatarKey string     `json:"-"`
+
+	Status         *AccountStatus `json:"status,omitempty"`
+	StatusReason   *string        `json:"statusReason,omitempty"`
+	SuspendedUntil *time.Time     `json:"suspendedUntil,omitempty"`
+}
+
+// IsEntity marks User as a federation entity, keyed by id.
+func (User) IsEntity() {}
+
 func (UserModel) TableName() string {
 	return "users"
-}
\ No newline at end of file
+}
+
+// EffectiveStatus is Status as of now, with lapsed suspensions back to
+// ACTIVE. Accounts stored before statuses existed count as ACTIVE.
+func (u *UserModel) EffectiveStatus(now time.Time) string {
+	switch {
+	case u.Status == "":
+		return StatusActive
+	case u.Status == StatusSuspended && u.StatusUntil != nil && !now.Before(*u.StatusUntil):
+		return StatusActive
+	default:
+		return u.Status
+	}
+}
+
+// Active reports whether the account may sign in and use its tokens.
+func (u *UserModel) Active(now time.Time) bool {
+	return u.EffectiveStatus(now) == StatusActive
+}
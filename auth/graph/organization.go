@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// activeMembership returns the caller's membership in the organization
+// selected by switchOrganization. The role is read from storage rather than
+// the token, so role changes apply without a new token.
+func (r *Resolver) activeMembership(ctx context.Context) (*model.MembershipModel, error) {
+	claims := middleware.CtxValue(ctx)
+	if claims == nil {
+		return nil, apperr.Unauthenticated("user not authenticated")
+	}
+	if claims.OrgID == "" {
+		return nil, apperr.Forbidden("no active organization, call switchOrganization first")
+	}
+	membership, err := r.MembershipByUser(ctx, claims.OrgID, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch membership: %w", err)
+	}
+	if membership == nil {
+		return nil, apperr.Forbidden("not a member of the active organization")
+	}
+	return membership, nil
+}
+
+// checkVisible fails with NotFound unless the caller may look up userID:
+// with an active organization, only its members are visible.
+//
+// The scoping is advisory. It narrows what a caller sees while they work in
+// an organization, but it is not an access boundary: the caller chooses the
+// active organization, and switchOrganization(null) drops it, after which
+// every user is visible again. Data that must stay within an organization
+// has to be checked against the caller's memberships instead.
+func (r *Resolver) checkVisible(ctx context.Context, userID string) error {
+	if claims := middleware.CtxValue(ctx); claims == nil || claims.OrgID == "" {
+		return nil
+	}
+	membership, err := r.activeMembership(ctx)
+	if err != nil {
+		return err
+	}
+	member, err := r.MembershipByUser(ctx, membership.OrganizationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch membership: %w", err)
+	}
+	if member == nil {
+		return apperr.NotFound("user not found")
+	}
+	return nil
+}
+
+// membershipView loads the organization and user a membership links.
+func (r *Resolver) membershipView(ctx context.Context, m *model.MembershipModel) (*model.Membership, error) {
+	org, err := r.OrganizationByID(ctx, m.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch organization: %w", err)
+	}
+	if org == nil {
+		return nil, apperr.NotFound("organization %s not found", m.OrganizationID)
+	}
+	user, err := r.UserByID(ctx, m.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user by id: %w", err)
+	}
+	if user == nil {
+		return nil, apperr.NotFound("user %s not found", m.UserID)
+	}
+	return model.ConvertToGraphQLMembership(*m, *org, *user), nil
+}
+
+func (r *Resolver) membershipViews(ctx context.Context, memberships []*model.MembershipModel) ([]*model.Membership, error) {
+	views := make([]*model.Membership, 0, len(memberships))
+	for _, m := range memberships {
+		view, err := r.membershipView(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// canManageMembers reports whether role may change the roles of others.
+func canManageMembers(role string) bool {
+	return role == model.OrgRoleOwner || role == model.OrgRoleAdmin
+}
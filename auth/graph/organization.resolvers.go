@@ -0,0 +1,157 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.80
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+)
+
+// CreateOrganization is the resolver for the createOrganization field.
+func (r *mutationResolver) CreateOrganization(ctx context.Context, input model.NewOrganization) (*model.Membership, error) {
+	claims := middleware.CtxValue(ctx)
+	if claims == nil {
+		return nil, apperr.Unauthenticated("user not authenticated")
+	}
+
+	name := strings.TrimSpace(input.Name)
+	slug := utils.Slugify(name)
+	if input.Slug != nil {
+		slug = utils.Slugify(*input.Slug)
+	}
+	if name == "" || slug == "" {
+		return nil, apperr.Validation("organization name is required")
+	}
+
+	org, err := r.OrganizationCreation(ctx, &model.NewOrganizationModel{Name: name, Slug: slug}, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	membership, err := r.MembershipByUser(ctx, org.ID, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch owner membership: %w", err)
+	}
+	if membership == nil {
+		return nil, fmt.Errorf("owner membership of organization %s is missing", org.ID)
+	}
+	return r.membershipView(ctx, membership)
+}
+
+// ChangeMemberRole is the resolver for the changeMemberRole field.
+func (r *mutationResolver) ChangeMemberRole(ctx context.Context, userID string, role model.OrganizationRole) (*model.Membership, error) {
+	actor, err := r.activeMembership(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageMembers(actor.Role) {
+		return nil, apperr.Forbidden("only organization owners and admins can change member roles")
+	}
+
+	target, err := r.MembershipByUser(ctx, actor.OrganizationID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch membership: %w", err)
+	}
+	if target == nil {
+		return nil, apperr.NotFound("user is not a member of the active organization")
+	}
+	if (role == model.OrganizationRoleOwner || target.Role == model.OrgRoleOwner) && actor.Role != model.OrgRoleOwner {
+		return nil, apperr.Forbidden("only organization owners can grant or revoke the OWNER role")
+	}
+
+	// The repository refuses to demote the last owner.
+	updated, err := r.MembershipUpdate(ctx, actor.OrganizationID, userID, role.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update member role: %w", err)
+	}
+	if updated == nil {
+		return nil, apperr.NotFound("user is not a member of the active organization")
+	}
+	return r.membershipView(ctx, updated)
+}
+
+// SwitchOrganization is the resolver for the switchOrganization field.
+func (r *mutationResolver) SwitchOrganization(ctx context.Context, organizationID *string) (*model.AuthPayload, error) {
+	claims := middleware.CtxValue(ctx)
+	if claims == nil {
+		return nil, apperr.Unauthenticated("user not authenticated")
+	}
+	user, err := r.UserByID(ctx, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user by id: %w", err)
+	}
+	if user == nil {
+		return nil, apperr.NotFound("user not found")
+	}
+
+	// A null organization drops back to a personal, organization-less token.
+	if organizationID == nil || *organizationID == "" {
+		return issueTokens(ctx, user)
+	}
+
+	membership, err := r.MembershipByUser(ctx, *organizationID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch membership: %w", err)
+	}
+	if membership == nil {
+		return nil, apperr.Forbidden("not a member of organization %s", *organizationID)
+	}
+	return issueTokens(ctx, user, jwt.WithOrganization(membership.OrganizationID, membership.Role))
+}
+
+// MyOrganizations is the resolver for the myOrganizations field.
+func (r *queryResolver) MyOrganizations(ctx context.Context) ([]*model.Membership, error) {
+	claims := middleware.CtxValue(ctx)
+	if claims == nil {
+		return nil, apperr.Unauthenticated("user not authenticated")
+	}
+	memberships, err := r.MembershipsByUser(ctx, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch memberships: %w", err)
+	}
+	return r.membershipViews(ctx, memberships)
+}
+
+// ActiveOrganization is the resolver for the activeOrganization field.
+func (r *queryResolver) ActiveOrganization(ctx context.Context) (*model.Organization, error) {
+	claims := middleware.CtxValue(ctx)
+	if claims == nil {
+		return nil, apperr.Unauthenticated("user not authenticated")
+	}
+	if claims.OrgID == "" {
+		return nil, nil
+	}
+	membership, err := r.activeMembership(ctx)
+	if err != nil {
+		return nil, err
+	}
+	org, err := r.OrganizationByID(ctx, membership.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch organization: %w", err)
+	}
+	if org == nil {
+		return nil, nil
+	}
+	return model.ConvertToGraphQLOrganization(*org), nil
+}
+
+// OrganizationMembers is the resolver for the organizationMembers field.
+func (r *queryResolver) OrganizationMembers(ctx context.Context) ([]*model.Membership, error) {
+	membership, err := r.activeMembership(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members, err := r.MembershipsByOrganization(ctx, membership.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch members: %w", err)
+	}
+	return r.membershipViews(ctx, members)
+}
@@ -0,0 +1,177 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+type membershipResp struct {
+	ID           string
+	Role         string
+	Organization struct{ ID, Name, Slug string }
+	User         struct{ ID string }
+}
+
+func createOrganization(t *testing.T, h *graphtest.Harness, s graphtest.Session, name string) membershipResp {
+	t.Helper()
+	var resp struct{ CreateOrganization membershipResp }
+	h.MustPost(`mutation($name: String!) {
+		createOrganization(input: {name: $name}) { id role organization { id name slug } user { id } }
+	}`, &resp, client.Var("name", name), s.Auth())
+	return resp.CreateOrganization
+}
+
+func addMember(t *testing.T, h *graphtest.Harness, orgID string, user *model.UserModel, role string) {
+	t.Helper()
+	if _, err := h.Repo.MembershipCreation(t.Context(), orgID, user.ID, role); err != nil {
+		t.Fatalf("add member: %v", err)
+	}
+}
+
+func TestCreateOrganization(t *testing.T) {
+	h := graphtest.New(t)
+	owner := h.LoginAsUser()
+
+	got := createOrganization(t, h, owner, "Acme Corp.")
+	if got.Role != model.OrgRoleOwner || got.User.ID != owner.User.ID {
+		t.Fatalf("creator membership = %+v", got)
+	}
+	if got.Organization.Name != "Acme Corp." || got.Organization.Slug != "acme-corp" {
+		t.Fatalf("organization = %+v", got.Organization)
+	}
+
+	var resp map[string]any
+	err := h.Post(`mutation { createOrganization(input: {name: "Acme", slug: "Acme-Corp"}) { id } }`, &resp, owner.Auth())
+	expectError(t, err, "already exists")
+
+	var mine struct{ MyOrganizations []membershipResp }
+	h.MustPost(`query { myOrganizations { role organization { id } } }`, &mine, owner.Auth())
+	if len(mine.MyOrganizations) != 1 || mine.MyOrganizations[0].Organization.ID != got.Organization.ID {
+		t.Fatalf("myOrganizations = %+v", mine.MyOrganizations)
+	}
+}
+
+func TestSwitchOrganization(t *testing.T) {
+	h := graphtest.New(t)
+	owner := h.LoginAsUser()
+	org := createOrganization(t, h, owner, "Acme").Organization
+
+	var active struct{ ActiveOrganization *struct{ ID string } }
+	h.MustPost(`query { activeOrganization { id } }`, &active, owner.Auth())
+	if active.ActiveOrganization != nil {
+		t.Fatalf("active organization before switching = %+v", active.ActiveOrganization)
+	}
+
+	switched := h.SwitchOrganization(owner, org.ID)
+	claims, err := jwt.ValidateJwt(t.Context(), switched.Token)
+	if err != nil {
+		t.Fatalf("validate switched token: %v", err)
+	}
+	if claims.OrgID != org.ID || claims.OrgRole != model.OrgRoleOwner || claims.ID != owner.User.ID {
+		t.Fatalf("switched claims = %+v", claims)
+	}
+	h.MustPost(`query { activeOrganization { id } }`, &active, switched.Auth())
+	if active.ActiveOrganization == nil || active.ActiveOrganization.ID != org.ID {
+		t.Fatalf("activeOrganization = %+v", active.ActiveOrganization)
+	}
+
+	cleared := h.SwitchOrganization(switched, "")
+	if claims, _ := jwt.ValidateJwt(t.Context(), cleared.Token); claims == nil || claims.OrgID != "" {
+		t.Fatalf("cleared claims = %+v", claims)
+	}
+
+	outsider := h.LoginAsUser()
+	var resp map[string]any
+	err = h.Post(`mutation($id: ID) { switchOrganization(organizationId: $id) { token } }`,
+		&resp, client.Var("id", org.ID), outsider.Auth())
+	expectError(t, err, "not a member")
+}
+
+func TestOrganizationMembersScopedToActiveOrganization(t *testing.T) {
+	h := graphtest.New(t)
+	owner := h.LoginAsUser()
+	acme := createOrganization(t, h, owner, "Acme").Organization
+	globex := createOrganization(t, h, owner, "Globex").Organization
+	member := h.CreateUser("member@example.com", model.RoleUser)
+	addMember(t, h, acme.ID, member, model.OrgRoleMember)
+
+	var resp map[string]any
+	err := h.Post(`query { organizationMembers { id } }`, &resp, owner.Auth())
+	expectError(t, err, "no active organization")
+
+	var members struct{ OrganizationMembers []membershipResp }
+	h.MustPost(`query { organizationMembers { role user { id } } }`, &members, h.SwitchOrganization(owner, acme.ID).Auth())
+	if len(members.OrganizationMembers) != 2 {
+		t.Fatalf("acme members = %+v", members.OrganizationMembers)
+	}
+
+	globexSession := h.SwitchOrganization(owner, globex.ID)
+	h.MustPost(`query { organizationMembers { role user { id } } }`, &members, globexSession.Auth())
+	if len(members.OrganizationMembers) != 1 || members.OrganizationMembers[0].User.ID != owner.User.ID {
+		t.Fatalf("globex members = %+v", members.OrganizationMembers)
+	}
+
+	var users struct{ UsersByRole []userResp }
+	h.MustPost(`query { usersByRole(role: "USER") { id } }`, &users, globexSession.Auth())
+	if len(users.UsersByRole) != 1 || users.UsersByRole[0].ID != owner.User.ID {
+		t.Fatalf("usersByRole in globex = %+v", users.UsersByRole)
+	}
+
+	lookups := map[string]string{
+		"user":      `query { user(id: "` + member.ID + `") { id } }`,
+		"userEmail": `query { userEmail(email: "` + member.Email + `") { id } }`,
+	}
+	for name, query := range lookups {
+		err := h.Post(query, &resp, globexSession.Auth())
+		expectError(t, err, "user not found")
+
+		var found map[string]struct{ ID string }
+		h.MustPost(query, &found, h.SwitchOrganization(owner, acme.ID).Auth())
+		if found[name].ID != member.ID {
+			t.Errorf("%s in acme = %+v", name, found)
+		}
+	}
+}
+
+func TestChangeMemberRole(t *testing.T) {
+	h := graphtest.New(t)
+	owner := h.LoginAsUser()
+	org := createOrganization(t, h, owner, "Acme").Organization
+	ownerSession := h.SwitchOrganization(owner, org.ID)
+
+	admin := h.LoginAsUser()
+	addMember(t, h, org.ID, admin.User, model.OrgRoleMember)
+	member := h.LoginAsUser()
+	addMember(t, h, org.ID, member.User, model.OrgRoleMember)
+
+	change := `mutation($id: ID!, $role: OrganizationRole!) { changeMemberRole(userId: $id, role: $role) { role user { id } } }`
+	var resp struct{ ChangeMemberRole membershipResp }
+	h.MustPost(change, &resp, client.Var("id", admin.User.ID), client.Var("role", "ADMIN"), ownerSession.Auth())
+	if resp.ChangeMemberRole.Role != model.OrgRoleAdmin {
+		t.Fatalf("changeMemberRole = %+v", resp.ChangeMemberRole)
+	}
+
+	// The promotion applies to a token issued before it.
+	adminSession := h.SwitchOrganization(admin, org.ID)
+	memberSession := h.SwitchOrganization(member, org.ID)
+
+	var errResp map[string]any
+	err := h.Post(change, &errResp, client.Var("id", admin.User.ID), client.Var("role", "MEMBER"), memberSession.Auth())
+	expectError(t, err, "only organization owners and admins")
+
+	err = h.Post(change, &errResp, client.Var("id", member.User.ID), client.Var("role", "OWNER"), adminSession.Auth())
+	expectError(t, err, "only organization owners can grant")
+
+	h.MustPost(change, &resp, client.Var("id", member.User.ID), client.Var("role", "ADMIN"), adminSession.Auth())
+
+	err = h.Post(change, &errResp, client.Var("id", owner.User.ID), client.Var("role", "MEMBER"), ownerSession.Auth())
+	expectError(t, err, "at least one owner")
+
+	outsider := h.LoginAsUser()
+	err = h.Post(change, &errResp, client.Var("id", outsider.User.ID), client.Var("role", "MEMBER"), ownerSession.Auth())
+	expectError(t, err, "not a member")
+}
@@ -0,0 +1,87 @@
+// Package postal validates postal codes against per-country formats kept in
+// the embedded postal_codes.tsv table.
+package postal
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+)
+
+//go:embed postal_codes.tsv
+var table string
+
+// Country is one row of the postal code table.
+type Country struct {
+	Code    string
+	Name    string
+	Example string
+	// pattern is nil for countries without postal codes.
+	pattern *regexp.Regexp
+}
+
+// HasPostalCodes reports whether addresses in the country carry a postal code.
+func (c Country) HasPostalCodes() bool {
+	return c.pattern != nil
+}
+
+var countries = mustParse(table)
+
+func mustParse(data string) map[string]Country {
+	parsed := map[string]Country{}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		row := scanner.Text()
+		if row == "" || strings.HasPrefix(row, "#") {
+			continue
+		}
+		cols := strings.Split(row, "\t")
+		if len(cols) != 4 {
+			panic(fmt.Sprintf("postal_codes.tsv:%d: want 4 columns, got %d", line, len(cols)))
+		}
+		c := Country{Code: cols[0], Name: cols[1], Example: cols[3]}
+		if cols[2] != "" {
+			c.pattern = regexp.MustCompile(`^(?:` + cols[2] + `)$`)
+			if !c.pattern.MatchString(c.Example) {
+				panic(fmt.Sprintf("postal_codes.tsv:%d: example %q does not match the pattern", line, c.Example))
+			}
+		}
+		parsed[c.Code] = c
+	}
+	return parsed
+}
+
+// Lookup returns the country with the ISO 3166-1 alpha-2 code, in any case.
+func Lookup(code string) (Country, bool) {
+	c, ok := countries[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+// Normalize upper-cases code and collapses runs of whitespace to one space.
+func Normalize(code string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(code)), " ")
+}
+
+// Validate checks code against the format of country and returns it
+// normalized. Codes given for countries without postal codes are dropped.
+func Validate(country, code string) (string, error) {
+	c, ok := Lookup(country)
+	if !ok {
+		return "", apperr.Validation("unsupported country %q", country)
+	}
+	if !c.HasPostalCodes() {
+		return "", nil
+	}
+	code = Normalize(code)
+	if code == "" {
+		return "", apperr.Validation("postal code is required for %s", c.Name)
+	}
+	if !c.pattern.MatchString(code) {
+		return "", apperr.Validation("invalid postal code %q for %s, expected a code like %q", code, c.Name, c.Example)
+	}
+	return code, nil
+}
@@ -0,0 +1,232 @@
+// Package cached puts a read-through cache in front of the user lookups of a
+// repository. The auth middleware looks up the token owner on every request,
+// so UserByID is served from the cache until its entry expires or a write
+// to the user deletes it.
+//
+// Cached users never include the password hash or tokens. UserByID returns
+// them without those fields, and UserByEmail, which login reads the hash
+// through, only serves misses from the cache.
+package cached
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/cache"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/metrics"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+)
+
+var logger = logging.For("cache")
+
+// notFound is cached for lookups that found no user, so that repeated
+// lookups of unknown IDs and addresses do not reach the database either.
+var notFound = []byte("null")
+
+// Options sets how long entries are kept.
+type Options struct {
+	// TTL is how long a found user is cached.
+	TTL time.Duration
+	// NegativeTTL is how long a lookup that found nothing is cached. Keep it
+	// short: a user created by another server is not found until it expires.
+	NegativeTTL time.Duration
+}
+
+// Store is a repos.Repository that caches user lookups of the repository
+// it wraps. Failures of the cache are logged and the lookups fall through
+// to the repository.
+type Store struct {
+	repos.Repository
+	cache cache.Cache
+	opts  Options
+}
+
+var _ repos.Repository = (*Store)(nil)
+
+// NewStore returns repo with user lookups cached in c.
+func NewStore(repo repos.Repository, c cache.Cache, opts Options) *Store {
+	return &Store{Repository: repo, cache: c, opts: opts}
+}
+
+func idKey(id string) string {
+	return "user:id:" + id
+}
+
+func emailKey(email string) string {
+	return "user:email:" + utils.EmailKey(email)
+}
+
+// UserByID implements repos.Repository. The user has no Password, Token or
+// RefreshToken when it comes from the cache.
+func (s *Store) UserByID(ctx context.Context, id string) (*model.UserModel, error) {
+	key := idKey(id)
+	if data, ok := s.get(ctx, key); ok {
+		if user, err := decode(data); err == nil {
+			metrics.UserCacheLookupsTotal.WithLabelValues(metrics.CacheHit).Inc()
+			return user, nil
+		}
+		logger.WarnContext(ctx, "dropping undecodable user cache entry", slog.String("key", key))
+	}
+	metrics.UserCacheLookupsTotal.WithLabelValues(metrics.CacheMiss).Inc()
+
+	user, err := s.Repository.UserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.set(ctx, key, user)
+	return user, nil
+}
+
+// UserByEmail implements repos.Repository. Found users are always read from
+// the repository, so that the password hash is current and never cached;
+// only addresses without an account are answered from the cache.
+func (s *Store) UserByEmail(ctx context.Context, email string) (*model.UserModel, error) {
+	key := emailKey(email)
+	if data, ok := s.get(ctx, key); ok && bytes.Equal(data, notFound) {
+		metrics.UserCacheLookupsTotal.WithLabelValues(metrics.CacheHit).Inc()
+		return nil, nil
+	}
+	metrics.UserCacheLookupsTotal.WithLabelValues(metrics.CacheMiss).Inc()
+
+	user, err := s.Repository.UserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		s.set(ctx, key, nil)
+	} else {
+		s.set(ctx, idKey(user.ID), user)
+	}
+	return user, nil
+}
+
+// UserCreation implements repos.Repository. It drops cached misses of the
+// new user's email address.
+func (s *Store) UserCreation(ctx context.Context, input *model.NewUserModel) (*model.UserModel, error) {
+	user, err := s.Repository.UserCreation(ctx, input)
+	if input != nil {
+		s.invalidate(ctx, []string{emailKey(input.Email)}, user)
+	}
+	return user, err
+}
+
+// InvitationSignUp implements repos.Repository. It drops cached misses of
+// the new user's email address.
+func (s *Store) InvitationSignUp(ctx context.Context, id string, input *model.NewUserModel) (*model.UserModel, *model.MembershipModel, error) {
+	user, membership, err := s.Repository.InvitationSignUp(ctx, id, input)
+	if input != nil {
+		s.invalidate(ctx, []string{emailKey(input.Email)}, user)
+	}
+	return user, membership, err
+}
+
+// UserDelete implements repos.Repository.
+func (s *Store) UserDelete(ctx context.Context, email string) error {
+	before := s.current(ctx, s.Repository.UserByEmail, email)
+	err := s.Repository.UserDelete(ctx, email)
+	s.invalidate(ctx, []string{emailKey(email)}, before)
+	return err
+}
+
+// UserUpdate implements repos.Repository. Role changes go through it.
+func (s *Store) UserUpdate(ctx context.Context, email string, input *model.NewUserModel) (*model.UserModel, error) {
+	before := s.current(ctx, s.Repository.UserByEmail, email)
+	user, err := s.Repository.UserUpdate(ctx, email, input)
+	s.invalidate(ctx, []string{emailKey(email)}, before, user)
+	return user, err
+}
+
+// UserAvatarUpdate implements repos.Repository.
+func (s *Store) UserAvatarUpdate(ctx context.Context, id, avatarKey string) (*model.UserModel, error) {
+	before := s.current(ctx, s.Repository.UserByID, id)
+	user, err := s.Repository.UserAvatarUpdate(ctx, id, avatarKey)
+	s.invalidate(ctx, []string{idKey(id)}, before, user)
+	return user, err
+}
+
+// UserStatusUpdate implements repos.Repository.
+func (s *Store) UserStatusUpdate(ctx context.Context, id, status, reason string, until *time.Time) (*model.UserModel, error) {
+	before := s.current(ctx, s.Repository.UserByID, id)
+	user, err := s.Repository.UserStatusUpdate(ctx, id, status, reason, until)
+	s.invalidate(ctx, []string{idKey(id)}, before, user)
+	return user, err
+}
+
+// get returns the cache entry under key. Failures of the cache count as
+// misses.
+func (s *Store) get(ctx context.Context, key string) ([]byte, bool) {
+	data, ok, err := s.cache.Get(ctx, key)
+	if err != nil {
+		metrics.UserCacheLookupsTotal.WithLabelValues(metrics.CacheError).Inc()
+		logger.WarnContext(ctx, "failed to read user cache", slog.String("key", key), slog.Any("error", err))
+		return nil, false
+	}
+	return data, ok
+}
+
+// set caches user under key without its secrets, or a miss if user is nil.
+func (s *Store) set(ctx context.Context, key string, user *model.UserModel) {
+	data, ttl := notFound, s.opts.NegativeTTL
+	if user != nil {
+		var err error
+		if data, err = json.Marshal(withoutSecrets(user)); err != nil {
+			return
+		}
+		ttl = s.opts.TTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	if err := s.cache.Set(ctx, key, data, ttl); err != nil {
+		logger.WarnContext(ctx, "failed to write user cache", slog.String("key", key), slog.Any("error", err))
+	}
+}
+
+// current reads the stored user before a write, bypassing the cache, to
+// learn which entries the write makes stale.
+func (s *Store) current(ctx context.Context, lookup func(context.Context, string) (*model.UserModel, error), arg string) *model.UserModel {
+	user, err := lookup(ctx, arg)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
+// invalidate deletes keys and the entries of users. It runs after writes
+// whether or not they failed, since a failed write may have been applied.
+func (s *Store) invalidate(ctx context.Context, keys []string, users ...*model.UserModel) {
+	for _, user := range users {
+		if user != nil {
+			keys = append(keys, idKey(user.ID), emailKey(user.Email))
+		}
+	}
+	if err := s.cache.Delete(ctx, keys...); err != nil {
+		logger.ErrorContext(ctx, "failed to invalidate user cache", slog.Any("error", err))
+	}
+}
+
+// withoutSecrets is the part of user that may be cached, which leaves out
+// the password hash and tokens.
+func withoutSecrets(user *model.UserModel) *model.UserModel {
+	public := *user
+	public.Password, public.Token, public.RefreshToken = "", "", ""
+	return &public
+}
+
+func decode(data []byte) (*model.UserModel, error) {
+	var user *model.UserModel
+	if err := json.Unmarshal(data, &user); err != nil {
+		return nil, err
+	}
+	if user != nil {
+		// EmailNormalized is not serialized.
+		user.EmailNormalized = utils.EmailKey(user.Email)
+	}
+	return user, nil
+}
@@ -0,0 +1,238 @@
+package cached
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/cache"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/memory"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/repostest"
+)
+
+var opts = Options{TTL: time.Minute, NegativeTTL: 10 * time.Second}
+
+func TestRepositoryContract(t *testing.T) {
+	repostest.Run(t, func(t *testing.T) repos.Repository {
+		return NewStore(memory.NewStore(), cache.NewLRU(100), opts)
+	})
+}
+
+// countingRepo counts the user lookups that reach the repository.
+type countingRepo struct {
+	repos.Repository
+	lookups int
+}
+
+func (r *countingRepo) UserByID(ctx context.Context, id string) (*model.UserModel, error) {
+	r.lookups++
+	return r.Repository.UserByID(ctx, id)
+}
+
+func (r *countingRepo) UserByEmail(ctx context.Context, email string) (*model.UserModel, error) {
+	r.lookups++
+	return r.Repository.UserByEmail(ctx, email)
+}
+
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time          { return c.now }
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestStore(t *testing.T) (*Store, *countingRepo, *clock) {
+	t.Helper()
+	clk := &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	repo := &countingRepo{Repository: memory.NewStore()}
+	return NewStore(repo, cache.NewLRU(100, cache.WithClock(clk.Now)), opts), repo, clk
+}
+
+func createUser(t *testing.T, s *Store, email string) *model.UserModel {
+	t.Helper()
+	user, err := s.UserCreation(context.Background(), repostest.NewUser(email, "BUYER"))
+	if err != nil {
+		t.Fatalf("UserCreation = %v", err)
+	}
+	return user
+}
+
+func TestLookupsAreCachedForTTL(t *testing.T) {
+	ctx := context.Background()
+	s, repo, clk := newTestStore(t)
+	user := createUser(t, s, "ada@example.com")
+	repo.lookups = 0
+
+	for i := 0; i < 3; i++ {
+		got, err := s.UserByID(ctx, user.ID)
+		if err != nil || got == nil || got.Email != user.Email {
+			t.Fatalf("UserByID = %v, %v", got, err)
+		}
+		if got.EmailNormalized != "ada@example.com" {
+			t.Errorf("cached EmailNormalized = %q", got.EmailNormalized)
+		}
+	}
+	if repo.lookups != 1 {
+		t.Errorf("repository lookups = %d; want 1", repo.lookups)
+	}
+
+	clk.Advance(opts.TTL + time.Second)
+	if _, err := s.UserByID(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if repo.lookups != 2 {
+		t.Errorf("repository lookups after TTL = %d; want 2", repo.lookups)
+	}
+}
+
+func TestSecretsAreNotCached(t *testing.T) {
+	ctx := context.Background()
+	s, repo, _ := newTestStore(t)
+	user := createUser(t, s, "ada@example.com")
+	if _, err := s.UserByID(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	data, ok, _ := s.cache.Get(ctx, idKey(user.ID))
+	if !ok {
+		t.Fatal("user was not cached")
+	}
+	if strings.Contains(string(data), "hashed-password") {
+		t.Errorf("cache entry holds the password hash: %s", data)
+	}
+	if got, _ := s.UserByID(ctx, user.ID); got.Password != "" || got.Token != "" || got.RefreshToken != "" {
+		t.Errorf("cached user has secrets: %+v", got)
+	}
+
+	// Login reads the hash by email, which always reaches the repository.
+	repo.lookups = 0
+	for i := 0; i < 2; i++ {
+		got, err := s.UserByEmail(ctx, "ADA@example.com")
+		if err != nil || got == nil || got.Password != "hashed-password" {
+			t.Fatalf("UserByEmail = %+v, %v; want the stored hash", got, err)
+		}
+	}
+	if repo.lookups != 2 {
+		t.Errorf("repository lookups by email = %d; want 2", repo.lookups)
+	}
+}
+
+func TestMissesAreCachedForNegativeTTL(t *testing.T) {
+	ctx := context.Background()
+	s, repo, clk := newTestStore(t)
+
+	for i := 0; i < 2; i++ {
+		if got, err := s.UserByID(ctx, "missing"); got != nil || err != nil {
+			t.Fatalf("UserByID(missing) = %v, %v; want nil, nil", got, err)
+		}
+	}
+	if repo.lookups != 1 {
+		t.Errorf("repository lookups = %d; want 1", repo.lookups)
+	}
+
+	clk.Advance(opts.NegativeTTL + time.Second)
+	if _, err := s.UserByID(ctx, "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if repo.lookups != 2 {
+		t.Errorf("repository lookups after negative TTL = %d; want 2", repo.lookups)
+	}
+}
+
+func TestCreationDropsCachedMiss(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestStore(t)
+
+	if got, _ := s.UserByEmail(ctx, "grace@example.com"); got != nil {
+		t.Fatalf("UserByEmail before creation = %v", got)
+	}
+	user := createUser(t, s, "Grace@example.com")
+	if got, _ := s.UserByEmail(ctx, "grace@example.com"); got == nil || got.ID != user.ID {
+		t.Errorf("UserByEmail after creation = %v; want %s", got, user.ID)
+	}
+}
+
+func TestWritesInvalidate(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestStore(t)
+	user := createUser(t, s, "ada@example.com")
+
+	// Fill the cache under both keys before each write.
+	warm := func() {
+		t.Helper()
+		if _, err := s.UserByID(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UserByEmail(ctx, user.Email); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	warm()
+	input := repostest.NewUser("ada@example.com", "ADMIN")
+	if _, err := s.UserUpdate(ctx, user.Email, input); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.UserByID(ctx, user.ID); got.Role != "ADMIN" {
+		t.Errorf("role after UserUpdate = %q; want ADMIN", got.Role)
+	}
+	if got, _ := s.UserByEmail(ctx, user.Email); got.Role != "ADMIN" {
+		t.Errorf("role by email after UserUpdate = %q; want ADMIN", got.Role)
+	}
+
+	warm()
+	if _, err := s.UserStatusUpdate(ctx, user.ID, model.StatusSuspended, "chargebacks", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.UserByEmail(ctx, user.Email); got.Status != model.StatusSuspended {
+		t.Errorf("status after UserStatusUpdate = %q; want %q", got.Status, model.StatusSuspended)
+	}
+
+	warm()
+	if _, err := s.UserAvatarUpdate(ctx, user.ID, "avatars/ada.png"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.UserByEmail(ctx, user.Email); got.AvatarKey != "avatars/ada.png" {
+		t.Errorf("avatar after UserAvatarUpdate = %q", got.AvatarKey)
+	}
+
+	warm()
+	if err := s.UserDelete(ctx, user.Email); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.UserByID(ctx, user.ID); got != nil {
+		t.Errorf("UserByID after UserDelete = %v; want nil", got)
+	}
+	if got, _ := s.UserByEmail(ctx, user.Email); got != nil {
+		t.Errorf("UserByEmail after UserDelete = %v; want nil", got)
+	}
+}
+
+// brokenCache fails every operation.
+type brokenCache struct{}
+
+var errBroken = errors.New("cache unavailable")
+
+func (brokenCache) Get(context.Context, string) ([]byte, bool, error) { return nil, false, errBroken }
+func (brokenCache) Set(context.Context, string, []byte, time.Duration) error {
+	return errBroken
+}
+func (brokenCache) Delete(context.Context, ...string) error { return errBroken }
+
+func TestCacheFailuresFallThrough(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore(memory.NewStore(), brokenCache{}, opts)
+	user := createUser(t, s, "ada@example.com")
+
+	if got, err := s.UserByID(ctx, user.ID); err != nil || got == nil {
+		t.Errorf("UserByID = %v, %v", got, err)
+	}
+	if got, err := s.UserByEmail(ctx, user.Email); err != nil || got == nil {
+		t.Errorf("UserByEmail = %v, %v", got, err)
+	}
+	if err := s.UserDelete(ctx, user.Email); err != nil {
+		t.Errorf("UserDelete = %v", err)
+	}
+}
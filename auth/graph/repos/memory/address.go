@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// AddressCreation implements repos.Repository.
+func (s *Store) AddressCreation(ctx context.Context, userID string, input *model.NewAddressModel) (*model.AddressModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[userID]; !ok {
+		return nil, fmt.Errorf("failed to create address: user %s does not exist", userID)
+	}
+
+	now := time.Now()
+	address := &model.AddressModel{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		CreatedAt: now,
+	}
+	applyAddress(address, input, now)
+	s.clearDefaults(address)
+	s.addresses = append(s.addresses, address)
+	c := *address
+	return &c, nil
+}
+
+// AddressByID implements repos.Repository.
+func (s *Store) AddressByID(ctx context.Context, id string) (*model.AddressModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if address := s.address(id); address != nil {
+		c := *address
+		return &c, nil
+	}
+	return nil, nil // Address not found
+}
+
+// AddressesByUser implements repos.Repository.
+func (s *Store) AddressesByUser(ctx context.Context, userID string) ([]*model.AddressModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	addresses := []*model.AddressModel{}
+	for _, address := range s.addresses {
+		if address.UserID == userID {
+			c := *address
+			addresses = append(addresses, &c)
+		}
+	}
+	return addresses, nil
+}
+
+// AddressUpdate implements repos.Repository.
+func (s *Store) AddressUpdate(ctx context.Context, id string, input *model.NewAddressModel) (*model.AddressModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	address := s.address(id)
+	if address == nil {
+		return nil, nil // Address not found
+	}
+	applyAddress(address, input, time.Now())
+	s.clearDefaults(address)
+	c := *address
+	return &c, nil
+}
+
+// AddressDelete implements repos.Repository.
+func (s *Store) AddressDelete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteAddresses(func(a *model.AddressModel) bool { return a.ID == id })
+	return nil
+}
+
+func applyAddress(address *model.AddressModel, input *model.NewAddressModel, now time.Time) {
+	address.FullName = input.FullName
+	address.Street = input.Street
+	address.City = input.City
+	address.State = input.State
+	address.PostalCode = input.PostalCode
+	address.Country = input.Country
+	address.Phone = input.Phone
+	address.IsDefaultShipping = input.IsDefaultShipping
+	address.IsDefaultBilling = input.IsDefaultBilling
+	address.UpdatedAt = now
+}
+
+// address must be called with s.mu held.
+func (s *Store) address(id string) *model.AddressModel {
+	for _, address := range s.addresses {
+		if address.ID == id {
+			return address
+		}
+	}
+	return nil
+}
+
+// clearDefaults unsets the default flags that address takes over on the
+// user's other addresses. It must be called with s.mu held.
+func (s *Store) clearDefaults(address *model.AddressModel) {
+	for _, other := range s.addresses {
+		if other.UserID != address.UserID || other.ID == address.ID {
+			continue
+		}
+		if address.IsDefaultShipping {
+			other.IsDefaultShipping = false
+		}
+		if address.IsDefaultBilling {
+			other.IsDefaultBilling = false
+		}
+	}
+}
+
+// deleteAddresses must be called with s.mu held.
+func (s *Store) deleteAddresses(match func(*model.AddressModel) bool) {
+	kept := s.addresses[:0]
+	for _, address := range s.addresses {
+		if !match(address) {
+			kept = append(kept, address)
+		}
+	}
+	s.addresses = kept
+}
@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// AuditLogCreation implements repos.Repository.
+func (s *Store) AuditLogCreation(ctx context.Context, input *model.NewAuditLogModel) (*model.AuditLogModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := &model.AuditLogModel{
+		ID:        uuid.NewString(),
+		ActorID:   input.ActorID,
+		SubjectID: input.SubjectID,
+		Action:    input.Action,
+		Operation: input.Operation,
+		Detail:    input.Detail,
+		RequestID: input.RequestID,
+		CreatedAt: time.Now(),
+	}
+	s.auditLogs = append(s.auditLogs, entry)
+	c := *entry
+	return &c, nil
+}
+
+// AuditLogs implements repos.Repository.
+func (s *Store) AuditLogs(ctx context.Context, filter model.AuditLogFilter) ([]*model.AuditLogModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries := []*model.AuditLogModel{}
+	for i := len(s.auditLogs) - 1; i >= 0; i-- {
+		entry := s.auditLogs[i]
+		if (filter.ActorID != "" && entry.ActorID != filter.ActorID) ||
+			(filter.SubjectID != "" && entry.SubjectID != filter.SubjectID) ||
+			(filter.Action != "" && entry.Action != filter.Action) {
+			continue
+		}
+		c := *entry
+		entries = append(entries, &c)
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
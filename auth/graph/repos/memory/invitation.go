@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// InvitationCreation implements repos.Repository.
+func (s *Store) InvitationCreation(ctx context.Context, input *model.NewInvitationModel) (*model.InvitationModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.organizations[input.OrganizationID]; !ok {
+		return nil, fmt.Errorf("failed to create invitation: organization %s does not exist", input.OrganizationID)
+	}
+
+	now := time.Now()
+	invitation := &model.InvitationModel{
+		ID:             uuid.NewString(),
+		OrganizationID: input.OrganizationID,
+		Email:          input.Email,
+		Role:           input.Role,
+		InvitedBy:      input.InvitedBy,
+		ExpiresAt:      input.ExpiresAt,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	s.invitations = append(s.invitations, invitation)
+	return cloneInvitation(invitation), nil
+}
+
+// InvitationByID implements repos.Repository.
+func (s *Store) InvitationByID(ctx context.Context, id string) (*model.InvitationModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if invitation := s.invitation(id); invitation != nil {
+		return cloneInvitation(invitation), nil
+	}
+	return nil, nil // Invitation not found
+}
+
+// InvitationsByOrganization implements repos.Repository.
+func (s *Store) InvitationsByOrganization(ctx context.Context, organizationID string) ([]*model.InvitationModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	invitations := []*model.InvitationModel{}
+	for _, invitation := range s.invitations {
+		if invitation.OrganizationID == organizationID {
+			invitations = append(invitations, cloneInvitation(invitation))
+		}
+	}
+	return invitations, nil
+}
+
+// InvitationRevoke implements repos.Repository.
+func (s *Store) InvitationRevoke(ctx context.Context, id string) (*model.InvitationModel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invitation := s.invitation(id)
+	if invitation == nil {
+		return nil, nil // Invitation not found
+	}
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return nil, apperr.Conflict("invitation is no longer pending")
+	}
+	now := time.Now()
+	invitation.RevokedAt = &now
+	invitation.UpdatedAt = now
+	return cloneInvitation(invitation), nil
+}
+
+// InvitationAccept implements repos.Repository.
+func (s *Store) InvitationAccept(ctx context.Context, id, userID string) (*model.MembershipModel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invitation := s.invitation(id)
+	if invitation == nil {
+		return nil, nil // Invitation not found
+	}
+	if err := s.acceptable(invitation, userID); err != nil {
+		return nil, err
+	}
+	if _, ok := s.users[userID]; !ok {
+		return nil, fmt.Errorf("failed to accept invitation: user %s does not exist", userID)
+	}
+	return s.accept(invitation, userID), nil
+}
+
+// InvitationSignUp implements repos.Repository.
+func (s *Store) InvitationSignUp(ctx context.Context, id string, input *model.NewUserModel) (*model.UserModel, *model.MembershipModel, error) {
+	if input == nil {
+		return nil, nil, fmt.Errorf("input parameter is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	invitation := s.invitation(id)
+	if invitation == nil {
+		return nil, nil, nil // Invitation not found
+	}
+	if s.byEmail(input.Email) != nil {
+		return nil, nil, apperr.Conflict("user with email %s already exists", input.Email)
+	}
+	user := newUser(input)
+	if err := s.acceptable(invitation, user.ID); err != nil {
+		return nil, nil, err
+	}
+	s.users[user.ID] = user
+	return clone(user), s.accept(invitation, user.ID), nil
+}
+
+// acceptable must be called with s.mu held.
+func (s *Store) acceptable(invitation *model.InvitationModel, userID string) error {
+	if !invitation.Pending(time.Now()) {
+		return fmt.Errorf("failed to accept invitation: invitation is no longer pending")
+	}
+	if s.membership(invitation.OrganizationID, userID) != nil {
+		return fmt.Errorf("failed to accept invitation: user %s is already a member of organization %s", userID, invitation.OrganizationID)
+	}
+	return nil
+}
+
+// accept must be called with s.mu held, after acceptable.
+func (s *Store) accept(invitation *model.InvitationModel, userID string) *model.MembershipModel {
+	now := time.Now()
+	invitation.AcceptedAt = &now
+	invitation.UpdatedAt = now
+	membership := &model.MembershipModel{
+		ID:             uuid.NewString(),
+		OrganizationID: invitation.OrganizationID,
+		UserID:         userID,
+		Role:           invitation.Role,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	s.memberships = append(s.memberships, membership)
+	c := *membership
+	return &c
+}
+
+// invitation must be called with s.mu held.
+func (s *Store) invitation(id string) *model.InvitationModel {
+	for _, invitation := range s.invitations {
+		if invitation.ID == id {
+			return invitation
+		}
+	}
+	return nil
+}
+
+// cloneInvitation copies the timestamps behind the pointers too.
+func cloneInvitation(invitation *model.InvitationModel) *model.InvitationModel {
+	c := *invitation
+	if c.AcceptedAt != nil {
+		t := *c.AcceptedAt
+		c.AcceptedAt = &t
+	}
+	if c.RevokedAt != nil {
+		t := *c.RevokedAt
+		c.RevokedAt = &t
+	}
+	return &c
+}
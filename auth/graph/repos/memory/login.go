@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// LoginEventCreation implements repos.Repository.
+func (s *Store) LoginEventCreation(ctx context.Context, input *model.NewLoginEventModel) (*model.LoginEventModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[input.UserID]; !ok {
+		return nil, fmt.Errorf("failed to create login event: user %s does not exist", input.UserID)
+	}
+	event := &model.LoginEventModel{
+		ID:          uuid.NewString(),
+		UserID:      input.UserID,
+		Method:      input.Method,
+		Outcome:     input.Outcome,
+		IP:          input.IP,
+		UserAgent:   input.UserAgent,
+		Fingerprint: input.Fingerprint,
+		CreatedAt:   time.Now(),
+	}
+	s.logins = append(s.logins, event)
+	return cloneLoginEvent(event), nil
+}
+
+// LoginEventByID implements repos.Repository.
+func (s *Store) LoginEventByID(ctx context.Context, id string) (*model.LoginEventModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, event := range s.logins {
+		if event.ID == id {
+			return cloneLoginEvent(event), nil
+		}
+	}
+	return nil, nil // Event not found
+}
+
+// LoginEvents implements repos.Repository.
+func (s *Store) LoginEvents(ctx context.Context, filter model.LoginEventFilter) ([]*model.LoginEventModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	events := []*model.LoginEventModel{}
+	for i := len(s.logins) - 1; i >= 0; i-- {
+		event := s.logins[i]
+		if (filter.UserID != "" && event.UserID != filter.UserID) ||
+			(filter.Outcome != "" && event.Outcome != filter.Outcome) ||
+			(filter.Fingerprint != "" && event.Fingerprint != filter.Fingerprint) {
+			continue
+		}
+		events = append(events, cloneLoginEvent(event))
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+// LoginEventReport implements repos.Repository.
+func (s *Store) LoginEventReport(ctx context.Context, id, userID string) (*model.LoginEventModel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range s.logins {
+		if event.ID != id || event.UserID != userID {
+			continue
+		}
+		if event.ReportedAt == nil {
+			now := time.Now()
+			event.ReportedAt = &now
+		}
+		return cloneLoginEvent(event), nil
+	}
+	return nil, nil // Event not found
+}
+
+// cloneLoginEvent copies the timestamp behind ReportedAt too.
+func cloneLoginEvent(event *model.LoginEventModel) *model.LoginEventModel {
+	c := *event
+	if c.ReportedAt != nil {
+		t := *c.ReportedAt
+		c.ReportedAt = &t
+	}
+	return &c
+}
+
+// deleteLoginEvents must be called with s.mu held.
+func (s *Store) deleteLoginEvents(userID string) {
+	kept := s.logins[:0]
+	for _, event := range s.logins {
+		if event.UserID != userID {
+			kept = append(kept, event)
+		}
+	}
+	s.logins = kept
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// MagicLinkCreation implements repos.Repository.
+func (s *Store) MagicLinkCreation(ctx context.Context, input *model.NewMagicLinkModel) (*model.MagicLinkModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[input.UserID]; !ok {
+		return nil, fmt.Errorf("failed to create magic link: user %s does not exist", input.UserID)
+	}
+	for _, link := range s.magicLinks {
+		if link.TokenHash == input.TokenHash {
+			return nil, fmt.Errorf("failed to create magic link: duplicate token")
+		}
+	}
+
+	link := &model.MagicLinkModel{
+		ID:        uuid.NewString(),
+		UserID:    input.UserID,
+		TokenHash: input.TokenHash,
+		NonceHash: input.NonceHash,
+		ExpiresAt: input.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	s.magicLinks = append(s.magicLinks, link)
+	return cloneMagicLink(link), nil
+}
+
+// MagicLinkConsume implements repos.Repository.
+func (s *Store) MagicLinkConsume(ctx context.Context, tokenHash string) (*model.MagicLinkModel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, link := range s.magicLinks {
+		if link.TokenHash != tokenHash {
+			continue
+		}
+		if link.UsedAt != nil || !now.Before(link.ExpiresAt) {
+			return nil, nil // No usable link
+		}
+		link.UsedAt = &now
+		return cloneMagicLink(link), nil
+	}
+	return nil, nil // No usable link
+}
+
+// MagicLinkCountSince implements repos.Repository.
+func (s *Store) MagicLinkCountSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	count := 0
+	for _, link := range s.magicLinks {
+		if link.UserID == userID && !link.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// cloneMagicLink copies the timestamp behind UsedAt too.
+func cloneMagicLink(link *model.MagicLinkModel) *model.MagicLinkModel {
+	c := *link
+	if c.UsedAt != nil {
+		t := *c.UsedAt
+		c.UsedAt = &t
+	}
+	return &c
+}
+
+// deleteMagicLinks must be called with s.mu held.
+func (s *Store) deleteMagicLinks(userID string) {
+	kept := s.magicLinks[:0]
+	for _, link := range s.magicLinks {
+		if link.UserID != userID {
+			kept = append(kept, link)
+		}
+	}
+	s.magicLinks = kept
+}
@@ -0,0 +1,211 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+)
+
+// Store is a repos.Repository that keeps everything in process memory. It is
+// meant for tests and local runs; nothing survives a restart.
+type Store struct {
+	mu            sync.RWMutex
+	users         map[string]*model.UserModel
+	organizations map[string]*model.OrganizationModel
+	// memberships is kept in creation order, matching the GORM store.
+	memberships []*model.MembershipModel
+	invitations []*model.InvitationModel
+	addresses   []*model.AddressModel
+	// auditLogs is append-only and survives UserDelete.
+	auditLogs  []*model.AuditLogModel
+	magicLinks []*model.MagicLinkModel
+	logins     []*model.LoginEventModel
+	keys       []*model.SigningKeyModel
+}
+
+// UserByEmail implements repos.Repository.
+func (s *Store) UserByEmail(ctx context.Context, email string) (*model.UserModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if user := s.byEmail(email); user != nil {
+		return clone(user), nil
+	}
+	return nil, nil // User not found
+}
+
+// UserByID implements repos.Repository.
+func (s *Store) UserByID(ctx context.Context, id string) (*model.UserModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if user, ok := s.users[id]; ok {
+		return clone(user), nil
+	}
+	return nil, nil // User not found
+}
+
+// UserByRole implements repos.Repository.
+func (s *Store) UserByRole(ctx context.Context, role string) ([]*model.UserModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	users := []*model.UserModel{}
+	for _, user := range s.users {
+		if user.Role == role {
+			users = append(users, clone(user))
+		}
+	}
+	return users, nil
+}
+
+// UserByIDs implements repos.Repository.
+func (s *Store) UserByIDs(ctx context.Context, ids []string) ([]*model.UserModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	users := []*model.UserModel{}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if user, ok := s.users[id]; ok && !seen[id] {
+			seen[id] = true
+			users = append(users, clone(user))
+		}
+	}
+	return users, nil
+}
+
+// UserCreation implements repos.Repository.
+func (s *Store) UserCreation(ctx context.Context, input *model.NewUserModel) (*model.UserModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byEmail(input.Email) != nil {
+		return nil, apperr.Conflict("user with email %s already exists", input.Email)
+	}
+
+	user := newUser(input)
+	s.users[user.ID] = user
+	return clone(user), nil
+}
+
+// newUser is the active account created from input.
+func newUser(input *model.NewUserModel) *model.UserModel {
+	now := time.Now()
+	return &model.UserModel{
+		ID:              uuid.NewString(),
+		FirstName:       input.FirstName,
+		LastName:        input.LastName,
+		Email:           strings.TrimSpace(input.Email),
+		EmailNormalized: utils.EmailKey(input.Email),
+		Password:        input.Password,
+		Role:            input.Role,
+		Status:          model.StatusActive,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+}
+
+// UserDelete implements repos.Repository.
+func (s *Store) UserDelete(ctx context.Context, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user := s.byEmail(email); user != nil {
+		delete(s.users, user.ID)
+		s.deleteMemberships(user.ID)
+		s.deleteAddresses(func(a *model.AddressModel) bool { return a.UserID == user.ID })
+		s.deleteMagicLinks(user.ID)
+		s.deleteLoginEvents(user.ID)
+	}
+	return nil
+}
+
+// UserUpdate implements repos.Repository.
+func (s *Store) UserUpdate(ctx context.Context, email string, input *model.NewUserModel) (*model.UserModel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user := s.byEmail(email)
+	if user == nil {
+		return nil, nil // User not found
+	}
+	if input.Email != "" {
+		if other := s.byEmail(input.Email); other != nil && other.ID != user.ID {
+			return nil, apperr.Conflict("user with email %s already exists", input.Email)
+		}
+		user.Email = strings.TrimSpace(input.Email)
+		user.EmailNormalized = utils.EmailKey(input.Email)
+	}
+	user.FirstName = input.FirstName
+	user.LastName = input.LastName
+	user.Password = input.Password
+	user.Role = input.Role
+	user.UpdatedAt = time.Now()
+	return clone(user), nil
+}
+
+// UserStatusUpdate implements repos.Repository.
+func (s *Store) UserStatusUpdate(ctx context.Context, id, status, reason string, until *time.Time) (*model.UserModel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[id]
+	if !ok {
+		return nil, nil // User not found
+	}
+	user.Status = status
+	user.StatusReason = reason
+	user.StatusUntil = nil
+	if until != nil {
+		t := *until
+		user.StatusUntil = &t
+	}
+	user.UpdatedAt = time.Now()
+	return clone(user), nil
+}
+
+// UserAvatarUpdate implements repos.Repository.
+func (s *Store) UserAvatarUpdate(ctx context.Context, id, avatarKey string) (*model.UserModel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[id]
+	if !ok {
+		return nil, nil // User not found
+	}
+	user.AvatarKey = avatarKey
+	user.UpdatedAt = time.Now()
+	return clone(user), nil
+}
+
+// byEmail must be called with s.mu held.
+func (s *Store) byEmail(email string) *model.UserModel {
+	key := utils.EmailKey(email)
+	for _, user := range s.users {
+		if user.EmailNormalized == key {
+			return user
+		}
+	}
+	return nil
+}
+
+// clone keeps callers from mutating stored records.
+func clone(user *model.UserModel) *model.UserModel {
+	c := *user
+	if c.StatusUntil != nil {
+		t := *c.StatusUntil
+		c.StatusUntil = &t
+	}
+	return &c
+}
+
+func NewStore() repos.Repository {
+	return &Store{
+		users:         map[string]*model.UserModel{},
+		organizations: map[string]*model.OrganizationModel{},
+	}
+}
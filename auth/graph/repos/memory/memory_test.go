@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/repostest"
+)
+
+func TestRepositoryContract(t *testing.T) {
+	repostest.Run(t, func(t *testing.T) repos.Repository {
+		return NewStore()
+	})
+}
@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// OrganizationCreation implements repos.Repository.
+func (s *Store) OrganizationCreation(ctx context.Context, input *model.NewOrganizationModel, ownerID string) (*model.OrganizationModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+	if input.Name == "" || input.Slug == "" {
+		return nil, apperr.Validation("organization name and slug are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bySlug(input.Slug) != nil {
+		return nil, apperr.Conflict("organization with slug %s already exists", input.Slug)
+	}
+	if _, ok := s.users[ownerID]; !ok {
+		return nil, fmt.Errorf("failed to create organization: user %s does not exist", ownerID)
+	}
+
+	now := time.Now()
+	org := &model.OrganizationModel{
+		ID:        uuid.NewString(),
+		Name:      input.Name,
+		Slug:      input.Slug,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.organizations[org.ID] = org
+	s.memberships = append(s.memberships, &model.MembershipModel{
+		ID:             uuid.NewString(),
+		OrganizationID: org.ID,
+		UserID:         ownerID,
+		Role:           model.OrgRoleOwner,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+	c := *org
+	return &c, nil
+}
+
+// OrganizationByID implements repos.Repository.
+func (s *Store) OrganizationByID(ctx context.Context, id string) (*model.OrganizationModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if org, ok := s.organizations[id]; ok {
+		c := *org
+		return &c, nil
+	}
+	return nil, nil // Organization not found
+}
+
+// OrganizationBySlug implements repos.Repository.
+func (s *Store) OrganizationBySlug(ctx context.Context, slug string) (*model.OrganizationModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if org := s.bySlug(slug); org != nil {
+		c := *org
+		return &c, nil
+	}
+	return nil, nil // Organization not found
+}
+
+// MembershipCreation implements repos.Repository.
+func (s *Store) MembershipCreation(ctx context.Context, organizationID, userID, role string) (*model.MembershipModel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.membership(organizationID, userID) != nil {
+		return nil, apperr.Conflict("user %s is already a member of organization %s", userID, organizationID)
+	}
+	if _, ok := s.organizations[organizationID]; !ok {
+		return nil, fmt.Errorf("failed to create membership: organization %s does not exist", organizationID)
+	}
+	if _, ok := s.users[userID]; !ok {
+		return nil, fmt.Errorf("failed to create membership: user %s does not exist", userID)
+	}
+
+	now := time.Now()
+	membership := &model.MembershipModel{
+		ID:             uuid.NewString(),
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Role:           role,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	s.memberships = append(s.memberships, membership)
+	c := *membership
+	return &c, nil
+}
+
+// MembershipByUser implements repos.Repository.
+func (s *Store) MembershipByUser(ctx context.Context, organizationID, userID string) (*model.MembershipModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if m := s.membership(organizationID, userID); m != nil {
+		c := *m
+		return &c, nil
+	}
+	return nil, nil // Membership not found
+}
+
+// MembershipsByOrganization implements repos.Repository.
+func (s *Store) MembershipsByOrganization(ctx context.Context, organizationID string) ([]*model.MembershipModel, error) {
+	return s.filterMemberships(func(m *model.MembershipModel) bool { return m.OrganizationID == organizationID }), nil
+}
+
+// MembershipsByUser implements repos.Repository.
+func (s *Store) MembershipsByUser(ctx context.Context, userID string) ([]*model.MembershipModel, error) {
+	return s.filterMemberships(func(m *model.MembershipModel) bool { return m.UserID == userID }), nil
+}
+
+// MembershipUpdate implements repos.Repository.
+func (s *Store) MembershipUpdate(ctx context.Context, organizationID, userID, role string) (*model.MembershipModel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.membership(organizationID, userID)
+	if m == nil {
+		return nil, nil // Membership not found
+	}
+	if m.Role == model.OrgRoleOwner && role != model.OrgRoleOwner && s.owners(organizationID) <= 1 {
+		return nil, apperr.Conflict("an organization must keep at least one owner")
+	}
+	m.Role = role
+	m.UpdatedAt = time.Now()
+	c := *m
+	return &c, nil
+}
+
+// owners must be called with s.mu held.
+func (s *Store) owners(organizationID string) int {
+	n := 0
+	for _, m := range s.memberships {
+		if m.OrganizationID == organizationID && m.Role == model.OrgRoleOwner {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Store) filterMemberships(keep func(*model.MembershipModel) bool) []*model.MembershipModel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	memberships := []*model.MembershipModel{}
+	for _, m := range s.memberships {
+		if keep(m) {
+			c := *m
+			memberships = append(memberships, &c)
+		}
+	}
+	return memberships
+}
+
+// bySlug must be called with s.mu held.
+func (s *Store) bySlug(slug string) *model.OrganizationModel {
+	for _, org := range s.organizations {
+		if org.Slug == slug {
+			return org
+		}
+	}
+	return nil
+}
+
+// membership must be called with s.mu held.
+func (s *Store) membership(organizationID, userID string) *model.MembershipModel {
+	for _, m := range s.memberships {
+		if m.OrganizationID == organizationID && m.UserID == userID {
+			return m
+		}
+	}
+	return nil
+}
+
+// deleteMemberships must be called with s.mu held.
+func (s *Store) deleteMemberships(userID string) {
+	kept := s.memberships[:0]
+	for _, m := range s.memberships {
+		if m.UserID != userID {
+			kept = append(kept, m)
+		}
+	}
+	s.memberships = kept
+}
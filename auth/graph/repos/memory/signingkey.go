@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// SigningKeyRotation implements repos.Repository.
+func (s *Store) SigningKeyRotation(ctx context.Context, input *model.NewSigningKeyModel, retireAt time.Time) (*model.SigningKeyModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.keys {
+		if key.ID == input.ID {
+			return nil, fmt.Errorf("failed to rotate signing key: duplicate id %s", input.ID)
+		}
+	}
+
+	now := time.Now()
+	kept := s.keys[:0]
+	for _, key := range s.keys {
+		if key.ExpiresAt == nil {
+			t := retireAt
+			key.ExpiresAt = &t
+		}
+		if key.ExpiresAt.After(now) {
+			kept = append(kept, key)
+		}
+	}
+	key := &model.SigningKeyModel{
+		ID:         input.ID,
+		Secret:     input.Secret,
+		ActiveFrom: input.ActiveFrom,
+		CreatedAt:  now,
+	}
+	s.keys = append(kept, key)
+	return cloneSigningKey(key), nil
+}
+
+// SigningKeys implements repos.Repository.
+func (s *Store) SigningKeys(ctx context.Context) ([]*model.SigningKeyModel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]*model.SigningKeyModel, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, cloneSigningKey(key))
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].ActiveFrom.Before(keys[j].ActiveFrom) })
+	return keys, nil
+}
+
+// cloneSigningKey copies the timestamp behind ExpiresAt too.
+func cloneSigningKey(key *model.SigningKeyModel) *model.SigningKeyModel {
+	c := *key
+	if c.ExpiresAt != nil {
+		t := *c.ExpiresAt
+		c.ExpiresAt = &t
+	}
+	return &c
+}
@@ -2,15 +2,128 @@ package repos
 
 import (
 	"context"
+	"time"
 
 	"github.com/tabed23/cloudmarket-auth/graph/model"
 )
 
+// Repository is everything the resolvers need from storage.
 type Repository interface {
+	UserRepository
+	OrganizationRepository
+	InvitationRepository
+	AddressRepository
+	AuditRepository
+	MagicLinkRepository
+	LoginEventRepository
+	SigningKeyRepository
+}
+
+type UserRepository interface {
 	UserCreation(ctx context.Context, input *model.NewUserModel) (*model.UserModel, error)
 	UserByEmail(ctx context.Context, email string) (*model.UserModel, error)
 	UserByID(ctx context.Context, id string) (*model.UserModel, error)
 	UserByRole(ctx context.Context, role string) ([]*model.UserModel, error)
+	// UserByIDs returns the users with the given IDs in one lookup, in no
+	// particular order. Unknown IDs are skipped.
+	UserByIDs(ctx context.Context, ids []string) ([]*model.UserModel, error)
 	UserDelete(ctx context.Context, email string) error
+	// UserUpdate replaces the name, password and role of the user with the
+	// given email, and the email too unless input.Email is empty. An email
+	// taken by another user is an apperr.Conflict.
 	UserUpdate(ctx context.Context, email string, input *model.NewUserModel) (*model.UserModel, error)
+	// UserAvatarUpdate points the user at a new set of avatar renditions.
+	UserAvatarUpdate(ctx context.Context, id, avatarKey string) (*model.UserModel, error)
+	// UserStatusUpdate sets the account status, its reason and, for
+	// suspensions, when it ends.
+	UserStatusUpdate(ctx context.Context, id, status, reason string, until *time.Time) (*model.UserModel, error)
+}
+
+// OrganizationRepository stores organizations and their memberships. Deleting
+// a user removes their memberships.
+type OrganizationRepository interface {
+	// OrganizationCreation creates the organization and makes ownerID its
+	// OWNER in one step.
+	OrganizationCreation(ctx context.Context, input *model.NewOrganizationModel, ownerID string) (*model.OrganizationModel, error)
+	OrganizationByID(ctx context.Context, id string) (*model.OrganizationModel, error)
+	OrganizationBySlug(ctx context.Context, slug string) (*model.OrganizationModel, error)
+	MembershipCreation(ctx context.Context, organizationID, userID, role string) (*model.MembershipModel, error)
+	MembershipByUser(ctx context.Context, organizationID, userID string) (*model.MembershipModel, error)
+	MembershipsByOrganization(ctx context.Context, organizationID string) ([]*model.MembershipModel, error)
+	MembershipsByUser(ctx context.Context, userID string) ([]*model.MembershipModel, error)
+	// MembershipUpdate changes the role of a member. Demoting the last
+	// OWNER fails with an apperr.Conflict, checked atomically with the
+	// update so that concurrent demotions cannot leave no owner.
+	MembershipUpdate(ctx context.Context, organizationID, userID, role string) (*model.MembershipModel, error)
+}
+
+// InvitationRepository stores organization invitations.
+type InvitationRepository interface {
+	InvitationCreation(ctx context.Context, input *model.NewInvitationModel) (*model.InvitationModel, error)
+	InvitationByID(ctx context.Context, id string) (*model.InvitationModel, error)
+	InvitationsByOrganization(ctx context.Context, organizationID string) ([]*model.InvitationModel, error)
+	// InvitationRevoke closes a pending invitation. It fails if the
+	// invitation was already accepted or revoked.
+	InvitationRevoke(ctx context.Context, id string) (*model.InvitationModel, error)
+	// InvitationAccept closes a pending invitation and makes userID a member
+	// with the invited role in one step.
+	InvitationAccept(ctx context.Context, id, userID string) (*model.MembershipModel, error)
+	// InvitationSignUp creates the user from input and accepts the pending
+	// invitation for them in one step: if either fails, neither is stored.
+	InvitationSignUp(ctx context.Context, id string, input *model.NewUserModel) (*model.UserModel, *model.MembershipModel, error)
+}
+
+// AddressRepository stores the address books of users. Deleting a user
+// removes their addresses.
+type AddressRepository interface {
+	// AddressCreation stores a new address for userID. Setting a default
+	// flag clears it on the user's other addresses in the same step.
+	AddressCreation(ctx context.Context, userID string, input *model.NewAddressModel) (*model.AddressModel, error)
+	AddressByID(ctx context.Context, id string) (*model.AddressModel, error)
+	AddressesByUser(ctx context.Context, userID string) ([]*model.AddressModel, error)
+	// AddressUpdate replaces every field of the address, with the same
+	// default flag handling as AddressCreation.
+	AddressUpdate(ctx context.Context, id string, input *model.NewAddressModel) (*model.AddressModel, error)
+	AddressDelete(ctx context.Context, id string) error
+}
+
+// AuditRepository appends to and reads the audit log. Entries are never
+// updated or deleted, not even with the users they mention.
+type AuditRepository interface {
+	AuditLogCreation(ctx context.Context, input *model.NewAuditLogModel) (*model.AuditLogModel, error)
+	AuditLogs(ctx context.Context, filter model.AuditLogFilter) ([]*model.AuditLogModel, error)
+}
+
+// MagicLinkRepository stores single-use sign-in links. Deleting a user
+// removes their links.
+type MagicLinkRepository interface {
+	MagicLinkCreation(ctx context.Context, input *model.NewMagicLinkModel) (*model.MagicLinkModel, error)
+	// MagicLinkConsume marks the unused, unexpired link with tokenHash as
+	// used and returns it. Of concurrent calls for one link only one gets
+	// it; the others, like calls for unknown or spent links, get nil, nil.
+	MagicLinkConsume(ctx context.Context, tokenHash string) (*model.MagicLinkModel, error)
+	// MagicLinkCountSince counts the links created for userID since then.
+	MagicLinkCountSince(ctx context.Context, userID string, since time.Time) (int, error)
+}
+
+// LoginEventRepository records sign-in attempts. Deleting a user removes
+// their history.
+type LoginEventRepository interface {
+	LoginEventCreation(ctx context.Context, input *model.NewLoginEventModel) (*model.LoginEventModel, error)
+	LoginEventByID(ctx context.Context, id string) (*model.LoginEventModel, error)
+	LoginEvents(ctx context.Context, filter model.LoginEventFilter) ([]*model.LoginEventModel, error)
+	// LoginEventReport marks the event as not made by userID's owner and
+	// returns it. Reporting twice keeps the first time; events of other
+	// users are not found.
+	LoginEventReport(ctx context.Context, id, userID string) (*model.LoginEventModel, error)
+}
+
+// SigningKeyRepository stores the rotated token signing keys.
+type SigningKeyRepository interface {
+	// SigningKeyRotation adds a key and, in the same transaction, sets
+	// retireAt as the expiry of every key without one and deletes the keys
+	// that have already expired.
+	SigningKeyRotation(ctx context.Context, input *model.NewSigningKeyModel, retireAt time.Time) (*model.SigningKeyModel, error)
+	// SigningKeys returns the stored keys, oldest first.
+	SigningKeys(ctx context.Context) ([]*model.SigningKeyModel, error)
 }
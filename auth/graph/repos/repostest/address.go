@@ -0,0 +1,157 @@
+package repostest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+)
+
+func runAddresses(t *testing.T, newRepo func(t *testing.T) repos.Repository) {
+	t.Run("AddressCreation", func(t *testing.T) { testAddressCreation(t, newRepo(t)) })
+	t.Run("AddressNotFoundReturnsNilNil", func(t *testing.T) { testAddressNotFound(t, newRepo(t)) })
+	t.Run("AddressDefaultsAreExclusive", func(t *testing.T) { testAddressDefaults(t, newRepo(t)) })
+	t.Run("AddressUpdate", func(t *testing.T) { testAddressUpdate(t, newRepo(t)) })
+	t.Run("AddressDelete", func(t *testing.T) { testAddressDelete(t, newRepo(t)) })
+}
+
+// NewAddress returns valid input for AddressCreation.
+func NewAddress(street string) *model.NewAddressModel {
+	return &model.NewAddressModel{
+		FullName:   "Ada Lovelace",
+		Street:     street,
+		City:       "London",
+		PostalCode: "SW1A 1AA",
+		Country:    "GB",
+		Phone:      "+44 20 7946 0000",
+	}
+}
+
+func mustCreateAddress(t *testing.T, repo repos.Repository, userID string, input *model.NewAddressModel) *model.AddressModel {
+	t.Helper()
+	address, err := repo.AddressCreation(context.Background(), userID, input)
+	if err != nil {
+		t.Fatalf("AddressCreation(%s): %v", input.Street, err)
+	}
+	return address
+}
+
+func testAddressCreation(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	user := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	other := MustCreate(t, repo, "grace@example.com", model.RoleUser)
+	created := mustCreateAddress(t, repo, user.ID, NewAddress("1 Main St"))
+	mustCreateAddress(t, repo, other.ID, NewAddress("2 Side St"))
+
+	got, err := repo.AddressByID(ctx, created.ID)
+	if err != nil || got == nil {
+		t.Fatalf("AddressByID = %v, %v", got, err)
+	}
+	if got.UserID != user.ID || got.FullName != "Ada Lovelace" || got.Street != "1 Main St" || got.City != "London" ||
+		got.PostalCode != "SW1A 1AA" || got.Country != "GB" || got.Phone != "+44 20 7946 0000" || got.CreatedAt.IsZero() {
+		t.Errorf("unexpected address %+v", got)
+	}
+
+	list, err := repo.AddressesByUser(ctx, user.ID)
+	if err != nil || len(list) != 1 || list[0].ID != created.ID {
+		t.Errorf("AddressesByUser = %v, %v", list, err)
+	}
+}
+
+func testAddressNotFound(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	missing := "00000000-0000-0000-0000-000000000000"
+	if a, err := repo.AddressByID(ctx, missing); a != nil || err != nil {
+		t.Errorf("AddressByID = %v, %v; want nil, nil", a, err)
+	}
+	if a, err := repo.AddressUpdate(ctx, missing, NewAddress("1 Main St")); a != nil || err != nil {
+		t.Errorf("AddressUpdate = %v, %v; want nil, nil", a, err)
+	}
+	if err := repo.AddressDelete(ctx, missing); err != nil {
+		t.Errorf("deleting a missing address: %v", err)
+	}
+}
+
+func testAddressDefaults(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	user := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	other := MustCreate(t, repo, "grace@example.com", model.RoleUser)
+
+	home := NewAddress("1 Home St")
+	home.IsDefaultShipping, home.IsDefaultBilling = true, true
+	first := mustCreateAddress(t, repo, user.ID, home)
+	othersDefault := mustCreateAddress(t, repo, other.ID, home)
+
+	work := NewAddress("2 Work St")
+	work.IsDefaultShipping = true
+	second := mustCreateAddress(t, repo, user.ID, work)
+
+	byID := func(id string) *model.AddressModel {
+		a, err := repo.AddressByID(ctx, id)
+		if err != nil || a == nil {
+			t.Fatalf("AddressByID(%s) = %v, %v", id, a, err)
+		}
+		return a
+	}
+	if a := byID(first.ID); a.IsDefaultShipping || !a.IsDefaultBilling {
+		t.Errorf("first address after new default shipping = %+v", a)
+	}
+	if a := byID(second.ID); !a.IsDefaultShipping || a.IsDefaultBilling {
+		t.Errorf("second address = %+v", a)
+	}
+	if a := byID(othersDefault.ID); !a.IsDefaultShipping || !a.IsDefaultBilling {
+		t.Errorf("defaults of another user changed: %+v", a)
+	}
+
+	work.IsDefaultBilling = true
+	if _, err := repo.AddressUpdate(ctx, second.ID, work); err != nil {
+		t.Fatalf("AddressUpdate: %v", err)
+	}
+	if a := byID(first.ID); a.IsDefaultShipping || a.IsDefaultBilling {
+		t.Errorf("first address after update took both defaults = %+v", a)
+	}
+}
+
+func testAddressUpdate(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	user := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	created := mustCreateAddress(t, repo, user.ID, NewAddress("1 Main St"))
+
+	input := NewAddress("9 New St")
+	input.City, input.Phone = "Manchester", ""
+	updated, err := repo.AddressUpdate(ctx, created.ID, input)
+	if err != nil || updated == nil {
+		t.Fatalf("AddressUpdate = %v, %v", updated, err)
+	}
+	got, _ := repo.AddressByID(ctx, created.ID)
+	for _, a := range []*model.AddressModel{updated, got} {
+		if a == nil || a.Street != "9 New St" || a.City != "Manchester" || a.Phone != "" || a.UserID != user.ID {
+			t.Errorf("update not applied: %+v", a)
+		}
+	}
+}
+
+func testAddressDelete(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	user := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	kept := mustCreateAddress(t, repo, user.ID, NewAddress("1 Main St"))
+	deleted := mustCreateAddress(t, repo, user.ID, NewAddress("2 Side St"))
+
+	if err := repo.AddressDelete(ctx, deleted.ID); err != nil {
+		t.Fatalf("AddressDelete: %v", err)
+	}
+	if a, _ := repo.AddressByID(ctx, deleted.ID); a != nil {
+		t.Errorf("deleted address still found: %+v", a)
+	}
+	if a, _ := repo.AddressByID(ctx, kept.ID); a == nil {
+		t.Error("AddressDelete removed another address")
+	}
+
+	if err := repo.UserDelete(ctx, user.Email); err != nil {
+		t.Fatalf("UserDelete: %v", err)
+	}
+	if list, err := repo.AddressesByUser(ctx, user.ID); err != nil || len(list) != 0 {
+		t.Errorf("addresses of deleted user = %v, %v", list, err)
+	}
+}
@@ -0,0 +1,116 @@
+package repostest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+)
+
+func runAudit(t *testing.T, newRepo func(t *testing.T) repos.Repository) {
+	t.Run("AuditLogCreation", func(t *testing.T) { testAuditLogCreation(t, newRepo(t)) })
+	t.Run("AuditLogsFilter", func(t *testing.T) { testAuditLogsFilter(t, newRepo(t)) })
+	t.Run("AuditLogsSurviveUserDelete", func(t *testing.T) { testAuditLogsSurviveUserDelete(t, newRepo(t)) })
+}
+
+func mustAudit(t *testing.T, repo repos.Repository, actorID, subjectID, action string) *model.AuditLogModel {
+	t.Helper()
+	entry, err := repo.AuditLogCreation(context.Background(), &model.NewAuditLogModel{
+		ActorID:   actorID,
+		SubjectID: subjectID,
+		Action:    action,
+		Operation: "GetMe",
+		Detail:    "query getMe",
+		RequestID: "req-1",
+	})
+	if err != nil {
+		t.Fatalf("AuditLogCreation: %v", err)
+	}
+	// Entries are ordered by time; keep their timestamps apart.
+	time.Sleep(2 * time.Millisecond)
+	return entry
+}
+
+func testAuditLogCreation(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	created := mustAudit(t, repo, "admin", "ada", model.AuditImpersonatedRequest)
+	if created.ID == "" || created.CreatedAt.IsZero() {
+		t.Fatalf("AuditLogCreation did not fill ID and CreatedAt: %+v", created)
+	}
+
+	entries, err := repo.AuditLogs(ctx, model.AuditLogFilter{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("AuditLogs = %v, %v; want one entry", entries, err)
+	}
+	got := entries[0]
+	if got.ID != created.ID || got.ActorID != "admin" || got.SubjectID != "ada" ||
+		got.Action != model.AuditImpersonatedRequest || got.Operation != "GetMe" ||
+		got.Detail != "query getMe" || got.RequestID != "req-1" {
+		t.Errorf("stored entry = %+v", got)
+	}
+
+	if _, err := repo.AuditLogCreation(ctx, nil); err == nil {
+		t.Error("AuditLogCreation(nil) succeeded")
+	}
+}
+
+func testAuditLogsFilter(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	first := mustAudit(t, repo, "admin", "ada", model.AuditImpersonationStart)
+	second := mustAudit(t, repo, "admin", "ada", model.AuditImpersonatedRequest)
+	third := mustAudit(t, repo, "admin", "grace", model.AuditImpersonatedRequest)
+	other := mustAudit(t, repo, "root", "ada", model.AuditImpersonatedRequest)
+
+	ids := func(entries []*model.AuditLogModel) []string {
+		out := make([]string, len(entries))
+		for i, e := range entries {
+			out[i] = e.ID
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		filter model.AuditLogFilter
+		want   []*model.AuditLogModel
+	}{
+		{"all, newest first", model.AuditLogFilter{}, []*model.AuditLogModel{other, third, second, first}},
+		{"actor", model.AuditLogFilter{ActorID: "admin"}, []*model.AuditLogModel{third, second, first}},
+		{"subject", model.AuditLogFilter{SubjectID: "ada"}, []*model.AuditLogModel{other, second, first}},
+		{"action", model.AuditLogFilter{ActorID: "admin", Action: model.AuditImpersonationStart}, []*model.AuditLogModel{first}},
+		{"limit", model.AuditLogFilter{SubjectID: "ada", Limit: 2}, []*model.AuditLogModel{other, second}},
+		{"no match", model.AuditLogFilter{ActorID: "nobody"}, nil},
+	}
+	for _, tc := range tests {
+		entries, err := repo.AuditLogs(ctx, tc.filter)
+		if err != nil {
+			t.Fatalf("%s: AuditLogs: %v", tc.name, err)
+		}
+		got, want := ids(entries), ids(tc.want)
+		if len(got) != len(want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: got %v, want %v", tc.name, got, want)
+				break
+			}
+		}
+	}
+}
+
+func testAuditLogsSurviveUserDelete(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	user := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	mustAudit(t, repo, "admin", user.ID, model.AuditImpersonatedRequest)
+
+	if err := repo.UserDelete(ctx, user.Email); err != nil {
+		t.Fatalf("UserDelete: %v", err)
+	}
+	entries, err := repo.AuditLogs(ctx, model.AuditLogFilter{SubjectID: user.ID})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("AuditLogs after UserDelete = %v, %v; want the entry kept", entries, err)
+	}
+}
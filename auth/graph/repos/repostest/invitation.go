@@ -0,0 +1,192 @@
+package repostest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+)
+
+func runInvitations(t *testing.T, newRepo func(t *testing.T) repos.Repository) {
+	t.Run("InvitationCreation", func(t *testing.T) { testInvitationCreation(t, newRepo(t)) })
+	t.Run("InvitationNotFoundReturnsNilNil", func(t *testing.T) { testInvitationNotFound(t, newRepo(t)) })
+	t.Run("InvitationAccept", func(t *testing.T) { testInvitationAccept(t, newRepo(t)) })
+	t.Run("InvitationAcceptExpired", func(t *testing.T) { testInvitationAcceptExpired(t, newRepo(t)) })
+	t.Run("InvitationSignUp", func(t *testing.T) { testInvitationSignUp(t, newRepo(t)) })
+	t.Run("InvitationSignUpIsAtomic", func(t *testing.T) { testInvitationSignUpIsAtomic(t, newRepo(t)) })
+	t.Run("InvitationRevoke", func(t *testing.T) { testInvitationRevoke(t, newRepo(t)) })
+}
+
+// MustInvite creates an invitation to org and fails the test on error.
+func MustInvite(t *testing.T, repo repos.Repository, org *model.OrganizationModel, email string, expiresAt time.Time) *model.InvitationModel {
+	t.Helper()
+	invitation, err := repo.InvitationCreation(context.Background(), &model.NewInvitationModel{
+		OrganizationID: org.ID,
+		Email:          email,
+		Role:           model.OrgRoleAdmin,
+		InvitedBy:      "inviter",
+		ExpiresAt:      expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("InvitationCreation(%s): %v", email, err)
+	}
+	return invitation
+}
+
+func testInvitationCreation(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	owner := MustCreate(t, repo, "owner@example.com", model.RoleUser)
+	org := MustCreateOrganization(t, repo, "acme", owner)
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	created := MustInvite(t, repo, org, "new@example.com", expiresAt)
+
+	got, err := repo.InvitationByID(ctx, created.ID)
+	if err != nil || got == nil {
+		t.Fatalf("InvitationByID = %v, %v", got, err)
+	}
+	if got.OrganizationID != org.ID || got.Email != "new@example.com" || got.Role != model.OrgRoleAdmin ||
+		got.InvitedBy != "inviter" || !got.ExpiresAt.Equal(expiresAt) || !got.Pending(time.Now()) {
+		t.Errorf("unexpected invitation %+v", got)
+	}
+
+	list, err := repo.InvitationsByOrganization(ctx, org.ID)
+	if err != nil || len(list) != 1 || list[0].ID != created.ID {
+		t.Errorf("InvitationsByOrganization = %v, %v", list, err)
+	}
+}
+
+func testInvitationNotFound(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	missing := "00000000-0000-0000-0000-000000000000"
+	if inv, err := repo.InvitationByID(ctx, missing); inv != nil || err != nil {
+		t.Errorf("InvitationByID = %v, %v; want nil, nil", inv, err)
+	}
+	if inv, err := repo.InvitationRevoke(ctx, missing); inv != nil || err != nil {
+		t.Errorf("InvitationRevoke = %v, %v; want nil, nil", inv, err)
+	}
+	if m, err := repo.InvitationAccept(ctx, missing, missing); m != nil || err != nil {
+		t.Errorf("InvitationAccept = %v, %v; want nil, nil", m, err)
+	}
+	if u, m, err := repo.InvitationSignUp(ctx, missing, NewUser("new@example.com", model.RoleUser)); u != nil || m != nil || err != nil {
+		t.Errorf("InvitationSignUp = %v, %v, %v; want nil, nil, nil", u, m, err)
+	}
+	if u, _ := repo.UserByEmail(ctx, "new@example.com"); u != nil {
+		t.Error("InvitationSignUp of a missing invitation created the user")
+	}
+}
+
+func testInvitationAccept(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	owner := MustCreate(t, repo, "owner@example.com", model.RoleUser)
+	invitee := MustCreate(t, repo, "new@example.com", model.RoleUser)
+	org := MustCreateOrganization(t, repo, "acme", owner)
+	invitation := MustInvite(t, repo, org, invitee.Email, time.Now().Add(time.Hour))
+
+	membership, err := repo.InvitationAccept(ctx, invitation.ID, invitee.ID)
+	if err != nil || membership == nil {
+		t.Fatalf("InvitationAccept = %v, %v", membership, err)
+	}
+	if membership.OrganizationID != org.ID || membership.UserID != invitee.ID || membership.Role != model.OrgRoleAdmin {
+		t.Errorf("unexpected membership %+v", membership)
+	}
+	if got, _ := repo.InvitationByID(ctx, invitation.ID); got == nil || got.AcceptedAt == nil || got.Pending(time.Now()) {
+		t.Errorf("invitation not closed: %+v", got)
+	}
+
+	if _, err := repo.InvitationAccept(ctx, invitation.ID, invitee.ID); err == nil {
+		t.Error("expected an error accepting an invitation twice")
+	}
+	if _, err := repo.InvitationRevoke(ctx, invitation.ID); err == nil {
+		t.Error("expected an error revoking an accepted invitation")
+	}
+
+	// A user who is already a member leaves the invitation open.
+	again := MustInvite(t, repo, org, invitee.Email, time.Now().Add(time.Hour))
+	if _, err := repo.InvitationAccept(ctx, again.ID, invitee.ID); err == nil {
+		t.Error("expected an error for an existing member")
+	}
+	if got, _ := repo.InvitationByID(ctx, again.ID); got == nil || !got.Pending(time.Now()) {
+		t.Errorf("failed accept closed the invitation: %+v", got)
+	}
+}
+
+func testInvitationAcceptExpired(t *testing.T, repo repos.Repository) {
+	owner := MustCreate(t, repo, "owner@example.com", model.RoleUser)
+	invitee := MustCreate(t, repo, "new@example.com", model.RoleUser)
+	org := MustCreateOrganization(t, repo, "acme", owner)
+	invitation := MustInvite(t, repo, org, invitee.Email, time.Now().Add(-time.Minute))
+
+	if _, err := repo.InvitationAccept(context.Background(), invitation.ID, invitee.ID); err == nil {
+		t.Fatal("expected an error for an expired invitation")
+	}
+	if m, _ := repo.MembershipByUser(context.Background(), org.ID, invitee.ID); m != nil {
+		t.Errorf("expired invitation created membership %+v", m)
+	}
+}
+
+func testInvitationRevoke(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	owner := MustCreate(t, repo, "owner@example.com", model.RoleUser)
+	invitee := MustCreate(t, repo, "new@example.com", model.RoleUser)
+	org := MustCreateOrganization(t, repo, "acme", owner)
+	invitation := MustInvite(t, repo, org, invitee.Email, time.Now().Add(time.Hour))
+
+	revoked, err := repo.InvitationRevoke(ctx, invitation.ID)
+	if err != nil || revoked == nil || revoked.RevokedAt == nil {
+		t.Fatalf("InvitationRevoke = %v, %v", revoked, err)
+	}
+	if _, err := repo.InvitationRevoke(ctx, invitation.ID); err == nil {
+		t.Error("expected an error revoking twice")
+	}
+	if _, err := repo.InvitationAccept(ctx, invitation.ID, invitee.ID); err == nil {
+		t.Error("expected an error accepting a revoked invitation")
+	}
+}
+
+func testInvitationSignUp(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	owner := MustCreate(t, repo, "owner@example.com", model.RoleUser)
+	org := MustCreateOrganization(t, repo, "acme", owner)
+	invitation := MustInvite(t, repo, org, "new@example.com", time.Now().Add(time.Hour))
+
+	user, membership, err := repo.InvitationSignUp(ctx, invitation.ID, NewUser("New@example.com", model.RoleUser))
+	if err != nil || user == nil || membership == nil {
+		t.Fatalf("InvitationSignUp = %v, %v, %v", user, membership, err)
+	}
+	if membership.OrganizationID != org.ID || membership.UserID != user.ID || membership.Role != model.OrgRoleAdmin {
+		t.Errorf("unexpected membership %+v", membership)
+	}
+	if got, err := repo.UserByEmail(ctx, "new@example.com"); err != nil || got == nil || got.ID != user.ID {
+		t.Errorf("UserByEmail = %v, %v", got, err)
+	}
+	if got, _ := repo.InvitationByID(ctx, invitation.ID); got == nil || got.Pending(time.Now()) {
+		t.Errorf("invitation still pending: %+v", got)
+	}
+}
+
+func testInvitationSignUpIsAtomic(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	owner := MustCreate(t, repo, "owner@example.com", model.RoleUser)
+	org := MustCreateOrganization(t, repo, "acme", owner)
+
+	// An invitation that can no longer be accepted creates no account.
+	expired := MustInvite(t, repo, org, "late@example.com", time.Now().Add(-time.Minute))
+	if u, m, err := repo.InvitationSignUp(ctx, expired.ID, NewUser("late@example.com", model.RoleUser)); err == nil {
+		t.Fatalf("InvitationSignUp of an expired invitation = %v, %v", u, m)
+	}
+	if u, _ := repo.UserByEmail(ctx, "late@example.com"); u != nil {
+		t.Error("a failed sign-up left the user behind")
+	}
+
+	// A taken email leaves the invitation pending.
+	invitation := MustInvite(t, repo, org, "owner@example.com", time.Now().Add(time.Hour))
+	if _, _, err := repo.InvitationSignUp(ctx, invitation.ID, NewUser("OWNER@example.com", model.RoleUser)); apperr.CodeOf(err) != apperr.CodeConflict {
+		t.Fatalf("InvitationSignUp with a taken email = %v; want a conflict", err)
+	}
+	if got, _ := repo.InvitationByID(ctx, invitation.ID); got == nil || !got.Pending(time.Now()) {
+		t.Errorf("a failed sign-up accepted the invitation: %+v", got)
+	}
+}
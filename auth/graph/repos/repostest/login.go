@@ -0,0 +1,148 @@
+package repostest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+)
+
+func runLoginEvents(t *testing.T, newRepo func(t *testing.T) repos.Repository) {
+	t.Run("LoginEventCreation", func(t *testing.T) { testLoginEventCreation(t, newRepo(t)) })
+	t.Run("LoginEventsFilter", func(t *testing.T) { testLoginEventsFilter(t, newRepo(t)) })
+	t.Run("LoginEventReport", func(t *testing.T) { testLoginEventReport(t, newRepo(t)) })
+	t.Run("LoginEventsDeletedWithUser", func(t *testing.T) { testLoginEventsDeletedWithUser(t, newRepo(t)) })
+}
+
+func mustLogin(t *testing.T, repo repos.Repository, userID string, outcome model.LoginOutcome, fingerprint string) *model.LoginEventModel {
+	t.Helper()
+	event, err := repo.LoginEventCreation(context.Background(), &model.NewLoginEventModel{
+		UserID:      userID,
+		Method:      model.LoginMethodPassword,
+		Outcome:     outcome,
+		IP:          "192.0.2.7",
+		UserAgent:   "Mozilla/5.0 (X11; Linux x86_64) Firefox/130.0",
+		Fingerprint: fingerprint,
+	})
+	if err != nil {
+		t.Fatalf("LoginEventCreation: %v", err)
+	}
+	// Events are ordered by time; keep their timestamps apart.
+	time.Sleep(2 * time.Millisecond)
+	return event
+}
+
+func testLoginEventCreation(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	user := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	created := mustLogin(t, repo, user.ID, model.LoginOutcomeSuccess, "fp-laptop")
+	if created.ID == "" || created.CreatedAt.IsZero() || created.Revoked() {
+		t.Fatalf("LoginEventCreation = %+v", created)
+	}
+
+	got, err := repo.LoginEventByID(ctx, created.ID)
+	if err != nil || got == nil {
+		t.Fatalf("LoginEventByID = %v, %v", got, err)
+	}
+	if got.UserID != user.ID || got.Method != model.LoginMethodPassword || got.Outcome != model.LoginOutcomeSuccess ||
+		got.IP != "192.0.2.7" || got.UserAgent == "" || got.Fingerprint != "fp-laptop" || got.ReportedAt != nil {
+		t.Errorf("stored event = %+v", got)
+	}
+
+	if event, err := repo.LoginEventByID(ctx, "missing"); event != nil || err != nil {
+		t.Errorf("LoginEventByID(missing) = %v, %v; want nil, nil", event, err)
+	}
+	if _, err := repo.LoginEventCreation(ctx, nil); err == nil {
+		t.Error("LoginEventCreation(nil) succeeded")
+	}
+	if _, err := repo.LoginEventCreation(ctx, &model.NewLoginEventModel{UserID: "missing", Outcome: model.LoginOutcomeSuccess}); err == nil {
+		t.Error("LoginEventCreation for a missing user succeeded")
+	}
+}
+
+func testLoginEventsFilter(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	ada := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	grace := MustCreate(t, repo, "grace@example.com", model.RoleUser)
+	first := mustLogin(t, repo, ada.ID, model.LoginOutcomeSuccess, "fp-laptop")
+	second := mustLogin(t, repo, ada.ID, model.LoginOutcomeBadPassword, "fp-phone")
+	third := mustLogin(t, repo, ada.ID, model.LoginOutcomeSuccess, "fp-phone")
+	other := mustLogin(t, repo, grace.ID, model.LoginOutcomeSuccess, "fp-laptop")
+
+	ids := func(events []*model.LoginEventModel) []string {
+		out := make([]string, len(events))
+		for i, e := range events {
+			out[i] = e.ID
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		filter model.LoginEventFilter
+		want   []*model.LoginEventModel
+	}{
+		{"all, newest first", model.LoginEventFilter{}, []*model.LoginEventModel{other, third, second, first}},
+		{"user", model.LoginEventFilter{UserID: ada.ID}, []*model.LoginEventModel{third, second, first}},
+		{"outcome", model.LoginEventFilter{UserID: ada.ID, Outcome: model.LoginOutcomeSuccess}, []*model.LoginEventModel{third, first}},
+		{"fingerprint", model.LoginEventFilter{UserID: ada.ID, Fingerprint: "fp-phone"}, []*model.LoginEventModel{third, second}},
+		{"limit", model.LoginEventFilter{UserID: ada.ID, Limit: 1}, []*model.LoginEventModel{third}},
+		{"no match", model.LoginEventFilter{UserID: grace.ID, Fingerprint: "fp-phone"}, nil},
+	}
+	for _, tc := range tests {
+		events, err := repo.LoginEvents(ctx, tc.filter)
+		if err != nil {
+			t.Fatalf("%s: LoginEvents: %v", tc.name, err)
+		}
+		got, want := ids(events), ids(tc.want)
+		if len(got) != len(want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: got %v, want %v", tc.name, got, want)
+				break
+			}
+		}
+	}
+}
+
+func testLoginEventReport(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	ada := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	grace := MustCreate(t, repo, "grace@example.com", model.RoleUser)
+	event := mustLogin(t, repo, ada.ID, model.LoginOutcomeSuccess, "fp-laptop")
+
+	if got, err := repo.LoginEventReport(ctx, event.ID, grace.ID); got != nil || err != nil {
+		t.Fatalf("LoginEventReport by another user = %v, %v; want nil, nil", got, err)
+	}
+	reported, err := repo.LoginEventReport(ctx, event.ID, ada.ID)
+	if err != nil || reported == nil || !reported.Revoked() {
+		t.Fatalf("LoginEventReport = %+v, %v", reported, err)
+	}
+	again, err := repo.LoginEventReport(ctx, event.ID, ada.ID)
+	if err != nil || again == nil || !again.ReportedAt.Equal(*reported.ReportedAt) {
+		t.Fatalf("second LoginEventReport = %+v, %v; want the first time kept", again, err)
+	}
+	if got, _ := repo.LoginEventByID(ctx, event.ID); got == nil || !got.Revoked() {
+		t.Errorf("LoginEventByID after report = %+v", got)
+	}
+	if got, err := repo.LoginEventReport(ctx, "missing", ada.ID); got != nil || err != nil {
+		t.Errorf("LoginEventReport(missing) = %v, %v; want nil, nil", got, err)
+	}
+}
+
+func testLoginEventsDeletedWithUser(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	user := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	event := mustLogin(t, repo, user.ID, model.LoginOutcomeSuccess, "fp-laptop")
+
+	if err := repo.UserDelete(ctx, user.Email); err != nil {
+		t.Fatalf("UserDelete: %v", err)
+	}
+	if got, err := repo.LoginEventByID(ctx, event.ID); got != nil || err != nil {
+		t.Fatalf("LoginEventByID after UserDelete = %v, %v; want nil, nil", got, err)
+	}
+}
@@ -0,0 +1,129 @@
+package repostest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+)
+
+func runMagicLinks(t *testing.T, newRepo func(t *testing.T) repos.Repository) {
+	t.Run("MagicLinkConsumeOnce", func(t *testing.T) { testMagicLinkConsumeOnce(t, newRepo(t)) })
+	t.Run("MagicLinkConsumeExpired", func(t *testing.T) { testMagicLinkConsumeExpired(t, newRepo(t)) })
+	t.Run("MagicLinkConsumeConcurrent", func(t *testing.T) { testMagicLinkConsumeConcurrent(t, newRepo(t)) })
+	t.Run("MagicLinkCountSince", func(t *testing.T) { testMagicLinkCountSince(t, newRepo(t)) })
+	t.Run("MagicLinksDeletedWithUser", func(t *testing.T) { testMagicLinksDeletedWithUser(t, newRepo(t)) })
+}
+
+func mustCreateMagicLink(t *testing.T, repo repos.Repository, userID, tokenHash string, expiresAt time.Time) *model.MagicLinkModel {
+	t.Helper()
+	link, err := repo.MagicLinkCreation(context.Background(), &model.NewMagicLinkModel{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		NonceHash: "nonce-" + tokenHash,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("MagicLinkCreation: %v", err)
+	}
+	return link
+}
+
+func testMagicLinkConsumeOnce(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	user := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	created := mustCreateMagicLink(t, repo, user.ID, "hash-1", time.Now().Add(10*time.Minute))
+	if created.ID == "" || created.UsedAt != nil {
+		t.Fatalf("MagicLinkCreation = %+v", created)
+	}
+
+	link, err := repo.MagicLinkConsume(ctx, "hash-1")
+	if err != nil || link == nil {
+		t.Fatalf("MagicLinkConsume = %v, %v", link, err)
+	}
+	if link.ID != created.ID || link.UserID != user.ID || link.NonceHash != "nonce-hash-1" || link.UsedAt == nil {
+		t.Errorf("consumed link = %+v", link)
+	}
+
+	if again, err := repo.MagicLinkConsume(ctx, "hash-1"); again != nil || err != nil {
+		t.Errorf("second MagicLinkConsume = %v, %v; want nil, nil", again, err)
+	}
+	if missing, err := repo.MagicLinkConsume(ctx, "missing"); missing != nil || err != nil {
+		t.Errorf("MagicLinkConsume(missing) = %v, %v; want nil, nil", missing, err)
+	}
+	if _, err := repo.MagicLinkCreation(ctx, nil); err == nil {
+		t.Error("MagicLinkCreation(nil) succeeded")
+	}
+}
+
+func testMagicLinkConsumeExpired(t *testing.T, repo repos.Repository) {
+	user := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	mustCreateMagicLink(t, repo, user.ID, "hash-1", time.Now().Add(-time.Second))
+
+	if link, err := repo.MagicLinkConsume(context.Background(), "hash-1"); link != nil || err != nil {
+		t.Errorf("MagicLinkConsume(expired) = %v, %v; want nil, nil", link, err)
+	}
+}
+
+func testMagicLinkConsumeConcurrent(t *testing.T, repo repos.Repository) {
+	user := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	mustCreateMagicLink(t, repo, user.ID, "hash-1", time.Now().Add(10*time.Minute))
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		won int
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			link, err := repo.MagicLinkConsume(context.Background(), "hash-1")
+			if err != nil {
+				t.Errorf("MagicLinkConsume: %v", err)
+			}
+			if link != nil {
+				mu.Lock()
+				won++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if won != 1 {
+		t.Errorf("%d concurrent consumers got the link, want 1", won)
+	}
+}
+
+func testMagicLinkCountSince(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	ada := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	grace := MustCreate(t, repo, "grace@example.com", model.RoleUser)
+	start := time.Now().Add(-time.Second)
+	mustCreateMagicLink(t, repo, ada.ID, "hash-1", time.Now().Add(time.Minute))
+	mustCreateMagicLink(t, repo, ada.ID, "hash-2", time.Now().Add(time.Minute))
+	mustCreateMagicLink(t, repo, grace.ID, "hash-3", time.Now().Add(time.Minute))
+
+	if n, err := repo.MagicLinkCountSince(ctx, ada.ID, start); n != 2 || err != nil {
+		t.Errorf("MagicLinkCountSince(ada) = %d, %v; want 2", n, err)
+	}
+	if n, err := repo.MagicLinkCountSince(ctx, ada.ID, time.Now().Add(time.Minute)); n != 0 || err != nil {
+		t.Errorf("MagicLinkCountSince(future) = %d, %v; want 0", n, err)
+	}
+}
+
+func testMagicLinksDeletedWithUser(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	user := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	mustCreateMagicLink(t, repo, user.ID, "hash-1", time.Now().Add(10*time.Minute))
+
+	if err := repo.UserDelete(ctx, user.Email); err != nil {
+		t.Fatalf("UserDelete: %v", err)
+	}
+	if link, err := repo.MagicLinkConsume(ctx, "hash-1"); link != nil || err != nil {
+		t.Errorf("link of a deleted user still usable: %v, %v", link, err)
+	}
+}
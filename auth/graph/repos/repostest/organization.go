@@ -0,0 +1,200 @@
+package repostest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+)
+
+func runOrganizations(t *testing.T, newRepo func(t *testing.T) repos.Repository) {
+	t.Run("OrganizationCreation", func(t *testing.T) { testOrganizationCreation(t, newRepo(t)) })
+	t.Run("OrganizationCreationDuplicateSlug", func(t *testing.T) { testOrganizationCreationDuplicateSlug(t, newRepo(t)) })
+	t.Run("OrganizationNotFoundReturnsNilNil", func(t *testing.T) { testOrganizationNotFound(t, newRepo(t)) })
+	t.Run("Memberships", func(t *testing.T) { testMemberships(t, newRepo(t)) })
+	t.Run("MembershipUpdate", func(t *testing.T) { testMembershipUpdate(t, newRepo(t)) })
+	t.Run("MembershipUpdateKeepsAnOwner", func(t *testing.T) { testMembershipUpdateKeepsAnOwner(t, newRepo(t)) })
+	t.Run("UserDeleteRemovesMemberships", func(t *testing.T) { testUserDeleteRemovesMemberships(t, newRepo(t)) })
+}
+
+// MustCreateOrganization creates an organization owned by owner and fails the
+// test on error.
+func MustCreateOrganization(t *testing.T, repo repos.Repository, slug string, owner *model.UserModel) *model.OrganizationModel {
+	t.Helper()
+	org, err := repo.OrganizationCreation(context.Background(), &model.NewOrganizationModel{Name: "Org " + slug, Slug: slug}, owner.ID)
+	if err != nil {
+		t.Fatalf("OrganizationCreation(%s): %v", slug, err)
+	}
+	return org
+}
+
+func testOrganizationCreation(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	owner := MustCreate(t, repo, "owner@example.com", model.RoleUser)
+	org := MustCreateOrganization(t, repo, "acme", owner)
+
+	if org.ID == "" || org.Name != "Org acme" || org.Slug != "acme" || org.CreatedAt.IsZero() {
+		t.Errorf("unexpected organization %+v", org)
+	}
+	for name, lookup := range map[string]func() (*model.OrganizationModel, error){
+		"OrganizationByID":   func() (*model.OrganizationModel, error) { return repo.OrganizationByID(ctx, org.ID) },
+		"OrganizationBySlug": func() (*model.OrganizationModel, error) { return repo.OrganizationBySlug(ctx, "acme") },
+	} {
+		got, err := lookup()
+		if err != nil || got == nil || got.ID != org.ID {
+			t.Errorf("%s = %v, %v", name, got, err)
+		}
+	}
+
+	membership, err := repo.MembershipByUser(ctx, org.ID, owner.ID)
+	if err != nil || membership == nil {
+		t.Fatalf("MembershipByUser = %v, %v", membership, err)
+	}
+	if membership.Role != model.OrgRoleOwner {
+		t.Errorf("creator role = %s, want %s", membership.Role, model.OrgRoleOwner)
+	}
+}
+
+func testOrganizationCreationDuplicateSlug(t *testing.T, repo repos.Repository) {
+	owner := MustCreate(t, repo, "owner@example.com", model.RoleUser)
+	MustCreateOrganization(t, repo, "acme", owner)
+	_, err := repo.OrganizationCreation(context.Background(), &model.NewOrganizationModel{Name: "Other", Slug: "acme"}, owner.ID)
+	if err == nil {
+		t.Fatal("expected an error for a duplicate slug")
+	}
+}
+
+func testOrganizationNotFound(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	missing := "00000000-0000-0000-0000-000000000000"
+	if org, err := repo.OrganizationByID(ctx, missing); org != nil || err != nil {
+		t.Errorf("OrganizationByID = %v, %v; want nil, nil", org, err)
+	}
+	if org, err := repo.OrganizationBySlug(ctx, "missing"); org != nil || err != nil {
+		t.Errorf("OrganizationBySlug = %v, %v; want nil, nil", org, err)
+	}
+	if m, err := repo.MembershipByUser(ctx, missing, missing); m != nil || err != nil {
+		t.Errorf("MembershipByUser = %v, %v; want nil, nil", m, err)
+	}
+	if m, err := repo.MembershipUpdate(ctx, missing, missing, model.OrgRoleAdmin); m != nil || err != nil {
+		t.Errorf("MembershipUpdate = %v, %v; want nil, nil", m, err)
+	}
+}
+
+func testMemberships(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	owner := MustCreate(t, repo, "owner@example.com", model.RoleUser)
+	member := MustCreate(t, repo, "member@example.com", model.RoleUser)
+	acme := MustCreateOrganization(t, repo, "acme", owner)
+	MustCreateOrganization(t, repo, "globex", owner)
+
+	if _, err := repo.MembershipCreation(ctx, acme.ID, member.ID, model.OrgRoleMember); err != nil {
+		t.Fatalf("MembershipCreation: %v", err)
+	}
+	if _, err := repo.MembershipCreation(ctx, acme.ID, member.ID, model.OrgRoleAdmin); err == nil {
+		t.Error("expected an error for a duplicate membership")
+	}
+
+	members, err := repo.MembershipsByOrganization(ctx, acme.ID)
+	if err != nil {
+		t.Fatalf("MembershipsByOrganization: %v", err)
+	}
+	roles := map[string]string{}
+	for _, m := range members {
+		roles[m.UserID] = m.Role
+	}
+	if len(members) != 2 || roles[owner.ID] != model.OrgRoleOwner || roles[member.ID] != model.OrgRoleMember {
+		t.Errorf("MembershipsByOrganization = %v", roles)
+	}
+
+	ownerOrgs, err := repo.MembershipsByUser(ctx, owner.ID)
+	if err != nil || len(ownerOrgs) != 2 {
+		t.Errorf("MembershipsByUser(owner) = %d memberships, %v; want 2", len(ownerOrgs), err)
+	}
+	memberOrgs, err := repo.MembershipsByUser(ctx, member.ID)
+	if err != nil || len(memberOrgs) != 1 || memberOrgs[0].OrganizationID != acme.ID {
+		t.Errorf("MembershipsByUser(member) = %v, %v", memberOrgs, err)
+	}
+}
+
+func testMembershipUpdate(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	owner := MustCreate(t, repo, "owner@example.com", model.RoleUser)
+	member := MustCreate(t, repo, "member@example.com", model.RoleUser)
+	org := MustCreateOrganization(t, repo, "acme", owner)
+	if _, err := repo.MembershipCreation(ctx, org.ID, member.ID, model.OrgRoleMember); err != nil {
+		t.Fatalf("MembershipCreation: %v", err)
+	}
+
+	updated, err := repo.MembershipUpdate(ctx, org.ID, member.ID, model.OrgRoleAdmin)
+	if err != nil || updated == nil || updated.Role != model.OrgRoleAdmin {
+		t.Fatalf("MembershipUpdate = %v, %v", updated, err)
+	}
+	got, err := repo.MembershipByUser(ctx, org.ID, member.ID)
+	if err != nil || got == nil || got.Role != model.OrgRoleAdmin {
+		t.Errorf("MembershipByUser after update = %v, %v", got, err)
+	}
+}
+
+func testMembershipUpdateKeepsAnOwner(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	owner := MustCreate(t, repo, "owner@example.com", model.RoleUser)
+	second := MustCreate(t, repo, "second@example.com", model.RoleUser)
+	org := MustCreateOrganization(t, repo, "acme", owner)
+
+	if m, err := repo.MembershipUpdate(ctx, org.ID, owner.ID, model.OrgRoleAdmin); m != nil || apperr.CodeOf(err) != apperr.CodeConflict {
+		t.Fatalf("demoting the only owner = %v, %v; want a conflict", m, err)
+	}
+	if _, err := repo.MembershipCreation(ctx, org.ID, second.ID, model.OrgRoleOwner); err != nil {
+		t.Fatalf("MembershipCreation: %v", err)
+	}
+
+	// Two owners demoting each other at once: one of them must stay.
+	var wg sync.WaitGroup
+	for _, id := range []string{owner.ID, second.ID} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repo.MembershipUpdate(ctx, org.ID, id, model.OrgRoleMember)
+		}()
+	}
+	wg.Wait()
+
+	members, err := repo.MembershipsByOrganization(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("MembershipsByOrganization: %v", err)
+	}
+	owners := 0
+	for _, m := range members {
+		if m.Role == model.OrgRoleOwner {
+			owners++
+		}
+	}
+	if owners != 1 {
+		t.Errorf("%d owners after concurrent demotions; want 1", owners)
+	}
+}
+
+func testUserDeleteRemovesMemberships(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	owner := MustCreate(t, repo, "owner@example.com", model.RoleUser)
+	member := MustCreate(t, repo, "member@example.com", model.RoleUser)
+	org := MustCreateOrganization(t, repo, "acme", owner)
+	if _, err := repo.MembershipCreation(ctx, org.ID, member.ID, model.OrgRoleMember); err != nil {
+		t.Fatalf("MembershipCreation: %v", err)
+	}
+
+	if err := repo.UserDelete(ctx, member.Email); err != nil {
+		t.Fatalf("UserDelete: %v", err)
+	}
+	if m, err := repo.MembershipByUser(ctx, org.ID, member.ID); m != nil || err != nil {
+		t.Errorf("membership of deleted user = %v, %v; want nil, nil", m, err)
+	}
+	members, err := repo.MembershipsByOrganization(ctx, org.ID)
+	if err != nil || len(members) != 1 {
+		t.Errorf("MembershipsByOrganization = %d memberships, %v; want 1", len(members), err)
+	}
+}
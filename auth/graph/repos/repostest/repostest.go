@@ -0,0 +1,367 @@
+// Package repostest is the contract suite every repos.Repository backend must
+// pass. Backends call Run from their own tests.
+package repostest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+)
+
+// Run exercises newRepo against the shared contract. newRepo must return an
+// empty repository for every call.
+func Run(t *testing.T, newRepo func(t *testing.T) repos.Repository) {
+	t.Run("UserCreation", func(t *testing.T) { testUserCreation(t, newRepo(t)) })
+	t.Run("UserCreationDuplicateEmail", func(t *testing.T) { testUserCreationDuplicateEmail(t, newRepo(t)) })
+	t.Run("UserCreationConcurrentDuplicates", func(t *testing.T) { testUserCreationConcurrentDuplicates(t, newRepo(t)) })
+	t.Run("UserCreationNilInput", func(t *testing.T) { testUserCreationNilInput(t, newRepo(t)) })
+	t.Run("NotFoundReturnsNilNil", func(t *testing.T) { testNotFound(t, newRepo(t)) })
+	t.Run("UserByRole", func(t *testing.T) { testUserByRole(t, newRepo(t)) })
+	t.Run("UserByIDs", func(t *testing.T) { testUserByIDs(t, newRepo(t)) })
+	t.Run("UserUpdate", func(t *testing.T) { testUserUpdate(t, newRepo(t)) })
+	t.Run("UserUpdateChangesEmail", func(t *testing.T) { testUserUpdateChangesEmail(t, newRepo(t)) })
+	t.Run("UserDelete", func(t *testing.T) { testUserDelete(t, newRepo(t)) })
+	t.Run("UserAvatarUpdate", func(t *testing.T) { testUserAvatarUpdate(t, newRepo(t)) })
+	t.Run("UserStatusUpdate", func(t *testing.T) { testUserStatusUpdate(t, newRepo(t)) })
+	t.Run("UserEmailNormalization", func(t *testing.T) { testUserEmailNormalization(t, newRepo(t)) })
+	runOrganizations(t, newRepo)
+	runInvitations(t, newRepo)
+	runAddresses(t, newRepo)
+	runAudit(t, newRepo)
+	runMagicLinks(t, newRepo)
+	runLoginEvents(t, newRepo)
+	runSigningKeys(t, newRepo)
+}
+
+// NewUser returns valid input for UserCreation.
+func NewUser(email, role string) *model.NewUserModel {
+	return &model.NewUserModel{
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Email:     email,
+		Password:  "hashed-password",
+		Role:      role,
+	}
+}
+
+// MustCreate creates a user and fails the test on error.
+func MustCreate(t *testing.T, repo repos.Repository, email, role string) *model.UserModel {
+	t.Helper()
+	user, err := repo.UserCreation(context.Background(), NewUser(email, role))
+	if err != nil {
+		t.Fatalf("UserCreation(%s): %v", email, err)
+	}
+	return user
+}
+
+func testUserCreation(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Second)
+	created := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+
+	if created.ID == "" {
+		t.Fatal("created user has no ID")
+	}
+	if created.CreatedAt.Before(before) || created.UpdatedAt.Before(before) {
+		t.Errorf("timestamps not set: created %v, updated %v", created.CreatedAt, created.UpdatedAt)
+	}
+
+	byID, err := repo.UserByID(ctx, created.ID)
+	if err != nil || byID == nil {
+		t.Fatalf("UserByID = %v, %v", byID, err)
+	}
+	byEmail, err := repo.UserByEmail(ctx, "ada@example.com")
+	if err != nil || byEmail == nil {
+		t.Fatalf("UserByEmail = %v, %v", byEmail, err)
+	}
+
+	for _, got := range []*model.UserModel{created, byID, byEmail} {
+		if got.ID != created.ID || got.FirstName != "Ada" || got.LastName != "Lovelace" ||
+			got.Email != "ada@example.com" || got.Password != "hashed-password" || got.Role != model.RoleUser {
+			t.Errorf("unexpected user %+v", got)
+		}
+	}
+}
+
+func testUserCreationDuplicateEmail(t *testing.T, repo repos.Repository) {
+	MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	if _, err := repo.UserCreation(context.Background(), NewUser("ada@example.com", model.RoleAdmin)); err == nil {
+		t.Fatal("expected an error for a duplicate email")
+	}
+}
+
+func testUserCreationConcurrentDuplicates(t *testing.T, repo repos.Repository) {
+	// Registrations racing for one email: one wins, the others conflict.
+	const n = 8
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = repo.UserCreation(context.Background(), NewUser("ada@example.com", model.RoleUser))
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case apperr.CodeOf(err) != apperr.CodeConflict:
+			t.Errorf("UserCreation = %v; want a conflict", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d users created; want 1", created)
+	}
+}
+
+func testUserCreationNilInput(t *testing.T, repo repos.Repository) {
+	if _, err := repo.UserCreation(context.Background(), nil); err == nil {
+		t.Fatal("expected an error for nil input")
+	}
+}
+
+func testNotFound(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	if user, err := repo.UserByID(ctx, "00000000-0000-0000-0000-000000000000"); user != nil || err != nil {
+		t.Errorf("UserByID = %v, %v; want nil, nil", user, err)
+	}
+	if user, err := repo.UserByEmail(ctx, "nobody@example.com"); user != nil || err != nil {
+		t.Errorf("UserByEmail = %v, %v; want nil, nil", user, err)
+	}
+	if user, err := repo.UserUpdate(ctx, "nobody@example.com", NewUser("nobody@example.com", model.RoleUser)); user != nil || err != nil {
+		t.Errorf("UserUpdate = %v, %v; want nil, nil", user, err)
+	}
+}
+
+func testUserByRole(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	MustCreate(t, repo, "user1@example.com", model.RoleUser)
+	MustCreate(t, repo, "user2@example.com", model.RoleUser)
+	MustCreate(t, repo, "admin@example.com", model.RoleAdmin)
+
+	users, err := repo.UserByRole(ctx, model.RoleUser)
+	if err != nil {
+		t.Fatalf("UserByRole: %v", err)
+	}
+	emails := map[string]bool{}
+	for _, u := range users {
+		emails[u.Email] = true
+	}
+	if len(users) != 2 || !emails["user1@example.com"] || !emails["user2@example.com"] {
+		t.Errorf("UserByRole(USER) = %v", emails)
+	}
+
+	none, err := repo.UserByRole(ctx, "MISSING")
+	if err != nil || len(none) != 0 {
+		t.Errorf("UserByRole(MISSING) = %v, %v; want empty, nil", none, err)
+	}
+}
+
+func testUserByIDs(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	ada := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	bob := MustCreate(t, repo, "bob@example.com", model.RoleAdmin)
+	MustCreate(t, repo, "carol@example.com", model.RoleUser)
+
+	users, err := repo.UserByIDs(ctx, []string{bob.ID, "missing", ada.ID, bob.ID})
+	if err != nil {
+		t.Fatalf("UserByIDs: %v", err)
+	}
+	emails := map[string]bool{}
+	for _, u := range users {
+		emails[u.Email] = true
+	}
+	if len(users) != 2 || !emails["ada@example.com"] || !emails["bob@example.com"] {
+		t.Errorf("UserByIDs = %v", emails)
+	}
+
+	none, err := repo.UserByIDs(ctx, nil)
+	if err != nil || len(none) != 0 {
+		t.Errorf("UserByIDs(nil) = %v, %v; want empty, nil", none, err)
+	}
+}
+
+func testUserUpdate(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	created := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+
+	updated, err := repo.UserUpdate(ctx, "ada@example.com", &model.NewUserModel{
+		FirstName: "Augusta",
+		LastName:  "King",
+		Email:     "ada@example.com",
+		Password:  "new-hash",
+		Role:      model.RoleAdmin,
+	})
+	if err != nil || updated == nil {
+		t.Fatalf("UserUpdate = %v, %v", updated, err)
+	}
+
+	got, err := repo.UserByID(ctx, created.ID)
+	if err != nil || got == nil {
+		t.Fatalf("UserByID = %v, %v", got, err)
+	}
+	for _, u := range []*model.UserModel{updated, got} {
+		if u.FirstName != "Augusta" || u.LastName != "King" || u.Password != "new-hash" || u.Role != model.RoleAdmin {
+			t.Errorf("update not applied: %+v", u)
+		}
+		if u.ID != created.ID || u.Email != created.Email {
+			t.Errorf("update changed identity: %+v", u)
+		}
+	}
+	if got.UpdatedAt.Before(created.UpdatedAt) {
+		t.Errorf("UpdatedAt went backwards: %v < %v", got.UpdatedAt, created.UpdatedAt)
+	}
+}
+
+func testUserAvatarUpdate(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	created := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	if created.AvatarKey != "" {
+		t.Fatalf("new user has avatar key %q", created.AvatarKey)
+	}
+
+	updated, err := repo.UserAvatarUpdate(ctx, created.ID, "avatars/"+created.ID+"/v1")
+	if err != nil || updated == nil {
+		t.Fatalf("UserAvatarUpdate = %v, %v", updated, err)
+	}
+	got, err := repo.UserByID(ctx, created.ID)
+	if err != nil || got == nil {
+		t.Fatalf("UserByID = %v, %v", got, err)
+	}
+	for _, u := range []*model.UserModel{updated, got} {
+		if u.AvatarKey != "avatars/"+created.ID+"/v1" {
+			t.Errorf("AvatarKey = %q", u.AvatarKey)
+		}
+		if u.FirstName != created.FirstName || u.Password != created.Password {
+			t.Errorf("avatar update changed other fields: %+v", u)
+		}
+	}
+
+	if user, err := repo.UserAvatarUpdate(ctx, "missing", "avatars/x"); user != nil || err != nil {
+		t.Errorf("UserAvatarUpdate(missing) = %v, %v; want nil, nil", user, err)
+	}
+}
+
+func testUserStatusUpdate(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	created := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	if created.Status != model.StatusActive || created.StatusUntil != nil {
+		t.Fatalf("new user status = %q until %v; want ACTIVE", created.Status, created.StatusUntil)
+	}
+
+	until := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	updated, err := repo.UserStatusUpdate(ctx, created.ID, model.StatusSuspended, "chargebacks", &until)
+	if err != nil || updated == nil {
+		t.Fatalf("UserStatusUpdate = %v, %v", updated, err)
+	}
+	got, err := repo.UserByID(ctx, created.ID)
+	if err != nil || got == nil {
+		t.Fatalf("UserByID = %v, %v", got, err)
+	}
+	for _, u := range []*model.UserModel{updated, got} {
+		if u.Status != model.StatusSuspended || u.StatusReason != "chargebacks" || u.StatusUntil == nil || !u.StatusUntil.Equal(until) {
+			t.Errorf("status not applied: %q %q %v", u.Status, u.StatusReason, u.StatusUntil)
+		}
+	}
+
+	reactivated, err := repo.UserStatusUpdate(ctx, created.ID, model.StatusActive, "", nil)
+	if err != nil || reactivated == nil {
+		t.Fatalf("UserStatusUpdate(ACTIVE) = %v, %v", reactivated, err)
+	}
+	if got, _ := repo.UserByID(ctx, created.ID); got.Status != model.StatusActive || got.StatusReason != "" || got.StatusUntil != nil {
+		t.Errorf("reactivation left %q %q %v", got.Status, got.StatusReason, got.StatusUntil)
+	}
+
+	if user, err := repo.UserStatusUpdate(ctx, "missing", model.StatusDisabled, "", nil); user != nil || err != nil {
+		t.Errorf("UserStatusUpdate(missing) = %v, %v; want nil, nil", user, err)
+	}
+}
+
+func testUserEmailNormalization(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	created := MustCreate(t, repo, " Ada@Bücher.DE ", model.RoleUser)
+	if created.Email != "Ada@Bücher.DE" || created.EmailNormalized != "ada@xn--bcher-kva.de" {
+		t.Fatalf("created email = %q, normalized %q", created.Email, created.EmailNormalized)
+	}
+
+	for _, email := range []string{"ada@bücher.de", "ADA@xn--bcher-kva.de", "  ada@BÜCHER.de"} {
+		got, err := repo.UserByEmail(ctx, email)
+		if err != nil || got == nil || got.ID != created.ID {
+			t.Errorf("UserByEmail(%q) = %v, %v", email, got, err)
+		}
+		if _, err := repo.UserCreation(ctx, NewUser(email, model.RoleUser)); err == nil {
+			t.Errorf("UserCreation(%q) succeeded for an existing address", email)
+		}
+	}
+
+	updated, err := repo.UserUpdate(ctx, "ADA@bücher.de", &model.NewUserModel{FirstName: "Augusta", Role: model.RoleUser})
+	if err != nil || updated == nil || updated.FirstName != "Augusta" {
+		t.Fatalf("UserUpdate by another spelling = %v, %v", updated, err)
+	}
+	if err := repo.UserDelete(ctx, "ada@XN--BCHER-KVA.DE"); err != nil {
+		t.Fatalf("UserDelete: %v", err)
+	}
+	if got, _ := repo.UserByID(ctx, created.ID); got != nil {
+		t.Error("UserDelete by another spelling kept the user")
+	}
+}
+
+func testUserUpdateChangesEmail(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	created := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	other := MustCreate(t, repo, "grace@example.com", model.RoleUser)
+
+	input := NewUser(" Augusta@Example.com ", model.RoleUser)
+	updated, err := repo.UserUpdate(ctx, "ada@example.com", input)
+	if err != nil || updated == nil {
+		t.Fatalf("UserUpdate = %v, %v", updated, err)
+	}
+	if updated.Email != "Augusta@Example.com" || updated.EmailNormalized != "augusta@example.com" {
+		t.Errorf("email = %q, normalized %q", updated.Email, updated.EmailNormalized)
+	}
+	if got, err := repo.UserByEmail(ctx, "augusta@example.com"); err != nil || got == nil || got.ID != created.ID {
+		t.Errorf("UserByEmail(new) = %v, %v", got, err)
+	}
+	if got, err := repo.UserByEmail(ctx, "ada@example.com"); got != nil || err != nil {
+		t.Errorf("UserByEmail(old) = %v, %v; want nil, nil", got, err)
+	}
+
+	// Another spelling of a taken address is still taken.
+	if u, err := repo.UserUpdate(ctx, "augusta@example.com", NewUser("GRACE@example.com", model.RoleUser)); u != nil || apperr.CodeOf(err) != apperr.CodeConflict {
+		t.Fatalf("UserUpdate to a taken email = %v, %v; want a conflict", u, err)
+	}
+	if got, _ := repo.UserByEmail(ctx, "grace@example.com"); got == nil || got.ID != other.ID {
+		t.Errorf("UserByEmail(grace) = %v; want the other user", got)
+	}
+	if got, _ := repo.UserByID(ctx, created.ID); got == nil || got.EmailNormalized != "augusta@example.com" {
+		t.Errorf("failed update changed the user: %+v", got)
+	}
+}
+
+func testUserDelete(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	created := MustCreate(t, repo, "ada@example.com", model.RoleUser)
+	MustCreate(t, repo, "grace@example.com", model.RoleUser)
+
+	if err := repo.UserDelete(ctx, "ada@example.com"); err != nil {
+		t.Fatalf("UserDelete: %v", err)
+	}
+	if user, err := repo.UserByID(ctx, created.ID); user != nil || err != nil {
+		t.Errorf("deleted user still found: %v, %v", user, err)
+	}
+	if user, _ := repo.UserByEmail(ctx, "grace@example.com"); user == nil {
+		t.Error("UserDelete removed another user")
+	}
+	if err := repo.UserDelete(ctx, "nobody@example.com"); err != nil {
+		t.Errorf("deleting a missing user: %v", err)
+	}
+}
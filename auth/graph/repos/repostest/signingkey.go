@@ -0,0 +1,89 @@
+package repostest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+)
+
+func runSigningKeys(t *testing.T, newRepo func(t *testing.T) repos.Repository) {
+	t.Run("SigningKeyRotation", func(t *testing.T) { testSigningKeyRotation(t, newRepo(t)) })
+	t.Run("SigningKeyRotationDropsExpired", func(t *testing.T) { testSigningKeyRotationDropsExpired(t, newRepo(t)) })
+}
+
+func mustRotate(t *testing.T, repo repos.Repository, id string, activeFrom, retireAt time.Time) *model.SigningKeyModel {
+	t.Helper()
+	key, err := repo.SigningKeyRotation(context.Background(), &model.NewSigningKeyModel{
+		ID:         id,
+		Secret:     "secret-" + id,
+		ActiveFrom: activeFrom,
+	}, retireAt)
+	if err != nil {
+		t.Fatalf("SigningKeyRotation(%s): %v", id, err)
+	}
+	return key
+}
+
+func testSigningKeyRotation(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	if keys, err := repo.SigningKeys(ctx); err != nil || len(keys) != 0 {
+		t.Fatalf("SigningKeys on empty repository = %v, %v", keys, err)
+	}
+
+	now := time.Now()
+	first := mustRotate(t, repo, "k1", now, now.Add(time.Hour))
+	if first.ID != "k1" || first.Secret != "secret-k1" || first.ExpiresAt != nil {
+		t.Fatalf("SigningKeyRotation = %+v", first)
+	}
+	retireAt := now.Add(2 * time.Hour)
+	mustRotate(t, repo, "k2", now.Add(time.Minute), retireAt)
+	mustRotate(t, repo, "k3", now.Add(2*time.Minute), now.Add(3*time.Hour))
+
+	keys, err := repo.SigningKeys(ctx)
+	if err != nil || len(keys) != 3 {
+		t.Fatalf("SigningKeys = %v, %v; want 3 keys", keys, err)
+	}
+	for i, id := range []string{"k1", "k2", "k3"} {
+		if keys[i].ID != id {
+			t.Errorf("keys[%d] = %s, want %s", i, keys[i].ID, id)
+		}
+	}
+	// A key keeps the expiry set by the rotation that replaced it.
+	if keys[0].ExpiresAt == nil || keys[0].ExpiresAt.Sub(retireAt).Abs() > time.Millisecond {
+		t.Errorf("k1 expires at %v, want %v", keys[0].ExpiresAt, retireAt)
+	}
+	if keys[1].ExpiresAt == nil || keys[2].ExpiresAt != nil {
+		t.Errorf("expiries = %v, %v; want k2 set and k3 unset", keys[1].ExpiresAt, keys[2].ExpiresAt)
+	}
+
+	if _, err := repo.SigningKeyRotation(ctx, &model.NewSigningKeyModel{ID: "k3", Secret: "x", ActiveFrom: now}, now.Add(time.Hour)); err == nil {
+		t.Error("SigningKeyRotation with a duplicate id succeeded")
+	}
+	if _, err := repo.SigningKeyRotation(ctx, nil, now); err == nil {
+		t.Error("SigningKeyRotation(nil) succeeded")
+	}
+}
+
+func testSigningKeyRotationDropsExpired(t *testing.T, repo repos.Repository) {
+	ctx := context.Background()
+	now := time.Now()
+	mustRotate(t, repo, "old", now.Add(-time.Hour), now)
+	// Retiring "old" at once makes the next rotation delete it.
+	mustRotate(t, repo, "current", now, now.Add(-time.Second))
+	mustRotate(t, repo, "next", now.Add(time.Minute), now.Add(time.Hour))
+
+	keys, err := repo.SigningKeys(ctx)
+	if err != nil {
+		t.Fatalf("SigningKeys: %v", err)
+	}
+	var ids []string
+	for _, key := range keys {
+		ids = append(ids, key.ID)
+	}
+	if len(ids) != 2 || ids[0] != "current" || ids[1] != "next" {
+		t.Errorf("SigningKeys = %v, want [current next]", ids)
+	}
+}
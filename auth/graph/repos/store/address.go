@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"gorm.io/gorm"
+)
+
+// AddressCreation implements repos.Repository.
+func (s *Store) AddressCreation(ctx context.Context, userID string, input *model.NewAddressModel) (*model.AddressModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	now := time.Now()
+	address := model.AddressModel{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		CreatedAt: now,
+	}
+	applyAddress(&address, input, now)
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaults(tx, &address); err != nil {
+			return err
+		}
+		return tx.Create(&address).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create address: %w", err)
+	}
+	return &address, nil
+}
+
+// AddressByID implements repos.Repository.
+func (s *Store) AddressByID(ctx context.Context, id string) (*model.AddressModel, error) {
+	var address model.AddressModel
+	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&address).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // Address not found
+		}
+		return nil, fmt.Errorf("failed to fetch address by id: %w", err)
+	}
+	return &address, nil
+}
+
+// AddressesByUser implements repos.Repository.
+func (s *Store) AddressesByUser(ctx context.Context, userID string) ([]*model.AddressModel, error) {
+	var addresses []*model.AddressModel
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&addresses).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch addresses by user: %w", err)
+	}
+	return addresses, nil
+}
+
+// AddressUpdate implements repos.Repository.
+func (s *Store) AddressUpdate(ctx context.Context, id string, input *model.NewAddressModel) (*model.AddressModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	var address model.AddressModel
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).First(&address).Error; err != nil {
+			return err
+		}
+		applyAddress(&address, input, time.Now())
+		if err := clearDefaults(tx, &address); err != nil {
+			return err
+		}
+		return tx.Save(&address).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // Address not found
+		}
+		return nil, fmt.Errorf("failed to update address: %w", err)
+	}
+	return &address, nil
+}
+
+// AddressDelete implements repos.Repository.
+func (s *Store) AddressDelete(ctx context.Context, id string) error {
+	if err := s.db.WithContext(ctx).Where("id = ?", id).Delete(&model.AddressModel{}).Error; err != nil {
+		return fmt.Errorf("failed to delete address: %w", err)
+	}
+	return nil
+}
+
+func applyAddress(address *model.AddressModel, input *model.NewAddressModel, now time.Time) {
+	address.FullName = input.FullName
+	address.Street = input.Street
+	address.City = input.City
+	address.State = input.State
+	address.PostalCode = input.PostalCode
+	address.Country = input.Country
+	address.Phone = input.Phone
+	address.IsDefaultShipping = input.IsDefaultShipping
+	address.IsDefaultBilling = input.IsDefaultBilling
+	address.UpdatedAt = now
+}
+
+// clearDefaults unsets the default flags that address takes over on the
+// user's other addresses, before the unique default indexes see two.
+func clearDefaults(tx *gorm.DB, address *model.AddressModel) error {
+	others := tx.Model(&model.AddressModel{}).Where("user_id = ? AND id <> ?", address.UserID, address.ID)
+	if address.IsDefaultShipping {
+		if err := others.Session(&gorm.Session{}).Update("is_default_shipping", false).Error; err != nil {
+			return err
+		}
+	}
+	if address.IsDefaultBilling {
+		if err := others.Session(&gorm.Session{}).Update("is_default_billing", false).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
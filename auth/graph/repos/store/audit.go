@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// AuditLogCreation implements repos.Repository.
+func (s *Store) AuditLogCreation(ctx context.Context, input *model.NewAuditLogModel) (*model.AuditLogModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	entry := model.AuditLogModel{
+		ID:        uuid.NewString(),
+		ActorID:   input.ActorID,
+		SubjectID: input.SubjectID,
+		Action:    input.Action,
+		Operation: input.Operation,
+		Detail:    input.Detail,
+		RequestID: input.RequestID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.db.WithContext(ctx).Create(&entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to create audit log entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// AuditLogs implements repos.Repository.
+func (s *Store) AuditLogs(ctx context.Context, filter model.AuditLogFilter) ([]*model.AuditLogModel, error) {
+	query := s.db.WithContext(ctx).Order("created_at DESC")
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.SubjectID != "" {
+		query = query.Where("subject_id = ?", filter.SubjectID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var entries []*model.AuditLogModel
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch audit log: %w", err)
+	}
+	return entries, nil
+}
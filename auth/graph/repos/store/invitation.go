@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"gorm.io/gorm"
+)
+
+// openInvitation matches invitations that have not been accepted or revoked.
+const openInvitation = "accepted_at IS NULL AND revoked_at IS NULL"
+
+// InvitationCreation implements repos.Repository.
+func (s *Store) InvitationCreation(ctx context.Context, input *model.NewInvitationModel) (*model.InvitationModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	now := time.Now()
+	invitation := model.InvitationModel{
+		ID:             uuid.NewString(),
+		OrganizationID: input.OrganizationID,
+		Email:          input.Email,
+		Role:           input.Role,
+		InvitedBy:      input.InvitedBy,
+		ExpiresAt:      input.ExpiresAt,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.db.WithContext(ctx).Create(&invitation).Error; err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+	return &invitation, nil
+}
+
+// InvitationByID implements repos.Repository.
+func (s *Store) InvitationByID(ctx context.Context, id string) (*model.InvitationModel, error) {
+	var invitation model.InvitationModel
+	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&invitation).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // Invitation not found
+		}
+		return nil, fmt.Errorf("failed to fetch invitation by id: %w", err)
+	}
+	return &invitation, nil
+}
+
+// InvitationsByOrganization implements repos.Repository.
+func (s *Store) InvitationsByOrganization(ctx context.Context, organizationID string) ([]*model.InvitationModel, error) {
+	var invitations []*model.InvitationModel
+	err := s.db.WithContext(ctx).
+		Where("organization_id = ?", organizationID).
+		Order("created_at").
+		Find(&invitations).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch invitations by organization: %w", err)
+	}
+	return invitations, nil
+}
+
+// InvitationRevoke implements repos.Repository.
+func (s *Store) InvitationRevoke(ctx context.Context, id string) (*model.InvitationModel, error) {
+	now := time.Now()
+	result := s.db.WithContext(ctx).Model(&model.InvitationModel{}).
+		Where("id = ? AND "+openInvitation, id).
+		Updates(map[string]interface{}{"revoked_at": now, "updated_at": now})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to revoke invitation: %w", result.Error)
+	}
+
+	invitation, err := s.InvitationByID(ctx, id)
+	if err != nil || invitation == nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperr.Conflict("invitation is no longer pending")
+	}
+	return invitation, nil
+}
+
+// InvitationAccept implements repos.Repository.
+func (s *Store) InvitationAccept(ctx context.Context, id, userID string) (*model.MembershipModel, error) {
+	var membership *model.MembershipModel
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) (err error) {
+		membership, err = acceptInvitation(tx, id, userID)
+		return err
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // Invitation not found
+		}
+		return nil, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+	return membership, nil
+}
+
+// InvitationSignUp implements repos.Repository.
+func (s *Store) InvitationSignUp(ctx context.Context, id string, input *model.NewUserModel) (*model.UserModel, *model.MembershipModel, error) {
+	if input == nil {
+		return nil, nil, fmt.Errorf("input parameter is nil")
+	}
+	user := newUser(input)
+	var membership *model.MembershipModel
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) (err error) {
+		var count int64
+		if err := tx.Model(&model.UserModel{}).Where("email_normalized = ?", user.EmailNormalized).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return apperr.Conflict("user with email %s already exists", input.Email)
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		membership, err = acceptInvitation(tx, id, user.ID)
+		return err
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, nil // Invitation not found
+		}
+		if s.duplicateKey(err) {
+			return nil, nil, apperr.Conflict("user with email %s already exists", input.Email)
+		}
+		return nil, nil, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+	return user, membership, nil
+}
+
+// acceptInvitation closes the pending invitation id and makes userID a
+// member within tx. A missing invitation is gorm.ErrRecordNotFound.
+func acceptInvitation(tx *gorm.DB, id, userID string) (*model.MembershipModel, error) {
+	var invitation model.InvitationModel
+	if err := tx.Where("id = ?", id).First(&invitation).Error; err != nil {
+		return nil, err
+	}
+
+	// The conditional update settles concurrent accepts of the same
+	// invitation: only one of them changes the row.
+	now := time.Now()
+	result := tx.Model(&model.InvitationModel{}).
+		Where("id = ? AND "+openInvitation+" AND expires_at > ?", id, now).
+		Updates(map[string]interface{}{"accepted_at": now, "updated_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperr.Conflict("invitation is no longer pending")
+	}
+
+	var count int64
+	if err := tx.Model(&model.MembershipModel{}).
+		Where("organization_id = ? AND user_id = ?", invitation.OrganizationID, userID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, apperr.Conflict("user %s is already a member of organization %s", userID, invitation.OrganizationID)
+	}
+
+	membership := &model.MembershipModel{
+		ID:             uuid.NewString(),
+		OrganizationID: invitation.OrganizationID,
+		UserID:         userID,
+		Role:           invitation.Role,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := tx.Create(membership).Error; err != nil {
+		return nil, err
+	}
+	return membership, nil
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"gorm.io/gorm"
+)
+
+// LoginEventCreation implements repos.Repository.
+func (s *Store) LoginEventCreation(ctx context.Context, input *model.NewLoginEventModel) (*model.LoginEventModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	event := model.LoginEventModel{
+		ID:          uuid.NewString(),
+		UserID:      input.UserID,
+		Method:      input.Method,
+		Outcome:     input.Outcome,
+		IP:          input.IP,
+		UserAgent:   input.UserAgent,
+		Fingerprint: input.Fingerprint,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.db.WithContext(ctx).Create(&event).Error; err != nil {
+		return nil, fmt.Errorf("failed to create login event: %w", err)
+	}
+	return &event, nil
+}
+
+// LoginEventByID implements repos.Repository.
+func (s *Store) LoginEventByID(ctx context.Context, id string) (*model.LoginEventModel, error) {
+	var event model.LoginEventModel
+	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&event).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // Event not found
+		}
+		return nil, fmt.Errorf("failed to fetch login event by id: %w", err)
+	}
+	return &event, nil
+}
+
+// LoginEvents implements repos.Repository.
+func (s *Store) LoginEvents(ctx context.Context, filter model.LoginEventFilter) ([]*model.LoginEventModel, error) {
+	query := s.db.WithContext(ctx).Order("created_at DESC")
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.Fingerprint != "" {
+		query = query.Where("fingerprint = ?", filter.Fingerprint)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var events []*model.LoginEventModel
+	if err := query.Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch login events: %w", err)
+	}
+	return events, nil
+}
+
+// LoginEventReport implements repos.Repository.
+func (s *Store) LoginEventReport(ctx context.Context, id, userID string) (*model.LoginEventModel, error) {
+	err := s.db.WithContext(ctx).Model(&model.LoginEventModel{}).
+		Where("id = ? AND user_id = ? AND reported_at IS NULL", id, userID).
+		Update("reported_at", time.Now()).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to report login event: %w", err)
+	}
+
+	var event model.LoginEventModel
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&event).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // Event not found
+		}
+		return nil, fmt.Errorf("failed to fetch login event: %w", err)
+	}
+	return &event, nil
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"gorm.io/gorm"
+)
+
+// MagicLinkCreation implements repos.Repository.
+func (s *Store) MagicLinkCreation(ctx context.Context, input *model.NewMagicLinkModel) (*model.MagicLinkModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	link := model.MagicLinkModel{
+		ID:        uuid.NewString(),
+		UserID:    input.UserID,
+		TokenHash: input.TokenHash,
+		NonceHash: input.NonceHash,
+		ExpiresAt: input.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := s.db.WithContext(ctx).Create(&link).Error; err != nil {
+		return nil, fmt.Errorf("failed to create magic link: %w", err)
+	}
+	return &link, nil
+}
+
+// MagicLinkConsume implements repos.Repository.
+func (s *Store) MagicLinkConsume(ctx context.Context, tokenHash string) (*model.MagicLinkModel, error) {
+	var link model.MagicLinkModel
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The conditional update settles concurrent uses of the same link:
+		// only one of them changes the row.
+		now := time.Now()
+		result := tx.Model(&model.MagicLinkModel{}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("token_hash = ?", tokenHash).First(&link).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // No usable link
+		}
+		return nil, fmt.Errorf("failed to consume magic link: %w", err)
+	}
+	return &link, nil
+}
+
+// MagicLinkCountSince implements repos.Repository.
+func (s *Store) MagicLinkCountSince(ctx context.Context, userID string, since time.Time) (int, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&model.MagicLinkModel{}).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count magic links: %w", err)
+	}
+	return int(count), nil
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrganizationCreation implements repos.Repository.
+func (s *Store) OrganizationCreation(ctx context.Context, input *model.NewOrganizationModel, ownerID string) (*model.OrganizationModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+	if input.Name == "" || input.Slug == "" {
+		return nil, apperr.Validation("organization name and slug are required")
+	}
+
+	existing, err := s.OrganizationBySlug(ctx, input.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("error checking existing organization: %w", err)
+	}
+	if existing != nil {
+		return nil, apperr.Conflict("organization with slug %s already exists", input.Slug)
+	}
+
+	now := time.Now()
+	org := model.OrganizationModel{
+		ID:        uuid.NewString(),
+		Name:      input.Name,
+		Slug:      input.Slug,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	owner := model.MembershipModel{
+		ID:             uuid.NewString(),
+		OrganizationID: org.ID,
+		UserID:         ownerID,
+		Role:           model.OrgRoleOwner,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&owner).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	return &org, nil
+}
+
+// OrganizationByID implements repos.Repository.
+func (s *Store) OrganizationByID(ctx context.Context, id string) (*model.OrganizationModel, error) {
+	var org model.OrganizationModel
+	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&org).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // Organization not found
+		}
+		return nil, fmt.Errorf("failed to fetch organization by id: %w", err)
+	}
+	return &org, nil
+}
+
+// OrganizationBySlug implements repos.Repository.
+func (s *Store) OrganizationBySlug(ctx context.Context, slug string) (*model.OrganizationModel, error) {
+	var org model.OrganizationModel
+	if err := s.db.WithContext(ctx).Where("slug = ?", slug).First(&org).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // Organization not found
+		}
+		return nil, fmt.Errorf("failed to fetch organization by slug: %w", err)
+	}
+	return &org, nil
+}
+
+// MembershipCreation implements repos.Repository.
+func (s *Store) MembershipCreation(ctx context.Context, organizationID, userID, role string) (*model.MembershipModel, error) {
+	existing, err := s.MembershipByUser(ctx, organizationID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking existing membership: %w", err)
+	}
+	if existing != nil {
+		return nil, apperr.Conflict("user %s is already a member of organization %s", userID, organizationID)
+	}
+
+	now := time.Now()
+	membership := model.MembershipModel{
+		ID:             uuid.NewString(),
+		OrganizationID: organizationID,
+		UserID:         userID,
+		Role:           role,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.db.WithContext(ctx).Create(&membership).Error; err != nil {
+		return nil, fmt.Errorf("failed to create membership: %w", err)
+	}
+	return &membership, nil
+}
+
+// MembershipByUser implements repos.Repository.
+func (s *Store) MembershipByUser(ctx context.Context, organizationID, userID string) (*model.MembershipModel, error) {
+	var membership model.MembershipModel
+	err := s.db.WithContext(ctx).
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		First(&membership).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // Membership not found
+		}
+		return nil, fmt.Errorf("failed to fetch membership: %w", err)
+	}
+	return &membership, nil
+}
+
+// MembershipsByOrganization implements repos.Repository.
+func (s *Store) MembershipsByOrganization(ctx context.Context, organizationID string) ([]*model.MembershipModel, error) {
+	var memberships []*model.MembershipModel
+	err := s.db.WithContext(ctx).
+		Where("organization_id = ?", organizationID).
+		Order("created_at").
+		Find(&memberships).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch memberships by organization: %w", err)
+	}
+	return memberships, nil
+}
+
+// MembershipsByUser implements repos.Repository.
+func (s *Store) MembershipsByUser(ctx context.Context, userID string) ([]*model.MembershipModel, error) {
+	var memberships []*model.MembershipModel
+	err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&memberships).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch memberships by user: %w", err)
+	}
+	return memberships, nil
+}
+
+// MembershipUpdate implements repos.Repository.
+func (s *Store) MembershipUpdate(ctx context.Context, organizationID, userID, role string) (*model.MembershipModel, error) {
+	var membership model.MembershipModel
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the owners makes concurrent demotions wait for each
+		// other, so that each counts the owners the other left.
+		var owners []model.MembershipModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("organization_id = ? AND role = ?", organizationID, model.OrgRoleOwner).
+			Find(&owners).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ? AND user_id = ?", organizationID, userID).First(&membership).Error; err != nil {
+			return err
+		}
+		if membership.Role == model.OrgRoleOwner && role != model.OrgRoleOwner && len(owners) <= 1 {
+			return apperr.Conflict("an organization must keep at least one owner")
+		}
+		membership.Role = role
+		membership.UpdatedAt = time.Now()
+		return tx.Save(&membership).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil // Membership not found
+		}
+		if apperr.Is(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update membership: %w", err)
+	}
+	return &membership, nil
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"gorm.io/gorm"
+)
+
+// SigningKeyRotation implements repos.Repository.
+func (s *Store) SigningKeyRotation(ctx context.Context, input *model.NewSigningKeyModel, retireAt time.Time) (*model.SigningKeyModel, error) {
+	if input == nil {
+		return nil, fmt.Errorf("input parameter is nil")
+	}
+
+	now := time.Now()
+	key := model.SigningKeyModel{
+		ID:         input.ID,
+		Secret:     input.Secret,
+		ActiveFrom: input.ActiveFrom,
+		CreatedAt:  now,
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.SigningKeyModel{}).
+			Where("expires_at IS NULL").
+			Update("expires_at", retireAt).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("expires_at <= ?", now).Delete(&model.SigningKeyModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&key).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate signing key: %w", err)
+	}
+	return &key, nil
+}
+
+// SigningKeys implements repos.Repository.
+func (s *Store) SigningKeys(ctx context.Context) ([]*model.SigningKeyModel, error) {
+	var keys []*model.SigningKeyModel
+	if err := s.db.WithContext(ctx).Order("active_from").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	return keys, nil
+}
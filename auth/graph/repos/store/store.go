@@ -2,12 +2,16 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
 	"github.com/tabed23/cloudmarket-auth/graph/model"
 	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
 	"gorm.io/gorm"
 )
 
@@ -18,8 +22,7 @@ type Store struct {
 // UserByEmail implements repos.Repository.
 func (s *Store) UserByEmail(ctx context.Context, email string) (*model.UserModel, error) {
 	var user model.UserModel
-	fmt.Println("Fetching user with email:", email)
-	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("email_normalized = ?", utils.EmailKey(email)).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil // User not found
 		}
@@ -31,7 +34,7 @@ func (s *Store) UserByEmail(ctx context.Context, email string) (*model.UserModel
 // UserByID implements repos.Repository.
 func (s *Store) UserByID(ctx context.Context, id string) (*model.UserModel, error) {
 	var user model.UserModel
-	if err := s.db.Where("id = ?", id).First(&user).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil // User not found
 		}
@@ -43,12 +46,24 @@ func (s *Store) UserByID(ctx context.Context, id string) (*model.UserModel, erro
 // UserByRole implements repos.Repository.
 func (s *Store) UserByRole(ctx context.Context, role string) ([]*model.UserModel, error) {
 	var users []*model.UserModel
-	if err := s.db.Where("role = ?", role).Find(&users).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("role = ?", role).Find(&users).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch users by role: %w", err)
 	}
 	return users, nil
 }
 
+// UserByIDs implements repos.Repository.
+func (s *Store) UserByIDs(ctx context.Context, ids []string) ([]*model.UserModel, error) {
+	users := []*model.UserModel{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := s.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch users by ids: %w", err)
+	}
+	return users, nil
+}
+
 // UserCreation implements repos.Repository.
 func (s *Store) UserCreation(ctx context.Context, input *model.NewUserModel) (*model.UserModel, error) {
 	if s == nil {
@@ -68,31 +83,41 @@ func (s *Store) UserCreation(ctx context.Context, input *model.NewUserModel) (*m
 		return nil, fmt.Errorf("error checking existing user: %w", err)
 	}
 	if usr != nil {
-		return nil, fmt.Errorf("user with email %s already exists", input.Email)
-	}
-
-	user := model.UserModel{
-		ID:        uuid.NewString(),
-		FirstName: input.FirstName,
-		LastName:  input.LastName,
-		Email:     input.Email,
-		Password:  input.Password,
-		Role:      input.Role,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		return nil, apperr.Conflict("user with email %s already exists", input.Email)
 	}
 
-	if err := s.db.Create(&user).Error; err != nil {
+	user := newUser(input)
+	if err := s.db.WithContext(ctx).Create(user).Error; err != nil {
+		// A concurrent registration may take the email after the check above.
+		if s.duplicateKey(err) {
+			return nil, apperr.Conflict("user with email %s already exists", input.Email)
+		}
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
+	return user, nil
+}
 
-	return &user, nil
+// newUser is the active account created from input.
+func newUser(input *model.NewUserModel) *model.UserModel {
+	now := time.Now()
+	return &model.UserModel{
+		ID:              uuid.NewString(),
+		FirstName:       input.FirstName,
+		LastName:        input.LastName,
+		Email:           strings.TrimSpace(input.Email),
+		EmailNormalized: utils.EmailKey(input.Email),
+		Password:        input.Password,
+		Role:            input.Role,
+		Status:          model.StatusActive,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
 }
 
 // UserDelete implements repos.Repository.
 func (s *Store) UserDelete(ctx context.Context, email string) error {
 
-	if err := s.db.Where("email = ?", email).Delete(&model.UserModel{}).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("email_normalized = ?", utils.EmailKey(email)).Delete(&model.UserModel{}).Error; err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 	return nil
@@ -101,7 +126,7 @@ func (s *Store) UserDelete(ctx context.Context, email string) error {
 // UserUpdate implements repos.Repository.
 func (s *Store) UserUpdate(ctx context.Context, email string, input *model.NewUserModel) (*model.UserModel, error) {
 	var user model.UserModel
-	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("email_normalized = ?", utils.EmailKey(email)).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil // User not found
 		}
@@ -111,13 +136,65 @@ func (s *Store) UserUpdate(ctx context.Context, email string, input *model.NewUs
 	user.LastName = input.LastName
 	user.Password = input.Password
 	user.Role = input.Role
+	if input.Email != "" {
+		user.Email = strings.TrimSpace(input.Email)
+		user.EmailNormalized = utils.EmailKey(input.Email)
+	}
 	user.UpdatedAt = time.Now()
-	if err := s.db.Save(&user).Error; err != nil {
+	if err := s.db.WithContext(ctx).Save(&user).Error; err != nil {
+		if s.duplicateKey(err) {
+			return nil, apperr.Conflict("user with email %s already exists", input.Email)
+		}
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 	return &user, nil
 }
 
+// duplicateKey reports whether err violates a unique index. The dialector
+// translates the driver's error, as TranslateError is not enabled.
+func (s *Store) duplicateKey(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	if t, ok := s.db.Dialector.(gorm.ErrorTranslator); ok {
+		return errors.Is(t.Translate(err), gorm.ErrDuplicatedKey)
+	}
+	return false
+}
+
+// UserAvatarUpdate implements repos.Repository.
+func (s *Store) UserAvatarUpdate(ctx context.Context, id, avatarKey string) (*model.UserModel, error) {
+	result := s.db.WithContext(ctx).Model(&model.UserModel{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"avatar_key": avatarKey, "updated_at": time.Now()})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update user avatar: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil // User not found
+	}
+	return s.UserByID(ctx, id)
+}
+
+// UserStatusUpdate implements repos.Repository.
+func (s *Store) UserStatusUpdate(ctx context.Context, id, status, reason string, until *time.Time) (*model.UserModel, error) {
+	result := s.db.WithContext(ctx).Model(&model.UserModel{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"status_reason": reason,
+			"status_until":  until,
+			"updated_at":    time.Now(),
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update user status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil // User not found
+	}
+	return s.UserByID(ctx, id)
+}
+
 func NewStore(db *gorm.DB) repos.Repository {
 	return &Store{
 		db: db,
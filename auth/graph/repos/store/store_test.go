@@ -0,0 +1,33 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tabed23/cloudmarket-auth/graph/config"
+	"github.com/tabed23/cloudmarket-auth/graph/migrations"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/repostest"
+)
+
+func TestRepositoryContractSQLite(t *testing.T) {
+	repostest.Run(t, func(t *testing.T) repos.Repository {
+		db, err := config.InitDB(config.DBConfig{Driver: config.DriverSQLite, URL: ":memory:"})
+		if err != nil {
+			t.Fatalf("InitDB: %v", err)
+		}
+		t.Cleanup(func() {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+		migrator, err := migrations.New(db)
+		if err != nil {
+			t.Fatalf("migrations.New: %v", err)
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			t.Fatalf("migrate up: %v", err)
+		}
+		return NewStore(db)
+	})
+}
@@ -1,6 +1,25 @@
 package graph
 
-import "github.com/tabed23/cloudmarket-auth/graph/repos"
+import (
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/tabed23/cloudmarket-auth/graph/accounts"
+	"github.com/tabed23/cloudmarket-auth/graph/background"
+	"github.com/tabed23/cloudmarket-auth/graph/events"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/mailer"
+	"github.com/tabed23/cloudmarket-auth/graph/metrics"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/storage"
+	"github.com/tabed23/cloudmarket-auth/graph/tracing"
+)
 
 // This file will not be regenerated automatically.
 //
@@ -8,4 +27,138 @@ import "github.com/tabed23/cloudmarket-auth/graph/repos"
 
 type Resolver struct{
 	repos.Repository
+	Mailer mailer.Mailer
+	// PublicURL is the base of links sent by email.
+	PublicURL string
+	// Blobs keeps uploaded avatars. Without it uploadAvatar fails and
+	// avatarUrl is always null.
+	Blobs          storage.BlobStore
+	AvatarMaxBytes int64
+	// MagicLinkTTL is how long emailed sign-in links stay valid.
+	MagicLinkTTL time.Duration
+	// Events receives account events such as sign-ins from new devices.
+	Events events.Publisher
+	// Accounts signs users in and registers them, with Mailer and Events.
+	Accounts *accounts.Service
+	// MetricOperations are the operation names metrics are labelled with.
+	MetricOperations []string
+	// Background runs work that must not delay the response, such as
+	// sending sign-in links. It may drop work when it is overloaded.
+	Background func(func())
+}
+
+// Defaults used without the matching options.
+const (
+	defaultAvatarMaxBytes = 5 << 20
+	defaultMagicLinkTTL   = 10 * time.Minute
+	defaultWorkers        = 4
+	defaultQueue          = 100
+)
+
+// Option configures optional Resolver dependencies.
+type Option func(*Resolver)
+
+// WithMailer sends emails through m instead of only logging them.
+func WithMailer(m mailer.Mailer) Option {
+	return func(r *Resolver) { r.Mailer = m }
+}
+
+// WithPublicURL sets the base URL of links sent by email.
+func WithPublicURL(url string) Option {
+	return func(r *Resolver) { r.PublicURL = strings.TrimRight(url, "/") }
+}
+
+// WithBlobStore stores uploaded avatars in b.
+func WithBlobStore(b storage.BlobStore) Option {
+	return func(r *Resolver) { r.Blobs = b }
+}
+
+// WithAvatarMaxBytes sets the largest accepted avatar upload.
+func WithAvatarMaxBytes(n int64) Option {
+	return func(r *Resolver) { r.AvatarMaxBytes = n }
+}
+
+// WithMagicLinkTTL sets how long emailed sign-in links stay valid.
+func WithMagicLinkTTL(d time.Duration) Option {
+	return func(r *Resolver) { r.MagicLinkTTL = d }
+}
+
+// WithMetricOperations sets the operation names that metrics are labelled
+// with; other operations are counted together.
+func WithMetricOperations(names ...string) Option {
+	return func(r *Resolver) { r.MetricOperations = names }
+}
+
+// WithBackground runs work after the response with run instead of on a
+// pool of the resolver's own. The server passes a pool it drains on
+// shutdown.
+func WithBackground(run func(func())) Option {
+	return func(r *Resolver) { r.Background = run }
+}
+
+// WithEvents publishes account events to p instead of only logging them.
+func WithEvents(p events.Publisher) Option {
+	return func(r *Resolver) { r.Events = p }
+}
+
+func newResolver(repo repos.Repository, opts []Option) *Resolver {
+	r := &Resolver{
+		Repository:     repo,
+		Mailer:         mailer.Log{},
+		PublicURL:      "http://localhost:8080",
+		AvatarMaxBytes: defaultAvatarMaxBytes,
+		MagicLinkTTL:   defaultMagicLinkTTL,
+		Events:         events.Log{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.Background == nil {
+		pool := background.New(defaultWorkers, defaultQueue)
+		r.Background = func(f func()) { pool.Go(f) }
+	}
+	r.Accounts = accounts.NewService(repo, r.Mailer, r.Events)
+	return r
+}
+
+// NewConfig wires the resolvers and schema directives around repo.
+func NewConfig(repo repos.Repository, opts ...Option) Config {
+	return newConfig(newResolver(repo, opts))
+}
+
+func newConfig(r *Resolver) Config {
+	c := Config{Resolvers: r}
+	c.Directives.Auth = middleware.Auth
+	c.Directives.Visibility = middleware.Visibility
+	c.Directives.NotImpersonated = middleware.NotImpersonated
+	return c
+}
+
+// NewHandler builds the GraphQL handler served on /query. Requests must pass
+// through middleware.AuthMiddleware first so @auth can see the caller.
+func NewHandler(repo repos.Repository, opts ...Option) *handler.Server {
+	r := newResolver(repo, opts)
+	srv := handler.New(NewExecutableSchema(newConfig(r)))
+
+	// The transports, cache and extensions of handler.NewDefaultServer, with
+	// multipart uploads sized for avatars; the rest of the form is small.
+	srv.AddTransport(transport.Websocket{KeepAlivePingInterval: 10 * time.Second})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{
+		MaxUploadSize: r.AvatarMaxBytes + 1<<20,
+		MaxMemory:     r.AvatarMaxBytes + 1<<20,
+	})
+	srv.SetErrorPresenter(presentError)
+	srv.SetRecoverFunc(recoverPanic)
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New[string](100)})
+	srv.Use(metrics.NewTracer(r.MetricOperations...))
+	srv.Use(tracing.Tracer{})
+	srv.Use(logging.Operations{})
+	srv.Use(csrfProtection{})
+	srv.Use(impersonationAudit{repo: r.Repository})
+	return srv
 }
@@ -0,0 +1,223 @@
+package graph_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+type userResp struct {
+	ID        string
+	FirstName string
+	LastName  string
+	Email     *string
+	Role      *string
+}
+
+func expectError(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected an error containing %q", want)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected an error containing %q, got %v", want, err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	h := graphtest.New(t)
+
+	var resp struct {
+		Register struct {
+			Token        string
+			RefreshToken string
+			User         userResp
+		}
+	}
+	h.MustPost(`mutation {
+		register(input: {firstName: "Ada", lastName: "Lovelace", email: "ada@example.com", password: "secret"}) {
+			token refreshToken user { id firstName email role }
+		}
+	}`, &resp)
+
+	got := resp.Register
+	if got.Token == "" || got.RefreshToken == "" || got.Token == got.RefreshToken {
+		t.Fatalf("unexpected tokens %q / %q", got.Token, got.RefreshToken)
+	}
+	if got.User.Email == nil || *got.User.Email != "ada@example.com" || got.User.Role == nil || *got.User.Role != model.RoleUser {
+		t.Fatalf("unexpected user %+v", got.User)
+	}
+
+	stored, _ := h.Repo.UserByEmail(t.Context(), "ada@example.com")
+	if stored == nil || stored.Password == "secret" {
+		t.Fatalf("password not hashed: %+v", stored)
+	}
+
+	var me struct{ GetMe userResp }
+	h.MustPost(`query { getMe { id } }`, &me, graphtest.Session{Token: got.Token}.Auth())
+	if me.GetMe.ID != got.User.ID {
+		t.Fatalf("token belongs to %q, want %q", me.GetMe.ID, got.User.ID)
+	}
+}
+
+func TestRegisterDuplicateEmail(t *testing.T) {
+	h := graphtest.New(t)
+	h.CreateUser("ada@example.com", model.RoleUser)
+
+	var resp map[string]any
+	err := h.Post(`mutation {
+		register(input: {firstName: "Ada", lastName: "Lovelace", email: "ada@example.com", password: "secret"}) { token }
+	}`, &resp)
+	expectError(t, err, "already exists")
+}
+
+func TestEmailsAreCaseInsensitive(t *testing.T) {
+	h := graphtest.New(t)
+	h.CreateUser("Ada@Example.com", model.RoleUser)
+
+	var resp map[string]any
+	err := h.Post(`mutation {
+		register(input: {firstName: "Ada", lastName: "Lovelace", email: " ada@EXAMPLE.com", password: "secret"}) { token }
+	}`, &resp)
+	expectError(t, err, "already exists")
+
+	for _, email := range []string{"ada@example.com", "ADA@example.COM", " Ada@Example.com "} {
+		if token := h.Login(email, graphtest.Password); token == "" {
+			t.Fatalf("login as %q returned no token", email)
+		}
+	}
+
+	err = h.Post(`mutation {
+		register(input: {firstName: "Ada", lastName: "Lovelace", email: "ada at example.com", password: "secret"}) { token }
+	}`, &resp)
+	expectError(t, err, "invalid email address")
+}
+
+func TestLogin(t *testing.T) {
+	h := graphtest.New(t)
+	h.CreateUser("ada@example.com", model.RoleUser)
+
+	if token := h.Login("ada@example.com", graphtest.Password); token == "" {
+		t.Fatal("login returned no token")
+	}
+
+	var resp map[string]any
+	err := h.Post(`mutation { login(email: "ada@example.com", password: "wrong") { token } }`, &resp)
+	expectError(t, err, "invalid credentials")
+
+	err = h.Post(`mutation { login(email: "nobody@example.com", password: "wrong") { token } }`, &resp)
+	if err == nil {
+		t.Fatal("login with an unknown email succeeded")
+	}
+}
+
+func TestGetMe(t *testing.T) {
+	h := graphtest.New(t)
+	session := h.LoginAsUser()
+
+	var resp struct{ GetMe userResp }
+	h.MustPost(`query { getMe { id email role } }`, &resp, session.Auth())
+	if resp.GetMe.ID != session.User.ID || resp.GetMe.Email == nil || *resp.GetMe.Email != session.User.Email {
+		t.Fatalf("unexpected getMe %+v", resp.GetMe)
+	}
+}
+
+func TestUsersByRole(t *testing.T) {
+	h := graphtest.New(t)
+	user := h.LoginAsUser()
+	other := h.CreateUser("other@example.com", model.RoleUser)
+	admin := h.LoginAsAdmin()
+
+	query := `query { usersByRole(role: "USER") { id email role } }`
+
+	var asAdmin struct{ UsersByRole []userResp }
+	h.MustPost(query, &asAdmin, admin.Auth())
+	if len(asAdmin.UsersByRole) != 2 {
+		t.Fatalf("got %d users, want 2", len(asAdmin.UsersByRole))
+	}
+	for _, u := range asAdmin.UsersByRole {
+		if u.Email == nil || u.Role == nil {
+			t.Errorf("admin cannot see email/role of %s", u.ID)
+		}
+	}
+
+	var asUser struct{ UsersByRole []userResp }
+	h.MustPost(query, &asUser, user.Auth())
+	for _, u := range asUser.UsersByRole {
+		switch u.ID {
+		case user.User.ID:
+			if u.Email == nil {
+				t.Error("user cannot see their own email")
+			}
+		case other.ID:
+			if u.Email != nil || u.Role != nil {
+				t.Errorf("user can see email/role of another user: %+v", u)
+			}
+		}
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	h := graphtest.New(t)
+	session := h.LoginAsUser()
+
+	var resp struct{ UpdateUser string }
+	h.MustPost(`mutation($email: String!) {
+		updateUser(email: $email, input: {firstName: "New", lastName: "Name", email: $email, password: "changed"})
+	}`, &resp, session.Auth(), client.Var("email", session.User.Email))
+
+	updated, _ := h.Repo.UserByID(t.Context(), session.User.ID)
+	if updated.FirstName != "New" || updated.LastName != "Name" {
+		t.Fatalf("update not applied: %+v", updated)
+	}
+	if token := h.Login(session.User.Email, "changed"); token == "" {
+		t.Fatal("cannot log in with the new password")
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	h := graphtest.New(t)
+	session := h.LoginAsUser()
+
+	var resp struct{ DeleteUser string }
+	h.MustPost(`mutation($email: String!) { deleteUser(email: $email) }`, &resp,
+		session.Auth(), client.Var("email", session.User.Email))
+
+	if user, _ := h.Repo.UserByID(t.Context(), session.User.ID); user != nil {
+		t.Fatal("user was not deleted")
+	}
+}
+
+func TestAuthDirectiveRejectsAnonymousCallers(t *testing.T) {
+	h := graphtest.New(t)
+	target := h.CreateUser("ada@example.com", model.RoleUser)
+
+	documents := map[string]string{
+		"user":        `query { user(id: "` + target.ID + `") { id } }`,
+		"userEmail":   `query { userEmail(email: "ada@example.com") { id } }`,
+		"usersByRole": `query { usersByRole(role: "USER") { id } }`,
+		"protected":   `query { protected }`,
+		"getMe":       `query { getMe { id } }`,
+		"updateUser":  `mutation { updateUser(email: "ada@example.com", input: {firstName: "x", lastName: "y", email: "ada@example.com", password: "z"}) }`,
+		"deleteUser":  `mutation { deleteUser(email: "ada@example.com") }`,
+	}
+	for name, document := range documents {
+		t.Run(name, func(t *testing.T) {
+			var resp map[string]any
+			expectError(t, h.Post(document, &resp), "Access Denied")
+		})
+	}
+
+	t.Run("invalid token", func(t *testing.T) {
+		var resp map[string]any
+		err := h.Post(`query { getMe { id } }`, &resp, graphtest.Session{Token: "not-a-jwt"}.Auth())
+		expectError(t, err, "Access Denied")
+	})
+
+	if user, _ := h.Repo.UserByID(t.Context(), target.ID); user == nil || user.FirstName != "Test" {
+		t.Fatalf("anonymous mutation changed the user: %+v", user)
+	}
+}
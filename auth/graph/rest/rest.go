@@ -0,0 +1,243 @@
+// Package rest serves JSON endpoints for signing in, for clients that would
+// rather not use GraphQL. They run on the accounts service like the
+// resolvers, behind the same middleware.
+package rest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/accounts"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+)
+
+// errCSRF answers cookie-authenticated requests without the CSRF header.
+const errCSRF = "missing or invalid CSRF token"
+
+// maxBodyBytes caps request bodies; every request here is a small form.
+const maxBodyBytes = 64 << 10
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+var logger = logging.For("rest")
+
+// NewHandler serves the /auth endpoints. Requests must pass through
+// middleware.ClientInfo and middleware.AuthMiddleware first, like those of
+// the GraphQL handler.
+func NewHandler(svc *accounts.Service) http.Handler {
+	h := &handler{svc: svc}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /auth/login", h.login)
+	mux.HandleFunc("POST /auth/register", h.register)
+	mux.HandleFunc("POST /auth/refresh", h.refresh)
+	mux.HandleFunc("POST /auth/logout", h.logout)
+	mux.HandleFunc("GET /auth/me", h.me)
+	mux.HandleFunc("GET /auth/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPI)
+	})
+	return h.audit(mux)
+}
+
+// audit writes requests made while impersonating a user to the audit log
+// before serving them, and refuses them if that fails.
+func (h *handler) audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h.svc.AuditImpersonation(r.Context(), r.Method+" "+r.URL.Path, ""); err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: err.Error()})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type handler struct {
+	svc *accounts.Service
+}
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type registerRequest struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+}
+
+// refreshRequest may leave RefreshToken empty in the cookie session mode to
+// use the refresh cookie.
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// sessionResponse mirrors the AuthPayload of the GraphQL API.
+type sessionResponse struct {
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refreshToken"`
+	User         userResponse `json:"user"`
+}
+
+// userResponse is the account as its owner sees it.
+type userResponse struct {
+	ID        string    `json:"id"`
+	FirstName string    `json:"firstName"`
+	LastName  string    `json:"lastName"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (h *handler) login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	session, err := h.svc.Login(r.Context(), req.Email, req.Password)
+	writeSession(w, r, http.StatusOK, session, err)
+}
+
+func (h *handler) register(w http.ResponseWriter, r *http.Request) {
+	var req registerRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	session, err := h.svc.Register(r.Context(), accounts.Registration{
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Email:     req.Email,
+		Password:  req.Password,
+	})
+	writeSession(w, r, http.StatusCreated, session, err)
+}
+
+func (h *handler) refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.RefreshToken == "" && middleware.CookieSessionsEnabled(r.Context()) {
+		if cookie, err := r.Cookie(middleware.RefreshCookie); err == nil {
+			if !middleware.ValidCSRF(r) {
+				writeJSON(w, http.StatusForbidden, errorResponse{Error: errCSRF})
+				return
+			}
+			req.RefreshToken = cookie.Value
+		}
+	}
+	session, err := h.svc.Refresh(r.Context(), req.RefreshToken)
+	writeSession(w, r, http.StatusOK, session, err)
+}
+
+func (h *handler) logout(w http.ResponseWriter, r *http.Request) {
+	if middleware.CSRFFailed(r.Context()) {
+		writeJSON(w, http.StatusForbidden, errorResponse{Error: errCSRF})
+		return
+	}
+	middleware.ClearSessionCookies(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) me(w http.ResponseWriter, r *http.Request) {
+	user, err := h.svc.Me(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toUser(user))
+}
+
+// decode reads a JSON body into v, answering 400 if it cannot.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON body"})
+		return false
+	}
+	return true
+}
+
+// writeSession answers with the session and, in the cookie session mode,
+// sets its cookies.
+func writeSession(w http.ResponseWriter, r *http.Request, status int, session *accounts.Session, err error) {
+	var inCookies bool
+	if err == nil {
+		inCookies, err = middleware.SetSessionCookies(r.Context(), session.Token, session.RefreshToken)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	resp := sessionResponse{
+		Token:        session.Token,
+		RefreshToken: session.RefreshToken,
+		User:         toUser(session.User),
+	}
+	// Tokens set as HttpOnly cookies are kept from scripts.
+	if inCookies {
+		resp.Token, resp.RefreshToken = "", ""
+	}
+	writeJSON(w, status, resp)
+}
+
+// writeError answers with the status code of the apperr code of err.
+// Internal errors are logged and not shown.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	switch apperr.CodeOf(err) {
+	case apperr.CodeValidation:
+		status = http.StatusBadRequest
+	case apperr.CodeUnauthenticated:
+		status = http.StatusUnauthorized
+	case apperr.CodeForbidden:
+		status = http.StatusForbidden
+	case apperr.CodeNotFound:
+		status = http.StatusNotFound
+	case apperr.CodeConflict:
+		status = http.StatusConflict
+	}
+	msg := err.Error()
+	if status == http.StatusInternalServerError {
+		logger.ErrorContext(r.Context(), "request failed", slog.Any("error", err))
+		msg = "internal error"
+	}
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	writeJSON(w, status, errorResponse{Error: msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func toUser(u *model.UserModel) userResponse {
+	return userResponse{
+		ID:        u.ID,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Email:     u.Email,
+		Role:      u.Role,
+		Status:    u.EffectiveStatus(time.Now()),
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+}
@@ -0,0 +1,335 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/accounts"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/rest"
+)
+
+type session struct {
+	Token        string
+	RefreshToken string
+	User         struct {
+		ID     string
+		Email  string
+		Role   string
+		Status string
+	}
+}
+
+// newServer serves the REST handler through the middleware of the server,
+// over the harness repository.
+func newServer(t *testing.T, h *graphtest.Harness) *httptest.Server {
+	t.Helper()
+	var handler http.Handler = rest.NewHandler(accounts.NewService(h.Repo, h.Mail, h.Events))
+	handler = middleware.AuthMiddleware(h.Repo)(handler)
+	handler = middleware.ClientInfo(false)(handler)
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// call sends body as JSON and decodes the response into resp, returning
+// the status code and, for errors, the message.
+func call(t *testing.T, srv *httptest.Server, method, path, token string, body, resp any) (int, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	if s, ok := body.(string); ok {
+		buf.WriteString(s)
+	} else if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, _ := http.NewRequest(method, srv.URL+path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		var e struct{ Error string }
+		json.NewDecoder(res.Body).Decode(&e)
+		return res.StatusCode, e.Error
+	}
+	if resp != nil {
+		if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
+			t.Fatalf("decode %s %s: %v", method, path, err)
+		}
+	}
+	return res.StatusCode, ""
+}
+
+func credentials(email, password string) map[string]string {
+	return map[string]string{"email": email, "password": password}
+}
+
+func TestLogin(t *testing.T) {
+	h := graphtest.New(t)
+	srv := newServer(t, h)
+	user := h.CreateUser("ada@example.com", model.RoleUser)
+
+	var got session
+	status, msg := call(t, srv, http.MethodPost, "/auth/login", "", credentials("ADA@example.com", graphtest.Password), &got)
+	if status != http.StatusOK {
+		t.Fatalf("login = %d %s", status, msg)
+	}
+	if got.User.ID != user.ID || got.User.Email != "ada@example.com" || got.User.Status != model.StatusActive || got.RefreshToken == "" {
+		t.Errorf("login = %+v", got)
+	}
+	claims, err := jwt.ValidateJwt(context.Background(), got.Token)
+	if err != nil || claims.SessionID == "" {
+		t.Fatalf("access token claims = %+v, %v", claims, err)
+	}
+	// The sign-in is in the login history like one through GraphQL.
+	if login, _ := h.Repo.LoginEventByID(context.Background(), claims.SessionID); login == nil || login.Method != model.LoginMethodPassword {
+		t.Errorf("login event = %+v", login)
+	}
+
+	for name, body := range map[string]any{
+		"bad password":  credentials("ada@example.com", "wrong"),
+		"unknown email": credentials("bob@example.com", graphtest.Password),
+	} {
+		if status, msg := call(t, srv, http.MethodPost, "/auth/login", "", body, nil); status != http.StatusUnauthorized || msg != "invalid credentials" {
+			t.Errorf("login with %s = %d %s", name, status, msg)
+		}
+	}
+	if status, _ := call(t, srv, http.MethodPost, "/auth/login", "", "{not json", nil); status != http.StatusBadRequest {
+		t.Errorf("login with a broken body = %d", status)
+	}
+
+	if _, err := h.Repo.UserStatusUpdate(context.Background(), user.ID, model.StatusDisabled, "left", nil); err != nil {
+		t.Fatalf("UserStatusUpdate: %v", err)
+	}
+	if status, msg := call(t, srv, http.MethodPost, "/auth/login", "", credentials("ada@example.com", graphtest.Password), nil); status != http.StatusForbidden || msg != "account is disabled" {
+		t.Errorf("login of a disabled account = %d %s", status, msg)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	h := graphtest.New(t)
+	srv := newServer(t, h)
+	body := map[string]string{"firstName": "Ada", "lastName": "Lovelace", "email": "ada@example.com", "password": "secret"}
+
+	var got session
+	if status, msg := call(t, srv, http.MethodPost, "/auth/register", "", body, &got); status != http.StatusCreated {
+		t.Fatalf("register = %d %s", status, msg)
+	}
+	if got.User.Role != model.RoleUser || got.Token == "" {
+		t.Errorf("register = %+v", got)
+	}
+
+	body["email"] = "ADA@example.com"
+	if status, _ := call(t, srv, http.MethodPost, "/auth/register", "", body, nil); status != http.StatusConflict {
+		t.Errorf("register with a taken email = %d", status)
+	}
+	body["email"] = "not-an-email"
+	if status, _ := call(t, srv, http.MethodPost, "/auth/register", "", body, nil); status != http.StatusBadRequest {
+		t.Errorf("register with an invalid email = %d", status)
+	}
+	body["email"], body["password"] = "bob@example.com", ""
+	if status, msg := call(t, srv, http.MethodPost, "/auth/register", "", body, nil); status != http.StatusBadRequest || msg != "password is required" {
+		t.Errorf("register without a password = %d %s", status, msg)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	h := graphtest.New(t)
+	srv := newServer(t, h)
+	user := h.CreateUser("ada@example.com", model.RoleUser)
+	var first session
+	call(t, srv, http.MethodPost, "/auth/login", "", credentials("ada@example.com", graphtest.Password), &first)
+
+	// A role changed since sign-in applies to the refreshed tokens.
+	if _, err := h.Repo.UserUpdate(context.Background(), user.Email, &model.NewUserModel{
+		FirstName: user.FirstName, LastName: user.LastName, Email: user.Email, Password: user.Password, Role: model.RoleAdmin,
+	}); err != nil {
+		t.Fatalf("UserUpdate: %v", err)
+	}
+	var refreshed session
+	if status, msg := call(t, srv, http.MethodPost, "/auth/refresh", "", map[string]string{"refreshToken": first.RefreshToken}, &refreshed); status != http.StatusOK {
+		t.Fatalf("refresh = %d %s", status, msg)
+	}
+	before, _ := jwt.ValidateJwt(context.Background(), first.Token)
+	after, err := jwt.ValidateJwt(context.Background(), refreshed.Token)
+	if err != nil || after.Role != model.RoleAdmin || after.SessionID != before.SessionID {
+		t.Errorf("refreshed claims = %+v, %v; want ADMIN in session %s", after, err, before.SessionID)
+	}
+
+	if status, _ := call(t, srv, http.MethodPost, "/auth/refresh", "", map[string]string{"refreshToken": first.Token}, nil); status != http.StatusUnauthorized {
+		t.Errorf("refresh with an access token = %d", status)
+	}
+
+	// Marking the sign-in as not theirs revokes its refresh tokens.
+	if _, err := h.Repo.LoginEventReport(context.Background(), before.SessionID, user.ID); err != nil {
+		t.Fatalf("LoginEventReport: %v", err)
+	}
+	if status, _ := call(t, srv, http.MethodPost, "/auth/refresh", "", map[string]string{"refreshToken": refreshed.RefreshToken}, nil); status != http.StatusUnauthorized {
+		t.Errorf("refresh of a revoked session = %d", status)
+	}
+}
+
+func TestMe(t *testing.T) {
+	h := graphtest.New(t)
+	srv := newServer(t, h)
+	s := h.LoginAsUser()
+
+	var got struct {
+		ID        string
+		Email     string
+		CreatedAt time.Time
+	}
+	if status, msg := call(t, srv, http.MethodGet, "/auth/me", s.Token, nil, &got); status != http.StatusOK {
+		t.Fatalf("me = %d %s", status, msg)
+	}
+	if got.ID != s.User.ID || got.Email != s.User.Email || got.CreatedAt.IsZero() {
+		t.Errorf("me = %+v", got)
+	}
+	if status, _ := call(t, srv, http.MethodGet, "/auth/me", "", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("me without a token = %d", status)
+	}
+	if status, _ := call(t, srv, http.MethodGet, "/auth/me", "garbage", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("me with an invalid token = %d", status)
+	}
+}
+
+func TestImpersonatedRequestsAreAudited(t *testing.T) {
+	h := graphtest.New(t)
+	srv := newServer(t, h)
+	admin := h.LoginAsAdmin()
+	user := h.LoginAsUser()
+	acting := h.Impersonate(admin, user.User.ID)
+
+	if status, msg := call(t, srv, http.MethodGet, "/auth/me", acting.Token, nil, nil); status != http.StatusOK {
+		t.Fatalf("me while impersonating = %d %s", status, msg)
+	}
+	entries, err := h.Repo.AuditLogs(t.Context(), model.AuditLogFilter{SubjectID: user.User.ID, Action: model.AuditImpersonatedRequest})
+	if err != nil || len(entries) != 1 || entries[0].ActorID != admin.User.ID || entries[0].Operation != "GET /auth/me" {
+		t.Errorf("audit log = %+v, %v; want GET /auth/me by the admin", entries, err)
+	}
+
+	// Requests that do not impersonate are not logged.
+	call(t, srv, http.MethodGet, "/auth/me", user.Token, nil, nil)
+	if entries, _ := h.Repo.AuditLogs(t.Context(), model.AuditLogFilter{Action: model.AuditImpersonatedRequest}); len(entries) != 1 {
+		t.Errorf("audit log has %d requests; want 1", len(entries))
+	}
+}
+
+func TestCookieSessions(t *testing.T) {
+	h := graphtest.New(t)
+	var handler http.Handler = rest.NewHandler(accounts.NewService(h.Repo, h.Mail, h.Events))
+	handler = middleware.AuthMiddleware(h.Repo)(handler)
+	handler = middleware.CookieSessions(middleware.CookieOptions{SameSite: http.SameSiteLaxMode})(handler)
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+	client := srv.Client()
+	client.Jar, _ = cookiejar.New(nil)
+	h.CreateUser("ada@example.com", model.RoleUser)
+
+	var last session
+	send := func(path, body, csrf string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if csrf != "" {
+			req.Header.Set(middleware.CSRFHeader, csrf)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		defer res.Body.Close()
+		last = session{}
+		json.NewDecoder(res.Body).Decode(&last)
+		return res
+	}
+	cookie := func(name string) string {
+		u, _ := url.Parse(srv.URL)
+		for _, c := range client.Jar.Cookies(u) {
+			if c.Name == name {
+				return c.Value
+			}
+		}
+		return ""
+	}
+
+	body, _ := json.Marshal(credentials("ada@example.com", graphtest.Password))
+
+	// Browsers mark requests from a form on another site, which must not
+	// sign the browser in.
+	crossSite, _ := http.NewRequest(http.MethodPost, srv.URL+"/auth/login", strings.NewReader(string(body)))
+	crossSite.Header.Set("Content-Type", "application/json")
+	crossSite.Header.Set("Sec-Fetch-Site", "cross-site")
+	res, err := client.Do(crossSite)
+	if err != nil {
+		t.Fatalf("POST /auth/login: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden || cookie(middleware.AccessCookie) != "" {
+		t.Fatalf("cross-site login = %d, access cookie %q", res.StatusCode, cookie(middleware.AccessCookie))
+	}
+
+	if res := send("/auth/login", string(body), ""); res.StatusCode != http.StatusOK {
+		t.Fatalf("login = %d", res.StatusCode)
+	}
+	if last.Token != "" || last.RefreshToken != "" {
+		t.Errorf("login body = %+v; want the tokens only in cookies", last)
+	}
+	if cookie(middleware.AccessCookie) == "" || cookie(middleware.RefreshCookie) == "" || cookie(middleware.CSRFCookie) == "" {
+		t.Fatalf("login did not set the session cookies")
+	}
+
+	// The refresh cookie is used when the body has no token, but only with
+	// the CSRF token.
+	if res := send("/auth/refresh", `{}`, ""); res.StatusCode != http.StatusForbidden {
+		t.Errorf("refresh without the CSRF token = %d", res.StatusCode)
+	}
+	if res := send("/auth/refresh", `{}`, cookie(middleware.CSRFCookie)); res.StatusCode != http.StatusOK {
+		t.Fatalf("refresh with the refresh cookie = %d", res.StatusCode)
+	}
+	if cookie(middleware.AccessCookie) == "" {
+		t.Errorf("refresh cleared the access cookie")
+	}
+
+	if res := send("/auth/logout", "", ""); res.StatusCode != http.StatusForbidden {
+		t.Errorf("logout without the CSRF token = %d", res.StatusCode)
+	}
+	if res := send("/auth/logout", "", cookie(middleware.CSRFCookie)); res.StatusCode != http.StatusNoContent {
+		t.Errorf("logout = %d", res.StatusCode)
+	}
+	if cookie(middleware.AccessCookie) != "" || cookie(middleware.RefreshCookie) != "" {
+		t.Errorf("logout did not clear the cookies")
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	srv := newServer(t, graphtest.New(t))
+	res, err := http.Get(srv.URL + "/auth/openapi.yaml")
+	if err != nil {
+		t.Fatalf("GET openapi.yaml: %v", err)
+	}
+	defer res.Body.Close()
+	var buf bytes.Buffer
+	buf.ReadFrom(res.Body)
+	for _, path := range []string{"/auth/login:", "/auth/register:", "/auth/refresh:", "/auth/logout:", "/auth/me:"} {
+		if !strings.Contains(buf.String(), path) {
+			t.Errorf("openapi.yaml does not document %s", path)
+		}
+	}
+}
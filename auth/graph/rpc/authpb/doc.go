@@ -0,0 +1,8 @@
+// Package authpb is the gRPC client and server API of the auth service,
+// generated from auth.proto. Internal services connect with
+//
+//	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	client := authpb.NewAuthServiceClient(conn)
+package authpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative auth.proto
@@ -0,0 +1,238 @@
+// Package rpc serves the gRPC API of package authpb to internal services.
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/rpc/authpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// MaxBatch is the most users GetUsers looks up in one call.
+const MaxBatch = 100
+
+var logger = logging.For("grpc")
+
+// Role ranks; a higher role includes the lower ones.
+var (
+	accountRoles      = map[string]int{model.RoleUser: 1, model.RoleAdmin: 2}
+	organizationRoles = map[string]int{model.OrgRoleMember: 1, model.OrgRoleAdmin: 2, model.OrgRoleOwner: 3}
+)
+
+type service struct {
+	authpb.UnimplementedAuthServiceServer
+	repo repos.Repository
+}
+
+// NewServer returns a gRPC server with the auth service registered over
+// repo. Every call is logged, and panics fail the call instead of the
+// server.
+func NewServer(repo repos.Repository, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{grpc.ChainUnaryInterceptor(logCalls)}, opts...)
+	s := grpc.NewServer(opts...)
+	authpb.RegisterAuthServiceServer(s, &service{repo: repo})
+	return s
+}
+
+// RequireToken refuses calls that do not carry token in an
+// "authorization: Bearer <token>" header. Pass it to NewServer when the
+// API is reachable beyond the host.
+func RequireToken(token string) grpc.ServerOption {
+	want := []byte("Bearer " + token)
+	return grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), want) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "missing or invalid credentials")
+		}
+		return handler(ctx, req)
+	})
+}
+
+// ValidateToken implements authpb.AuthServiceServer.
+func (s *service) ValidateToken(ctx context.Context, req *authpb.ValidateTokenRequest) (*authpb.ValidateTokenResponse, error) {
+	claims, err := middleware.Authenticate(ctx, s.repo, req.GetToken())
+	if err != nil {
+		return &authpb.ValidateTokenResponse{Reason: err.Error()}, nil
+	}
+	// The calling service acts on the token, so its use is audited like a
+	// request to the other APIs.
+	if err := middleware.AuditImpersonation(ctx, s.repo, claims, "grpc ValidateToken", ""); err != nil {
+		return &authpb.ValidateTokenResponse{Reason: err.Error()}, nil
+	}
+	return &authpb.ValidateTokenResponse{Valid: true, Claims: toClaims(claims)}, nil
+}
+
+// GetUser implements authpb.AuthServiceServer.
+func (s *service) GetUser(ctx context.Context, req *authpb.GetUserRequest) (*authpb.User, error) {
+	var user *model.UserModel
+	var err error
+	switch lookup := req.GetLookup().(type) {
+	case *authpb.GetUserRequest_Id:
+		user, err = s.repo.UserByID(ctx, lookup.Id)
+	case *authpb.GetUserRequest_Email:
+		user, err = s.repo.UserByEmail(ctx, lookup.Email)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "id or email is required")
+	}
+	if err != nil {
+		return nil, internal(ctx, "failed to fetch user", err)
+	}
+	if user == nil {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return toUser(user, time.Now()), nil
+}
+
+// GetUsers implements authpb.AuthServiceServer.
+func (s *service) GetUsers(ctx context.Context, req *authpb.GetUsersRequest) (*authpb.GetUsersResponse, error) {
+	if len(req.GetIds()) > MaxBatch {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d ids can be looked up at once", MaxBatch)
+	}
+
+	users, err := s.repo.UserByIDs(ctx, req.GetIds())
+	if err != nil {
+		return nil, internal(ctx, "failed to fetch users", err)
+	}
+	byID := make(map[string]*model.UserModel, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	now := time.Now()
+	resp := &authpb.GetUsersResponse{}
+	seen := make(map[string]bool, len(req.GetIds()))
+	for _, id := range req.GetIds() {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if user := byID[id]; user != nil {
+			resp.Users = append(resp.Users, toUser(user, now))
+		} else {
+			resp.MissingIds = append(resp.MissingIds, id)
+		}
+	}
+	return resp, nil
+}
+
+// CheckPermission implements authpb.AuthServiceServer.
+func (s *service) CheckPermission(ctx context.Context, req *authpb.CheckPermissionRequest) (*authpb.CheckPermissionResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	ranks, scope := accountRoles, "account"
+	if req.GetOrganizationId() != "" {
+		ranks, scope = organizationRoles, "organization"
+	}
+	required, ok := ranks[req.GetRole()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown %s role %q", scope, req.GetRole())
+	}
+
+	user, err := s.repo.UserByID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, internal(ctx, "failed to fetch user", err)
+	}
+	if user == nil {
+		return deny("user not found"), nil
+	}
+	if st := user.EffectiveStatus(time.Now()); st != model.StatusActive {
+		return deny("account is " + strings.ToLower(st)), nil
+	}
+
+	held := user.Role
+	if req.GetOrganizationId() != "" {
+		membership, err := s.repo.MembershipByUser(ctx, req.GetOrganizationId(), user.ID)
+		if err != nil {
+			return nil, internal(ctx, "failed to fetch membership", err)
+		}
+		if membership == nil {
+			return deny("not a member of the organization"), nil
+		}
+		held = membership.Role
+	}
+	if ranks[held] < required {
+		return deny(fmt.Sprintf("%s role is %s", scope, held)), nil
+	}
+	return &authpb.CheckPermissionResponse{Allowed: true}, nil
+}
+
+func deny(reason string) *authpb.CheckPermissionResponse {
+	return &authpb.CheckPermissionResponse{Reason: reason}
+}
+
+// internal logs err and hides it from the caller.
+func internal(ctx context.Context, msg string, err error) error {
+	logger.ErrorContext(ctx, msg, slog.Any("error", err))
+	return status.Error(codes.Internal, msg)
+}
+
+func toClaims(c *jwt.JwtClaims) *authpb.Claims {
+	claims := &authpb.Claims{
+		UserId:           c.ID,
+		Email:            c.Email,
+		Role:             c.Role,
+		OrganizationId:   c.OrgID,
+		OrganizationRole: c.OrgRole,
+		SessionId:        c.SessionID,
+		ExpiresAt:        timestamppb.New(time.Unix(c.ExpiresAt, 0)),
+	}
+	if c.Act != nil {
+		claims.ActorId = c.Act.ID
+	}
+	return claims
+}
+
+func toUser(u *model.UserModel, now time.Time) *authpb.User {
+	return &authpb.User{
+		Id:        u.ID,
+		Email:     u.Email,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Role:      u.Role,
+		Status:    u.EffectiveStatus(now),
+		CreatedAt: timestamppb.New(u.CreatedAt),
+		UpdatedAt: timestamppb.New(u.UpdatedAt),
+	}
+}
+
+// logCalls writes one log line per call, as logging.Middleware does for
+// HTTP requests, and turns panics into INTERNAL errors.
+func logCalls(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	ctx = logging.WithFields(ctx, uuid.NewString())
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			logger.ErrorContext(ctx, "panic in call", slog.Any("panic", p), slog.String("stack", string(debug.Stack())))
+			resp, err = nil, status.Error(codes.Internal, "internal error")
+		}
+		code := status.Code(err)
+		level := slog.LevelInfo
+		if code == codes.Internal || code == codes.Unknown {
+			level = slog.LevelError
+		}
+		logger.Log(ctx, level, "call completed",
+			slog.String("method", info.FullMethod),
+			slog.String("code", code.String()),
+			slog.Duration("duration", time.Since(start)),
+		)
+	}()
+	return handler(ctx, req)
+}
@@ -0,0 +1,227 @@
+package rpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/rpc"
+	"github.com/tabed23/cloudmarket-auth/graph/rpc/authpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial serves the auth service over an in-memory listener and returns a
+// client connected to it.
+func dial(t *testing.T, h *graphtest.Harness, opts ...grpc.ServerOption) authpb.AuthServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := rpc.NewServer(h.Repo, opts...)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return authpb.NewAuthServiceClient(conn)
+}
+
+func TestValidateToken(t *testing.T) {
+	h := graphtest.New(t)
+	client := dial(t, h)
+	ctx := context.Background()
+	session := h.LoginAsUser()
+
+	resp, err := client.ValidateToken(ctx, &authpb.ValidateTokenRequest{Token: session.Token})
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	c := resp.GetClaims()
+	if !resp.GetValid() || c.GetUserId() != session.User.ID || c.GetEmail() != session.User.Email ||
+		c.GetRole() != model.RoleUser || c.GetSessionId() == "" || !c.GetExpiresAt().AsTime().After(time.Now()) {
+		t.Errorf("ValidateToken = %v", resp)
+	}
+
+	for name, token := range map[string]string{"empty": "", "garbage": "not-a-token", "tampered": session.Token + "x"} {
+		resp, err := client.ValidateToken(ctx, &authpb.ValidateTokenRequest{Token: token})
+		if err != nil || resp.GetValid() || resp.GetReason() == "" || resp.GetClaims() != nil {
+			t.Errorf("ValidateToken(%s) = %v, %v; want an invalid result with a reason", name, resp, err)
+		}
+	}
+
+	// Suspending the account invalidates its tokens at once.
+	if _, err := h.Repo.UserStatusUpdate(ctx, session.User.ID, model.StatusSuspended, "abuse", nil); err != nil {
+		t.Fatalf("UserStatusUpdate: %v", err)
+	}
+	if resp, err := client.ValidateToken(ctx, &authpb.ValidateTokenRequest{Token: session.Token}); err != nil || resp.GetValid() {
+		t.Errorf("ValidateToken of a suspended account = %v, %v", resp, err)
+	}
+}
+
+func TestValidateTokenImpersonation(t *testing.T) {
+	h := graphtest.New(t)
+	client := dial(t, h)
+	admin := h.LoginAsAdmin()
+	user := h.LoginAsUser()
+	acting := h.Impersonate(admin, user.User.ID)
+
+	resp, err := client.ValidateToken(context.Background(), &authpb.ValidateTokenRequest{Token: acting.Token})
+	if err != nil || !resp.GetValid() {
+		t.Fatalf("ValidateToken = %v, %v", resp, err)
+	}
+	if resp.GetClaims().GetUserId() != user.User.ID || resp.GetClaims().GetActorId() != admin.User.ID {
+		t.Errorf("claims = %v, want user %s acted on by %s", resp.GetClaims(), user.User.ID, admin.User.ID)
+	}
+	entries, err := h.Repo.AuditLogs(context.Background(), model.AuditLogFilter{SubjectID: user.User.ID, Action: model.AuditImpersonatedRequest})
+	if err != nil || len(entries) != 1 || entries[0].ActorID != admin.User.ID || entries[0].Operation != "grpc ValidateToken" {
+		t.Errorf("audit log = %+v, %v; want the validation by the admin", entries, err)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	h := graphtest.New(t)
+	client := dial(t, h)
+	ctx := context.Background()
+	user := h.CreateUser("Ada@Example.com", model.RoleUser)
+
+	byID, err := client.GetUser(ctx, &authpb.GetUserRequest{Lookup: &authpb.GetUserRequest_Id{Id: user.ID}})
+	if err != nil {
+		t.Fatalf("GetUser(id): %v", err)
+	}
+	if byID.GetId() != user.ID || byID.GetEmail() != "Ada@Example.com" || byID.GetRole() != model.RoleUser ||
+		byID.GetStatus() != model.StatusActive || byID.GetCreatedAt().AsTime().IsZero() {
+		t.Errorf("GetUser(id) = %v", byID)
+	}
+	byEmail, err := client.GetUser(ctx, &authpb.GetUserRequest{Lookup: &authpb.GetUserRequest_Email{Email: "ada@example.com"}})
+	if err != nil || byEmail.GetId() != user.ID {
+		t.Errorf("GetUser(email) = %v, %v", byEmail, err)
+	}
+
+	_, err = client.GetUser(ctx, &authpb.GetUserRequest{Lookup: &authpb.GetUserRequest_Id{Id: "missing"}})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetUser(missing) error = %v, want NOT_FOUND", err)
+	}
+	_, err = client.GetUser(ctx, &authpb.GetUserRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetUser() error = %v, want INVALID_ARGUMENT", err)
+	}
+}
+
+func TestGetUsers(t *testing.T) {
+	h := graphtest.New(t)
+	client := dial(t, h)
+	ctx := context.Background()
+	ada := h.CreateUser("ada@example.com", model.RoleUser)
+	bob := h.CreateUser("bob@example.com", model.RoleAdmin)
+
+	resp, err := client.GetUsers(ctx, &authpb.GetUsersRequest{Ids: []string{bob.ID, "missing", ada.ID, bob.ID}})
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	users := resp.GetUsers()
+	if len(users) != 2 || users[0].GetId() != bob.ID || users[1].GetId() != ada.ID {
+		t.Errorf("users = %v, want bob then ada", users)
+	}
+	if missing := resp.GetMissingIds(); len(missing) != 1 || missing[0] != "missing" {
+		t.Errorf("missing ids = %v", missing)
+	}
+
+	_, err = client.GetUsers(ctx, &authpb.GetUsersRequest{Ids: make([]string, rpc.MaxBatch+1)})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetUsers over the batch limit error = %v, want INVALID_ARGUMENT", err)
+	}
+}
+
+func TestCheckPermission(t *testing.T) {
+	h := graphtest.New(t)
+	client := dial(t, h)
+	ctx := context.Background()
+	admin := h.CreateUser("admin@example.com", model.RoleAdmin)
+	member := h.CreateUser("member@example.com", model.RoleUser)
+	org, err := h.Repo.OrganizationCreation(ctx, &model.NewOrganizationModel{Name: "Acme", Slug: "acme"}, admin.ID)
+	if err != nil {
+		t.Fatalf("OrganizationCreation: %v", err)
+	}
+	if _, err := h.Repo.MembershipCreation(ctx, org.ID, member.ID, model.OrgRoleMember); err != nil {
+		t.Fatalf("MembershipCreation: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		userID, org string
+		role        string
+		allowed     bool
+	}{
+		{"admin is a user", admin.ID, "", model.RoleUser, true},
+		{"admin is an admin", admin.ID, "", model.RoleAdmin, true},
+		{"user is not an admin", member.ID, "", model.RoleAdmin, false},
+		{"owner is a member", admin.ID, org.ID, model.OrgRoleMember, true},
+		{"owner is an owner", admin.ID, org.ID, model.OrgRoleOwner, true},
+		{"member is a member", member.ID, org.ID, model.OrgRoleMember, true},
+		{"member is not an org admin", member.ID, org.ID, model.OrgRoleAdmin, false},
+		{"outsider is not a member", member.ID, "other-org", model.OrgRoleMember, false},
+		{"unknown user", "missing", "", model.RoleUser, false},
+	} {
+		resp, err := client.CheckPermission(ctx, &authpb.CheckPermissionRequest{UserId: tc.userID, OrganizationId: tc.org, Role: tc.role})
+		if err != nil {
+			t.Errorf("%s: CheckPermission: %v", tc.name, err)
+			continue
+		}
+		if resp.GetAllowed() != tc.allowed || (resp.GetReason() == "") != tc.allowed {
+			t.Errorf("%s: CheckPermission = %v, want allowed %v", tc.name, resp, tc.allowed)
+		}
+	}
+
+	if _, err := h.Repo.UserStatusUpdate(ctx, admin.ID, model.StatusDisabled, "left", nil); err != nil {
+		t.Fatalf("UserStatusUpdate: %v", err)
+	}
+	resp, err := client.CheckPermission(ctx, &authpb.CheckPermissionRequest{UserId: admin.ID, Role: model.RoleUser})
+	if err != nil || resp.GetAllowed() || resp.GetReason() != "account is disabled" {
+		t.Errorf("CheckPermission of a disabled account = %v, %v", resp, err)
+	}
+
+	for _, req := range []*authpb.CheckPermissionRequest{
+		{Role: model.RoleUser},
+		{UserId: member.ID, Role: model.OrgRoleOwner},
+		{UserId: member.ID, OrganizationId: org.ID, Role: "SUPERUSER"},
+	} {
+		if _, err := client.CheckPermission(ctx, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("CheckPermission(%v) error = %v, want INVALID_ARGUMENT", req, err)
+		}
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	h := graphtest.New(t)
+	client := dial(t, h, rpc.RequireToken("internal-secret"))
+	user := h.CreateUser("ada@example.com", model.RoleUser)
+	req := &authpb.GetUserRequest{Lookup: &authpb.GetUserRequest_Email{Email: user.Email}}
+
+	for name, md := range map[string]metadata.MD{
+		"missing": nil,
+		"wrong":   metadata.Pairs("authorization", "Bearer guessed"),
+		"scheme":  metadata.Pairs("authorization", "internal-secret"),
+	} {
+		ctx := metadata.NewOutgoingContext(context.Background(), md)
+		if _, err := client.GetUser(ctx, req); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("GetUser with %s credentials = %v; want Unauthenticated", name, err)
+		}
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer internal-secret")
+	if got, err := client.GetUser(ctx, req); err != nil || got.GetId() != user.ID {
+		t.Errorf("GetUser with the token = %v, %v", got, err)
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/apperr"
 	"github.com/tabed23/cloudmarket-auth/graph/middleware"
 	"github.com/tabed23/cloudmarket-auth/graph/model"
 	"github.com/tabed23/cloudmarket-auth/graph/utils"
@@ -16,79 +16,18 @@ import (
 
 // Login is the resolver for the login field.
 func (r *mutationResolver) Login(ctx context.Context, email string, password string) (*model.AuthPayload, error) {
-	// Fetch user by email
-	user, err := r.UserByEmail(ctx, email)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch user by email: %w", err)
-	}
-
-	// Validate password
-	if !utils.CheckPasswordHash(password, user.Password) {
-		return nil, fmt.Errorf("invalid credentials")
-	}
-
-	// Generate JWT token
-	token, err := jwt.GenreateJwt(ctx, user.ID, user.Email, user.Role)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate JWT: %w", err)
-	}
-
-	// Generate refresh token (same for simplicity in this case)
-	refreshToken := token
-
-	// Convert user to GraphQL user model
-	usr := model.ConvertToGraphQLUser(*user)
-
-	// Return AuthPayload with token and refreshToken
-	return &model.AuthPayload{
-		Token:        token,
-		RefreshToken: refreshToken,
-		User:         usr,
-	}, nil
+	return authPayload(ctx)(r.Accounts.Login(ctx, email, password))
 }
 
 // Register is the resolver for the register field.
 func (r *mutationResolver) Register(ctx context.Context, input model.NewUser) (*model.AuthPayload, error) {
-	hashpass, err := utils.HashPassword(input.Password)
-	if err != nil {
-		return nil, fmt.Errorf("failed to hash password: %w", err)
-	}
-
-	user := model.NewUserModel{
-		FirstName: input.FirstName,
-		LastName:  input.LastName,
-		Email:     input.Email,
-		Password:  hashpass,
-		Role:      "USER",
-	}
-
-	if r == nil {
-		return nil, fmt.Errorf("user service is nil")
-	}
-
-	createUser, err := r.UserCreation(ctx, &user)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
-	}
-
-	// Check if createUser is nil
-	if createUser == nil {
-		return nil, fmt.Errorf("created user is nil")
-	}
-
-	token, err := jwt.GenreateJwt(ctx, createUser.ID, createUser.Email, createUser.Role)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate JWT: %w", err)
-	}
-
-	refreshToken := token
-	usr := model.ConvertToGraphQLUser(*createUser)
+	return authPayload(ctx)(r.Accounts.Register(ctx, registration(input)))
+}
 
-	return &model.AuthPayload{
-		Token:        token,
-		RefreshToken: refreshToken,
-		User:         usr,
-	}, nil // Fixed: was returning err instead of nil
+// Logout is the resolver for the logout field.
+func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
+	middleware.ClearSessionCookies(ctx)
+	return true, nil
 }
 
 // DeleteUser is the resolver for the deleteUser field.
@@ -97,6 +36,9 @@ func (r *mutationResolver) DeleteUser(ctx context.Context, email string) (string
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch user by email: %w", err)
 	}
+	if usrer == nil {
+		return "", apperr.NotFound("user not found")
+	}
 	err = r.UserDelete(ctx, usrer.Email)
 	if err != nil {
 		return "", fmt.Errorf("failed to delete user: %w", err)
@@ -106,10 +48,19 @@ func (r *mutationResolver) DeleteUser(ctx context.Context, email string) (string
 
 // UpdateUser is the resolver for the updateUser field.
 func (r *mutationResolver) UpdateUser(ctx context.Context, email string, input *model.NewUser) (string, error) {
+	if input == nil {
+		return "", apperr.Validation("input is required")
+	}
 	user, err := r.UserByEmail(ctx, email)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch user by email: %w", err)
 	}
+	if user == nil {
+		return "", apperr.NotFound("user not found")
+	}
+	if _, err := utils.NormalizeEmail(input.Email); err != nil {
+		return "", apperr.Validation("%s", err)
+	}
 	hashpass, err := utils.HashPassword(input.Password)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
@@ -135,7 +86,10 @@ func (r *queryResolver) User(ctx context.Context, id string) (*model.User, error
 		return nil, fmt.Errorf("failed to fetch user by id: %w", err)
 	}
 	if user == nil {
-		return nil, fmt.Errorf("user not found")
+		return nil, apperr.NotFound("user not found")
+	}
+	if err := r.checkVisible(ctx, user.ID); err != nil {
+		return nil, err
 	}
 
 	usr := model.ConvertToGraphQLUser(*user)
@@ -147,7 +101,13 @@ func (r *queryResolver) UserEmail(ctx context.Context, email string) (*model.Use
 	// Fetch user by email
 	user, err := r.UserByEmail(ctx, email)
 	if err != nil {
-		return nil, fmt.Errorf("could not find user with email %s: %v", email, err)
+		return nil, fmt.Errorf("failed to fetch user by email: %w", err)
+	}
+	if user == nil {
+		return nil, apperr.NotFound("user not found")
+	}
+	if err := r.checkVisible(ctx, user.ID); err != nil {
+		return nil, err
 	}
 
 	// Convert to GraphQL User model
@@ -155,13 +115,36 @@ func (r *queryResolver) UserEmail(ctx context.Context, email string) (*model.Use
 	return usr, nil
 }
 
-// UsersByRole is the resolver for the usersByRole field.
 // UsersByRole is the resolver for the usersByRole field.
 func (r *queryResolver) UsersByRole(ctx context.Context, role string) ([]*model.User, error) {
 	// Fetch users by role
 	users, err := r.UserByRole(ctx, role)
 	if err != nil {
-		return nil, fmt.Errorf("could not find users with role %s: %v", role, err)
+		return nil, fmt.Errorf("failed to fetch users by role: %w", err)
+	}
+
+	// With an active organization only its members are listed. Like
+	// checkVisible, this only narrows the view.
+	if claims := middleware.CtxValue(ctx); claims != nil && claims.OrgID != "" {
+		membership, err := r.activeMembership(ctx)
+		if err != nil {
+			return nil, err
+		}
+		members, err := r.MembershipsByOrganization(ctx, membership.OrganizationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch members: %w", err)
+		}
+		inOrg := make(map[string]bool, len(members))
+		for _, m := range members {
+			inOrg[m.UserID] = true
+		}
+		scoped := users[:0]
+		for _, u := range users {
+			if inOrg[u.ID] {
+				scoped = append(scoped, u)
+			}
+		}
+		users = scoped
 	}
 
 	// Convert the list of users to GraphQL User models
@@ -177,24 +160,18 @@ func (r *queryResolver) UsersByRole(ctx context.Context, role string) ([]*model.
 func (r *queryResolver) Protected(ctx context.Context) (string, error) {
 	claims := middleware.CtxValue(ctx)
 	if claims == nil {
-		return "", fmt.Errorf("user not authenticated")
+		return "", apperr.Unauthenticated("user not authenticated")
 	}
 	return fmt.Sprintf("Protected route accessed by user %s", claims.Email), nil
 }
 
 // GetMe is the resolver for the getMe field.
 func (r *queryResolver) GetMe(ctx context.Context) (*model.User, error) {
-	claims := middleware.CtxValue(ctx)
-	if claims == nil {
-		return nil, fmt.Errorf("user not authenticated")
-	}
-
-	user, err := r.UserByID(ctx, claims.ID)
+	user, err := r.Accounts.Me(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("could not find user: %v", err)
+		return nil, err
 	}
-	usr := model.ConvertToGraphQLUser(*user)
-	return usr, nil
+	return model.ConvertToGraphQLUser(*user), nil
 }
 
 // Mutation returns MutationResolver implementation.
@@ -203,5 +180,9 @@ func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// User returns UserResolver implementation.
+func (r *Resolver) User() UserResolver { return &userResolver{r} }
+
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
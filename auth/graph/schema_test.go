@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// credentialFields must never be reachable from any output type.
+var credentialFields = []string{"password", "passwordHash", "hashedPassword"}
+
+// tokenFields may only be returned by the payloads that issue them.
+var tokenFields = []string{"token", "refreshToken"}
+
+var tokenPayloads = map[string]bool{"AuthPayload": true, "ImpersonationPayload": true}
+
+func newSchemaTestClient() *client.Client {
+	srv := handler.New(NewExecutableSchema(Config{Resolvers: &Resolver{}}))
+	srv.AddTransport(transport.POST{})
+	return client.New(srv)
+}
+
+func TestOutputTypesHaveNoCredentialFields(t *testing.T) {
+	schema := NewExecutableSchema(Config{Resolvers: &Resolver{}}).Schema()
+	for name, def := range schema.Types {
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+		if def.Kind != ast.Object && def.Kind != ast.Interface {
+			continue
+		}
+		for _, field := range credentialFields {
+			if def.Fields.ForName(field) != nil {
+				t.Errorf("%s exposes credential field %q", name, field)
+			}
+		}
+		if tokenPayloads[name] {
+			continue
+		}
+		for _, field := range tokenFields {
+			if def.Fields.ForName(field) != nil {
+				t.Errorf("%s exposes token field %q", name, field)
+			}
+		}
+	}
+}
+
+func TestUserVisibilityDirectives(t *testing.T) {
+	schema := NewExecutableSchema(Config{Resolvers: &Resolver{}}).Schema()
+	user := schema.Types["User"]
+	for _, field := range []string{"email", "role"} {
+		def := user.Fields.ForName(field)
+		if def == nil {
+			t.Fatalf("User.%s is missing", field)
+		}
+		if def.Type.NonNull {
+			t.Errorf("User.%s must be nullable so hidden values resolve to null", field)
+		}
+		if def.Directives.ForName("visibility") == nil {
+			t.Errorf("User.%s is missing @visibility", field)
+		}
+	}
+}
+
+func TestCredentialFieldsCannotBeSelected(t *testing.T) {
+	c := newSchemaTestClient()
+	queries := map[string]string{
+		"getMe password":       `query { getMe { id password } }`,
+		"getMe token":          `query { getMe { id token } }`,
+		"getMe refreshToken":   `query { getMe { id refreshToken } }`,
+		"usersByRole password": `query { usersByRole(role: "USER") { password } }`,
+		"login user password":  `mutation { login(email: "a@b.c", password: "x") { user { password } } }`,
+		"register user token":  `mutation { register(input: {firstName: "a", lastName: "b", email: "a@b.c", password: "x"}) { user { token } } }`,
+		"fragment on User":     `query { getMe { ...secret } } fragment secret on User { password }`,
+	}
+	for name, query := range queries {
+		t.Run(name, func(t *testing.T) {
+			var resp map[string]interface{}
+			err := c.Post(query, &resp)
+			if err == nil {
+				t.Fatal("expected a validation error")
+			}
+			if !strings.Contains(err.Error(), "Cannot query field") {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalFS is a BlobStore on the local filesystem. Objects are written to a
+// temporary file and renamed into place, so readers never see partial data.
+type LocalFS struct {
+	root    string
+	baseURL string
+}
+
+// NewLocalFS stores objects below root and builds their URLs from baseURL,
+// which is where Handler is expected to be mounted.
+func NewLocalFS(root, baseURL string) (*LocalFS, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &LocalFS{root: root, baseURL: strings.TrimRight(baseURL, "/")}, nil
+}
+
+// Put implements BlobStore.
+func (l *LocalFS) Put(ctx context.Context, key, contentType string, r io.Reader) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	return nil
+}
+
+// Delete implements BlobStore.
+func (l *LocalFS) Delete(ctx context.Context, key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(name); err != nil {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
+
+// URL implements BlobStore.
+func (l *LocalFS) URL(key string) string {
+	return l.baseURL + "/" + key
+}
+
+// Handler serves stored objects by key. Directory listings are not served.
+func (l *LocalFS) Handler() http.Handler {
+	files := http.FileServer(http.Dir(l.root))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := CleanKey(strings.TrimPrefix(r.URL.Path, "/")); err != nil || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		files.ServeHTTP(w, r)
+	})
+}
+
+func (l *LocalFS) path(key string) (string, error) {
+	cleaned, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(cleaned)), nil
+}
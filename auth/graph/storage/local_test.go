@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanKey(t *testing.T) {
+	valid := map[string]string{
+		"avatars/u1/a/64.jpg":    "avatars/u1/a/64.jpg",
+		"avatars//u1/./64.jpg":   "avatars/u1/64.jpg",
+		"avatars/u1/x/../64.jpg": "avatars/u1/64.jpg",
+	}
+	for key, want := range valid {
+		if got, err := CleanKey(key); err != nil || got != want {
+			t.Errorf("CleanKey(%q) = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	for _, key := range []string{"", ".", "..", "../etc/passwd", "a/../../b", "/etc/passwd", `a\..\b`} {
+		if got, err := CleanKey(key); err == nil {
+			t.Errorf("CleanKey(%q) = %q, want an error", key, got)
+		}
+	}
+}
+
+func TestLocalFSPutDeleteServe(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	fs, err := NewLocalFS(root, "https://cdn.test/blobs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Put(ctx, "avatars/u1/v1/64.jpg", "image/jpeg", strings.NewReader("first")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := fs.Put(ctx, "avatars/u1/v1/64.jpg", "image/jpeg", strings.NewReader("second")); err != nil {
+		t.Fatalf("Put over an existing key: %v", err)
+	}
+	if got := fs.URL("avatars/u1/v1/64.jpg"); got != "https://cdn.test/blobs/avatars/u1/v1/64.jpg" {
+		t.Errorf("URL = %q", got)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		fs.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	rec := get("/avatars/u1/v1/64.jpg")
+	if body, _ := io.ReadAll(rec.Body); rec.Code != http.StatusOK || string(body) != "second" {
+		t.Fatalf("GET = %d %q", rec.Code, body)
+	}
+	if rec := get("/avatars/u1/"); rec.Code != http.StatusNotFound {
+		t.Errorf("directory listing served: %d", rec.Code)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(root, "avatars", "u1", "v1"))
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+
+	if err := fs.Delete(ctx, "avatars/u1/v1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if rec := get("/avatars/u1/v1/64.jpg"); rec.Code != http.StatusNotFound {
+		t.Errorf("deleted blob served: %d", rec.Code)
+	}
+	if err := fs.Delete(ctx, "avatars/u1/v1"); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+	if err := fs.Put(ctx, "../escape", "text/plain", strings.NewReader("x")); err == nil {
+		t.Error("Put outside the root succeeded")
+	}
+}
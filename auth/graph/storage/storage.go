@@ -0,0 +1,36 @@
+// Package storage keeps binary objects such as avatar images outside the
+// database.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// BlobStore stores objects under slash-separated keys and serves them from
+// public URLs.
+type BlobStore interface {
+	// Put stores the contents of r under key, replacing any existing object.
+	Put(ctx context.Context, key, contentType string, r io.Reader) error
+	// Delete removes key and, when key is a prefix, everything below it.
+	// Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of key.
+	URL(key string) string
+}
+
+// CleanKey rejects keys that are empty, absolute or that climb out of the
+// store with "..", and returns the key in canonical form.
+func CleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "otel:span"
+
+// GormPlugin records a client span for every GORM statement. Statements are
+// recorded with their placeholders, never with bound values.
+type GormPlugin struct{}
+
+var _ gorm.Plugin = GormPlugin{}
+
+func (GormPlugin) Name() string {
+	return "otel-tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		before, after func(string, func(*gorm.DB)) error
+		name          string
+	}{
+		{cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register, "gorm.Create"},
+		{cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register, "gorm.Query"},
+		{cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register, "gorm.Update"},
+		{cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register, "gorm.Delete"},
+		{cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register, "gorm.Row"},
+		{cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register, "gorm.Raw"},
+	}
+	for _, h := range hooks {
+		if err := h.before("otel:before:"+h.name, startSpan(h.name)); err != nil {
+			return err
+		}
+		if err := h.after("otel:after:"+h.name, endSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startSpan(name string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx, span := tracer().Start(tx.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("db.system", tx.Dialector.Name())),
+		)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endSpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.statement", tx.Statement.SQL.String()),
+		attribute.String("db.sql.table", tx.Statement.Table),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+	if err := tx.Error; err != nil && err != gorm.ErrRecordNotFound {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
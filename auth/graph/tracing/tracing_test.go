@@ -0,0 +1,98 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/tabed23/cloudmarket-auth/graph/graphtest"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/store"
+	"github.com/tabed23/cloudmarket-auth/graph/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	remoteTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	remoteSpanID  = "00f067aa0ba902b7"
+)
+
+func TestLoginSpanTree(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+
+	db := graphtest.OpenSQLite(t)
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		t.Fatalf("register gorm plugin: %v", err)
+	}
+	h := graphtest.NewWithRepository(t, store.NewStore(db))
+	h.CreateUser("ada@example.com", model.RoleUser)
+
+	c := client.New(tracing.Middleware(h.Handler), client.Path("/query"))
+	var resp struct{ Login struct{ Token string } }
+	c.MustPost(`mutation Login { login(email: "ada@example.com", password: "`+graphtest.Password+`") { token } }`, &resp,
+		client.AddHeader("traceparent", "00-"+remoteTraceID+"-"+remoteSpanID+"-01"))
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	var dbSpans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() != remoteTraceID {
+			continue
+		}
+		if span.Name() == "gorm.Query" {
+			dbSpans = append(dbSpans, span)
+			continue
+		}
+		spans[span.Name()] = span
+	}
+
+	httpSpan := mustSpan(t, spans, "POST /query")
+	operation := mustSpan(t, spans, "mutation Login")
+	field := mustSpan(t, spans, "Mutation.login")
+
+	if got := httpSpan.Parent().SpanID().String(); got != remoteSpanID || !httpSpan.Parent().IsRemote() {
+		t.Errorf("http span parent = %s, want remote %s", got, remoteSpanID)
+	}
+	assertParent(t, operation, httpSpan)
+	assertParent(t, field, operation)
+
+	if len(dbSpans) == 0 {
+		t.Fatal("no gorm.Query span in the login trace")
+	}
+	for _, span := range dbSpans {
+		assertParent(t, span, field)
+		for _, attr := range span.Attributes() {
+			if attr.Key == "db.statement" && attr.Value.AsString() == "" {
+				t.Error("gorm span has an empty db.statement")
+			}
+		}
+	}
+}
+
+func mustSpan(t *testing.T, spans map[string]sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	span, ok := spans[name]
+	if !ok {
+		names := make([]string, 0, len(spans))
+		for n := range spans {
+			names = append(names, n)
+		}
+		t.Fatalf("span %q not recorded, got %v", name, names)
+	}
+	return span
+}
+
+func assertParent(t *testing.T, child, parent sdktrace.ReadOnlySpan) {
+	t.Helper()
+	if child.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("%s is not a child of %s", child.Name(), parent.Name())
+	}
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// NormalizeEmail returns the canonical form of an email address, under
+// which two spellings of one mailbox are the same account: surrounding
+// space trimmed, lowercased, and the domain in its ASCII (punycode) form,
+// so "Bob@Bücher.de" becomes "bob@xn--bcher-kva.de".
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 || at == len(email)-1 {
+		return "", fmt.Errorf("invalid email address %q", email)
+	}
+	local, domain := email[:at], strings.TrimSuffix(email[at+1:], ".")
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil || ascii == "" {
+		return "", fmt.Errorf("invalid email domain %q", domain)
+	}
+	return strings.ToLower(local) + "@" + strings.ToLower(ascii), nil
+}
+
+// EmailKey is NormalizeEmail for storage and lookups. An address that does
+// not normalize, such as one stored before addresses were checked, is only
+// trimmed and lowercased so that it can still be found.
+func EmailKey(email string) string {
+	if normalized, err := NormalizeEmail(email); err == nil {
+		return normalized
+	}
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// SameEmail reports whether a and b are spellings of one address.
+func SameEmail(a, b string) bool {
+	return EmailKey(a) == EmailKey(b)
+}
@@ -0,0 +1,38 @@
+package utils
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	valid := map[string]string{
+		"bob@example.com":       "bob@example.com",
+		"  Bob@Example.COM ":    "bob@example.com",
+		"BOB@example.com.":      "bob@example.com",
+		"bob@Bücher.de":         "bob@xn--bcher-kva.de",
+		"bob@xn--bcher-kva.de":  "bob@xn--bcher-kva.de",
+		"Ünal@example.com":      "ünal@example.com",
+		`"a@b"@example.com`:     `"a@b"@example.com`,
+		"first.last+tag@ex.com": "first.last+tag@ex.com",
+	}
+	for in, want := range valid {
+		if got, err := NormalizeEmail(in); err != nil || got != want {
+			t.Errorf("NormalizeEmail(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "bob", "@example.com", "bob@", "bob@exa mple.com", "bob@-bad-.com"} {
+		if got, err := NormalizeEmail(in); err == nil {
+			t.Errorf("NormalizeEmail(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestSameEmail(t *testing.T) {
+	if !SameEmail("Bob@Bücher.DE", " bob@xn--bcher-kva.de") {
+		t.Error("spellings of one address differ")
+	}
+	if SameEmail("bob@example.com", "bob2@example.com") {
+		t.Error("different addresses are the same")
+	}
+	if EmailKey(" Not An Address ") != "not an address" {
+		t.Errorf("EmailKey of an invalid address = %q", EmailKey(" Not An Address "))
+	}
+}
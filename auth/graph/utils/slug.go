@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Slugify lowercases s and joins its letters and digits with single dashes,
+// so "Acme Corp." becomes "acme-corp".
+func Slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+)
+
+const keysUsage = "usage: auth keys rotate [flags]"
+
+var keysCommands = map[string]command{
+	"rotate": keysRotate,
+}
+
+// runKeys implements `auth keys rotate`.
+func runKeys(args []string) {
+	runCommand("keys", keysUsage, keysCommands, args)
+}
+
+// keysRotate adds a token signing key. Servers sign with it once
+// jwt.KeyPropagationDelay has passed and all of them can verify it; the
+// keys it replaces keep verifying their tokens until those have expired.
+func keysRotate(*flag.FlagSet) func(context.Context, *cliEnv) error {
+	return func(ctx context.Context, env *cliEnv) error {
+		key, err := jwt.NewKey(time.Now().Add(jwt.KeyPropagationDelay))
+		if err != nil {
+			return err
+		}
+		secret, err := jwt.SealSecret(key)
+		if err != nil {
+			return err
+		}
+		_, err = env.repo.SigningKeyRotation(ctx, &model.NewSigningKeyModel{
+			ID:         key.ID,
+			Secret:     secret,
+			ActiveFrom: key.ActiveFrom,
+		}, key.ActiveFrom.Add(jwt.MaxTokenTTL()))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(env.out, "added signing key %s, in use from %s\n", key.ID, key.ActiveFrom.Format("2006-01-02 15:04:05 MST"))
+		return nil
+	}
+}
+
+// loadSigningKeys hands the stored signing keys to the jwt package.
+func loadSigningKeys(ctx context.Context, repo repos.SigningKeyRepository) error {
+	stored, err := repo.SigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	keys := make([]jwt.Key, 0, len(stored))
+	for _, k := range stored {
+		secret, err := jwt.OpenSecret(k.ID, k.Secret)
+		if err != nil {
+			return fmt.Errorf("signing key %s: invalid secret: %w", k.ID, err)
+		}
+		keys = append(keys, jwt.Key{ID: k.ID, Secret: secret, ActiveFrom: k.ActiveFrom, ExpiresAt: k.ExpiresAt})
+	}
+	jwt.SetKeys(keys)
+	return nil
+}
+
+// refreshSigningKeys reloads the signing keys every jwt.KeyRefreshInterval
+// until ctx is done, so that rotations reach running servers.
+func refreshSigningKeys(ctx context.Context, repo repos.SigningKeyRepository) {
+	ticker := time.NewTicker(jwt.KeyRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := loadSigningKeys(ctx, repo); err != nil {
+				logger.Error("failed to refresh signing keys", slog.Any("error", err))
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/tabed23/cloudmarket-auth/graph/config"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/migrations"
+)
+
+const migrateUsage = "usage: auth migrate up|down [steps]|status [flags]"
+
+// runMigrate implements `auth migrate up|down [steps]|status`.
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fatal("invalid arguments", errors.New(migrateUsage))
+	}
+	action, args := args[0], args[1:]
+
+	steps := 1
+	if action == "down" && len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil {
+			if n < 1 {
+				fatal("invalid arguments", errors.New("migrate down: steps must be at least 1"))
+			}
+			steps, args = n, args[1:]
+		}
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	logging.Setup(os.Stdout, cfg.Log.Level, cfg.Log.Levels)
+	if cfg.DB.Driver == config.DriverMemory {
+		fatal("invalid arguments", errors.New("migrate: the memory driver has no schema to migrate"))
+	}
+	db, err := config.InitDB(cfg.DB)
+	if err != nil {
+		fatal("failed to open database", err)
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		fatal("failed to load migrations", err)
+	}
+
+	ctx := context.Background()
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fatal("migrate up failed", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fatal("migrate down failed", err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("no applied migrations")
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fatal("migrate status failed", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		w.Flush()
+	default:
+		fatal("invalid arguments", errors.New(migrateUsage))
+	}
+}
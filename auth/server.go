@@ -1,43 +1,270 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gorilla/mux"
-	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/tabed23/cloudmarket-auth/graph"
+	"github.com/tabed23/cloudmarket-auth/graph/accounts"
+	"github.com/tabed23/cloudmarket-auth/graph/background"
+	"github.com/tabed23/cloudmarket-auth/graph/cache"
 	"github.com/tabed23/cloudmarket-auth/graph/config"
+	"github.com/tabed23/cloudmarket-auth/graph/events"
+	"github.com/tabed23/cloudmarket-auth/graph/health"
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/logging"
+	"github.com/tabed23/cloudmarket-auth/graph/mailer"
+	"github.com/tabed23/cloudmarket-auth/graph/metrics"
 	"github.com/tabed23/cloudmarket-auth/graph/middleware"
+	"github.com/tabed23/cloudmarket-auth/graph/migrations"
+	"github.com/tabed23/cloudmarket-auth/graph/repos"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/cached"
+	"github.com/tabed23/cloudmarket-auth/graph/repos/memory"
 	"github.com/tabed23/cloudmarket-auth/graph/repos/store"
+	"github.com/tabed23/cloudmarket-auth/graph/rest"
+	"github.com/tabed23/cloudmarket-auth/graph/rpc"
+	"github.com/tabed23/cloudmarket-auth/graph/storage"
+	"github.com/tabed23/cloudmarket-auth/graph/tracing"
+	"google.golang.org/grpc"
+	"gorm.io/gorm"
 )
 
-const defaultPort = "8080"
+var logger = logging.For("server")
+
+// fatal logs err and exits; used for startup failures.
+func fatal(msg string, err error) {
+	logger.Error(msg, slog.Any("error", err))
+	os.Exit(1)
+}
+
+const usage = `usage: auth [serve] [flags]
+       auth migrate up|down [steps]|status [flags]
+       auth user create|set-role|reset-password|list [flags]
+       auth keys rotate [flags]`
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = defaultPort
+	// Without a command, as before there were any, the flags are serve's.
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	switch name {
+	case "serve":
+		runServe(args)
+	case "migrate":
+		runMigrate(args)
+	case "user":
+		runUser(args)
+	case "keys":
+		runKeys(args)
+	case "help":
+		fmt.Println(usage)
+	default:
+		fatal("invalid arguments", fmt.Errorf("unknown command %q\n%s", name, usage))
+	}
+}
+
+// runServe implements `auth serve`, the API server.
+func runServe(args []string) {
+	cfg, err := config.Load(args)
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	logging.Setup(os.Stdout, cfg.Log.Level, cfg.Log.Levels)
+	jwt.Configure(cfg.JWT.Secret, cfg.JWT.Issuer, cfg.JWT.AccessTokenTTL, cfg.JWT.RefreshTokenTTL, cfg.JWT.InvitationTTL, cfg.JWT.ImpersonationTTL)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("failed to set up tracing", err)
 	}
-	if err :=godotenv.Load(); err != nil {
-		log.Print("No .env file found")
+
+	repo, db, err := openRepository(cfg)
+	if err != nil {
+		fatal("failed to open repository", err)
+	}
+	if err := loadSigningKeys(context.Background(), repo); err != nil {
+		fatal("failed to load signing keys", err)
+	}
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		fatal("failed to set up mail", err)
+	}
+	blobs, err := storage.NewLocalFS(cfg.Blob.Dir, cfg.Blob.BaseURL)
+	if err != nil {
+		fatal("failed to set up blob storage", err)
+	}
+	checker := health.New(db)
+	if db != nil {
+		if err := metrics.RegisterDBStats(db); err != nil {
+			fatal("failed to register pool metrics", err)
+		}
 	}
-	db := config.InitDB()
-	store := store.NewStore(db)
+
 	r := mux.NewRouter()
-	r.Use(middleware.AuthMiddleware)
-	c :=  graph.Config{Resolvers: &graph.Resolver{Repository: store}}
-	c.Directives.Auth = middleware.Auth 
+	r.Use(tracing.Middleware)
+	r.Use(logging.Middleware)
+	r.Use(middleware.ClientInfo(cfg.TrustProxy))
+	if cfg.Cookies.Enabled {
+		sameSite, _ := cfg.Cookies.SameSiteMode()
+		r.Use(middleware.CookieSessions(middleware.CookieOptions{Domain: cfg.Cookies.Domain, SameSite: sameSite}))
+	}
+	r.Use(middleware.AuthMiddleware(repo))
+	r.HandleFunc("/healthz", checker.Liveness).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", checker.Readiness).Methods(http.MethodGet)
+	r.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+	r.PathPrefix("/blobs/").Handler(http.StripPrefix("/blobs", blobs.Handler())).Methods(http.MethodGet, http.MethodHead)
+	r.PathPrefix("/auth/").Handler(rest.NewHandler(accounts.NewService(repo, mail, events.Log{})))
+	r.Handle("/", playground.Handler("GraphQL playground", "/query"))
+	// Emails sent after the response, drained on shutdown.
+	pool := background.New(cfg.BackgroundWorkers, cfg.BackgroundQueue)
+	r.Handle("/query", graph.NewHandler(repo,
+		graph.WithMailer(mail),
+		graph.WithPublicURL(cfg.PublicURL),
+		graph.WithBlobStore(blobs),
+		graph.WithAvatarMaxBytes(cfg.AvatarMaxBytes),
+		graph.WithMagicLinkTTL(cfg.MagicLinkTTL),
+		graph.WithMetricOperations(cfg.MetricOperations...),
+		graph.WithBackground(func(f func()) { pool.Go(f) }),
+	))
+
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go refreshSigningKeys(ctx, repo)
 
-	srv := handler.NewDefaultServer(graph.NewExecutableSchema(c))
+	// The gRPC API for internal services shares the repository.
+	var grpcOpts []grpc.ServerOption
+	if cfg.GRPCToken != "" {
+		grpcOpts = append(grpcOpts, rpc.RequireToken(cfg.GRPCToken))
+	}
+	grpcServer := rpc.NewServer(repo, grpcOpts...)
+	grpcListener, err := net.Listen("tcp", net.JoinHostPort(cfg.GRPCAddr, cfg.GRPCPort))
+	if err != nil {
+		fatal("failed to listen for gRPC", err)
+	}
+
+	serveErr := make(chan error, 2)
+	go func() {
+		logger.Info("listening", slog.String("addr", server.Addr),
+			slog.String("playground", "http://localhost:"+cfg.Port+"/"))
+		serveErr <- server.ListenAndServe()
+	}()
+	go func() {
+		logger.Info("listening for gRPC", slog.String("addr", grpcListener.Addr().String()))
+		serveErr <- grpcServer.Serve(grpcListener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		fatal("server failed", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Fail readiness first and give load balancers DrainDelay to stop
+	// sending traffic, then let in-flight requests finish.
+	logger.Info("shutting down", slog.Duration("drain_delay", cfg.DrainDelay),
+		slog.Duration("drain_timeout", cfg.ShutdownTimeout))
+	checker.SetDraining()
+	time.Sleep(cfg.DrainDelay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to drain requests", slog.Any("error", err))
+	}
+	if err := pool.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to drain background tasks", slog.Any("error", err))
+	}
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		logger.Error("failed to drain gRPC calls", slog.Any("error", shutdownCtx.Err()))
+		grpcServer.Stop()
+	}
+
+	if db != nil {
+		if sqlDB, err := db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				logger.Error("failed to close database pool", slog.Any("error", err))
+			}
+		}
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("failed to flush traces", slog.Any("error", err))
+	}
+	logger.Info("server stopped")
+}
 
+// openRepository builds the repository backend selected by DB_DRIVER. The
+// returned *gorm.DB is nil for the memory backend.
+func openRepository(cfg *config.Config) (repos.Repository, *gorm.DB, error) {
+	if cfg.DB.Driver == config.DriverMemory {
+		repo, err := withCache(cfg.Cache, memory.NewStore())
+		return repo, nil, err
+	}
 
-	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", middleware.AuthMiddleware(srv))
+	db, err := config.InitDB(cfg.DB)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, nil, err
+	}
+	if cfg.DB.MigrateOnStart {
+		migrator, err := migrations.New(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := migrator.Up(context.Background()); err != nil {
+			return nil, nil, err
+		}
+	}
+	repo, err := withCache(cfg.Cache, store.NewStore(db))
+	if err != nil {
+		return nil, nil, err
+	}
+	return repo, db, nil
+}
 
-	log.Printf("connect to http://localhost:%s/ for GraphQL playground", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+// withCache wraps repo in the user cache selected by CACHE_DRIVER. The CLI
+// goes through it as well, so that its writes invalidate a shared cache.
+func withCache(cfg config.CacheConfig, repo repos.Repository) (repos.Repository, error) {
+	var c cache.Cache
+	switch cfg.Driver {
+	case config.CacheDriverMemory:
+		logger.Warn("user cache is per process; changes made by other servers or the CLI apply here within CACHE_TTL",
+			slog.Duration("ttl", cfg.TTL))
+		c = cache.NewLRU(cfg.Size)
+	case config.CacheDriverRedis:
+		redis, err := cache.OpenRedis(context.Background(), cfg.RedisURL, "auth:")
+		if err != nil {
+			return nil, err
+		}
+		c = redis
+	default:
+		return repo, nil
+	}
+	return cached.NewStore(repo, c, cached.Options{TTL: cfg.TTL, NegativeTTL: cfg.NegativeTTL}), nil
 }
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tabed23/cloudmarket-auth/graph/jwt"
+	"github.com/tabed23/cloudmarket-auth/graph/model"
+	"github.com/tabed23/cloudmarket-auth/graph/utils"
+)
+
+const userUsage = "usage: auth user create|set-role|reset-password|list [flags]"
+
+var userCommands = map[string]command{
+	"create":         userCreate,
+	"set-role":       userSetRole,
+	"reset-password": userResetPassword,
+	"list":           userList,
+}
+
+// runUser implements `auth user create|set-role|reset-password|list`.
+func runUser(args []string) {
+	runCommand("user", userUsage, userCommands, args)
+}
+
+// userCreate adds an account. Unlike registration it can create admins,
+// which is how the first admin account comes about.
+func userCreate(fset *flag.FlagSet) func(context.Context, *cliEnv) error {
+	email := fset.String("email", "", "email address of the account")
+	firstName := fset.String("first-name", "", "first name of the account holder")
+	lastName := fset.String("last-name", "", "last name of the account holder")
+	admin := fset.Bool("admin", false, "give the account the ADMIN role")
+	fromStdin := fset.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+
+	return func(ctx context.Context, env *cliEnv) error {
+		if _, err := utils.NormalizeEmail(*email); err != nil {
+			return err
+		}
+		existing, err := env.repo.UserByEmail(ctx, *email)
+		if err != nil {
+			return fmt.Errorf("failed to fetch user by email: %w", err)
+		}
+		if existing != nil {
+			return fmt.Errorf("%s already has an account", existing.Email)
+		}
+		password, generated, err := env.password(*fromStdin)
+		if err != nil {
+			return err
+		}
+		hash, err := utils.HashPassword(password)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+
+		role := model.RoleUser
+		if *admin {
+			role = model.RoleAdmin
+		}
+		user, err := env.repo.UserCreation(ctx, &model.NewUserModel{
+			FirstName: *firstName,
+			LastName:  *lastName,
+			Email:     *email,
+			Password:  hash,
+			Role:      role,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		fmt.Fprintf(env.out, "created %s %s with id %s\n", role, user.Email, user.ID)
+		if generated {
+			fmt.Fprintf(env.out, "password: %s\n", password)
+		}
+		return nil
+	}
+}
+
+// userSetRole changes the role of an account. Tokens issued before keep the
+// old role until they expire.
+func userSetRole(fset *flag.FlagSet) func(context.Context, *cliEnv) error {
+	email := fset.String("email", "", "email address of the account")
+	role := fset.String("role", "", "new role: ADMIN or USER")
+
+	return func(ctx context.Context, env *cliEnv) error {
+		newRole, err := parseRole(*role)
+		if err != nil {
+			return err
+		}
+		user, err := findUser(ctx, env, *email)
+		if err != nil {
+			return err
+		}
+		if user.Role == newRole {
+			fmt.Fprintf(env.out, "%s is already %s\n", user.Email, newRole)
+			return nil
+		}
+		if _, err := updateUser(ctx, env, user, user.Password, newRole); err != nil {
+			return err
+		}
+		fmt.Fprintf(env.out, "changed the role of %s from %s to %s\n", user.Email, user.Role, newRole)
+		fmt.Fprintf(env.out, "tokens issued before keep the %s role until they expire, within %s\n", user.Role, jwt.AccessTokenTTL())
+		return nil
+	}
+}
+
+// userResetPassword sets a new password on an account.
+func userResetPassword(fset *flag.FlagSet) func(context.Context, *cliEnv) error {
+	email := fset.String("email", "", "email address of the account")
+	fromStdin := fset.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+
+	return func(ctx context.Context, env *cliEnv) error {
+		user, err := findUser(ctx, env, *email)
+		if err != nil {
+			return err
+		}
+		password, generated, err := env.password(*fromStdin)
+		if err != nil {
+			return err
+		}
+		hash, err := utils.HashPassword(password)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		if _, err := updateUser(ctx, env, user, hash, user.Role); err != nil {
+			return err
+		}
+		fmt.Fprintf(env.out, "reset the password of %s\n", user.Email)
+		if generated {
+			fmt.Fprintf(env.out, "password: %s\n", password)
+		}
+		return nil
+	}
+}
+
+// userList prints the accounts, optionally of one role, sorted by email.
+func userList(fset *flag.FlagSet) func(context.Context, *cliEnv) error {
+	role := fset.String("role", "", "only list accounts with this role: ADMIN or USER")
+
+	return func(ctx context.Context, env *cliEnv) error {
+		roles := []string{model.RoleAdmin, model.RoleUser}
+		if *role != "" {
+			r, err := parseRole(*role)
+			if err != nil {
+				return err
+			}
+			roles = []string{r}
+		}
+
+		var users []*model.UserModel
+		for _, r := range roles {
+			found, err := env.repo.UserByRole(ctx, r)
+			if err != nil {
+				return fmt.Errorf("failed to fetch users by role: %w", err)
+			}
+			users = append(users, found...)
+		}
+		sort.Slice(users, func(i, j int) bool { return users[i].EmailNormalized < users[j].EmailNormalized })
+
+		now := time.Now()
+		w := tabwriter.NewWriter(env.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tEMAIL\tNAME\tROLE\tSTATUS\tCREATED AT")
+		for _, u := range users {
+			name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", u.ID, u.Email, name, u.Role, u.EffectiveStatus(now),
+				u.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+		}
+		return w.Flush()
+	}
+}
+
+func parseRole(role string) (string, error) {
+	switch r := strings.ToUpper(role); r {
+	case model.RoleAdmin, model.RoleUser:
+		return r, nil
+	case "":
+		return "", errors.New("-role is required")
+	default:
+		return "", fmt.Errorf("unknown role %q, want ADMIN or USER", role)
+	}
+}
+
+func findUser(ctx context.Context, env *cliEnv, email string) (*model.UserModel, error) {
+	if email == "" {
+		return nil, errors.New("-email is required")
+	}
+	user, err := env.repo.UserByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user by email: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("no account has the email address %s", email)
+	}
+	return user, nil
+}
+
+// updateUser saves a new password hash and role, keeping the rest.
+func updateUser(ctx context.Context, env *cliEnv, user *model.UserModel, passwordHash, role string) (*model.UserModel, error) {
+	updated, err := env.repo.UserUpdate(ctx, user.Email, &model.NewUserModel{
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+		Password:  passwordHash,
+		Role:      role,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	if updated == nil {
+		return nil, fmt.Errorf("no account has the email address %s", user.Email)
+	}
+	return updated, nil
+}